@@ -0,0 +1,42 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"qinniu/internal/models"
+	"qinniu/internal/pkg/auth"
+
+	"qinniu/internal/pkg/initconfig"
+)
+
+// 创建初始管理员密钥，之后通过 /api/keys 管理其他密钥
+func main() {
+	name := flag.String("name", "admin", "Name of the admin API key")
+	force := flag.Bool("force", false, "Create a new admin key even if an active one already exists")
+	flag.Parse()
+
+	initconfig.Init()
+
+	if !*force {
+		count, err := models.CountActiveAdminKeys()
+		if err != nil {
+			log.Fatalf("查询管理员密钥失败: %v", err)
+		}
+		if count > 0 {
+			log.Fatalf("已存在 %d 个有效的管理员密钥，如需再创建请使用 -force", count)
+		}
+	}
+
+	plain, key, err := auth.GenerateAPIKey(*name, models.RoleAdmin, nil)
+	if err != nil {
+		log.Fatalf("生成密钥失败: %v", err)
+	}
+	key.CreatedBy = "cmd/admin"
+	if err := key.Create(); err != nil {
+		log.Fatalf("保存密钥失败: %v", err)
+	}
+
+	log.Printf("已创建管理员密钥 %s (%s)，请妥善保存，明文不会再次显示", key.Name, key.ID.Hex())
+	fmt.Println(plain)
+}
@@ -11,6 +11,7 @@ import (
 
 	"qinniu/internal/pkg/initconfig"
 	"qinniu/internal/pkg/queue"
+	"qinniu/internal/webhook"
 	"qinniu/internal/worker"
 	"strings"
 	"sync"
@@ -20,21 +21,25 @@ import (
 func main() {
 	initconfig.Init()
 
+	// 只生成 webhook 投递记录，由 API 服务负责发送
+	webhook.Register()
+
 	// 启动评估服务
 	go func() {
 		log.Println("Starting evaluator service...")
 
-		// 初始化 AI 客户端
-		apiKey := os.Getenv("AI_API_KEY")
-		if apiKey == "" {
-			log.Fatal("AI_API_KEY environment variable not set")
+		// 初始化 AI 客户端，后端由 AI_PROVIDER 选择
+		aiClient, err := ai.NewClientFromEnv()
+		if err != nil {
+			log.Fatalf("Failed to initialize AI client: %v", err)
 		}
 
-		log.Printf("Using AI API key: %s...", apiKey[:10])
-
-		aiClient := ai.NewClient(apiKey)
 		queueClient := queue.NewQueue()
 		evaluator := worker.NewEvaluator(aiClient, queueClient)
+		// 配置了 GitHub token 时收集 README、源码等工作证据
+		if os.Getenv("GITHUB_TOKEN") != "" {
+			evaluator.SetEvidenceBuilder(ai.NewEvidenceBuilder(crawler.NewGitHubCrawler(), ai.LoadEvidenceConfig()))
+		}
 
 		log.Println("Evaluator service started, waiting for tasks...")
 		if err := evaluator.Start(); err != nil {
@@ -49,14 +54,31 @@ func main() {
 	// 支持多用户名输入
 	usernames := flag.String("users", "", "GitHub usernames to analyze (comma-separated)")
 	concurrency := flag.Int("concurrency", 5, "Number of concurrent crawlers")
+	platform := flag.String("platform", crawler.PlatformGitHub, "Code hosting platform: github, gitlab or gitee")
+	baseURL := flag.String("base-url", "", "API base URL for self-hosted GitLab/Gitee instances")
+	full := flag.Bool("full", false, "Ignore incremental cursors and recrawl everything")
 	flag.Parse()
 
 	if *usernames == "" {
 		log.Fatal("Please provide GitHub usernames using -users flag")
 	}
 
-	// 创建爬虫实例
-	crawlerInstance := crawler.NewGitHubCrawler()
+	// 创建爬虫实例，GitHub 之外的平台走通用的平台爬取流程
+	var fetch func(string) (*models.Developer, error)
+	if *platform == crawler.PlatformGitHub {
+		gc := crawler.NewGitHubCrawler()
+		fetch = func(username string) (*models.Developer, error) {
+			return gc.GetUserDataWithOptions(username, crawler.CrawlOptions{Full: *full})
+		}
+	} else {
+		p, err := crawler.NewPlatform(*platform, *baseURL)
+		if err != nil {
+			log.Fatal(err)
+		}
+		fetch = func(username string) (*models.Developer, error) {
+			return crawler.CrawlPlatformUser(p, username, crawler.CrawlOptions{Full: *full})
+		}
+	}
 
 	// 创建工作池
 	userChan := make(chan string)
@@ -68,7 +90,7 @@ func main() {
 		go func() {
 			defer wg.Done()
 			for username := range userChan {
-				developer, err := processUserWithRetry(crawlerInstance, username)
+				developer, err := processUserWithRetry(fetch, username)
 				if err != nil {
 					log.Printf("Error processing user %s: %v\n", username, err)
 					continue
@@ -97,7 +119,7 @@ func main() {
 }
 
 // 添加重试机制
-func processUserWithRetry(crawler *crawler.GitHubCrawler, username string) (*models.Developer, error) {
+func processUserWithRetry(fetch func(string) (*models.Developer, error), username string) (*models.Developer, error) {
 	fmt.Printf("\nProcessing user: %s\n", username) // 添加处理提示
 
 	var developer *models.Developer
@@ -107,7 +129,7 @@ func processUserWithRetry(crawler *crawler.GitHubCrawler, username string) (*mod
 	retryDelay := time.Second
 
 	for i := 0; i < maxRetries; i++ {
-		developer, err = crawler.GetUserData(username)
+		developer, err = fetch(username)
 		if err == nil {
 			return developer, nil
 		}
@@ -159,5 +181,5 @@ func printResult(developer *models.Developer) {
 	fmt.Printf("Updated At: %v\n", developer.UpdatedAt)
 
 	fmt.Println("Saved to database successfully!")
-	fmt.Println("====================================\n")
+	fmt.Print("====================================\n\n")
 }
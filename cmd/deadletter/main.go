@@ -0,0 +1,166 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"qinniu/internal/pkg/queue"
+	"strings"
+	"time"
+
+	"github.com/joho/godotenv"
+)
+
+const usage = `查看和处理评估任务的死信队列
+
+用法:
+  deadletter list [-limit 50]      列出最近的死信
+  deadletter show <id>             查看死信的任务、最后的错误和全部处理记录
+  deadletter replay <id>... | -all 重新放回评估队列
+  deadletter purge <id>... | -all  删除死信
+`
+
+func main() {
+	flag.Usage = func() { fmt.Fprint(os.Stderr, usage) }
+	flag.Parse()
+	if flag.NArg() == 0 {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	// 只需要 Redis 连接配置
+	if err := godotenv.Load("configs/.env"); err != nil {
+		log.Printf("警告: 未能加载 .env 文件: %v", err)
+	}
+
+	dlq := queue.NewDeadLetterQueue()
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+
+	cmd, args := flag.Arg(0), flag.Args()[1:]
+	switch cmd {
+	case "list":
+		fs := flag.NewFlagSet(cmd, flag.ExitOnError)
+		limit := fs.Int64("limit", 50, "Maximum number of dead letters to list")
+		fs.Parse(args)
+		list(ctx, dlq, *limit)
+	case "show":
+		if len(args) != 1 {
+			log.Fatalf("用法: deadletter show <id>")
+		}
+		show(ctx, dlq, args[0])
+	case "replay", "purge":
+		fs := flag.NewFlagSet(cmd, flag.ExitOnError)
+		all := fs.Bool("all", false, "Apply to every dead letter")
+		fs.Parse(args)
+		if *all == (fs.NArg() > 0) {
+			log.Fatalf("用法: deadletter %s <id>... 或 deadletter %s -all", cmd, cmd)
+		}
+		if cmd == "replay" {
+			replay(ctx, dlq, fs.Args(), *all)
+		} else {
+			purge(ctx, dlq, fs.Args(), *all)
+		}
+	default:
+		flag.Usage()
+		os.Exit(2)
+	}
+}
+
+func list(ctx context.Context, dlq *queue.DeadLetterQueue, limit int64) {
+	letters, total, err := dlq.List(ctx, limit)
+	if err != nil {
+		log.Fatalf("读取死信队列失败: %v", err)
+	}
+	fmt.Printf("共 %d 个死信\n", total)
+	for _, dead := range letters {
+		fmt.Printf("%s  %-20s  %-12s  %d 次  %s  %s\n",
+			dead.ID, dead.Username, dead.Reason, len(dead.Attempts),
+			dead.DeadAt.Local().Format("2006-01-02 15:04:05"), oneLine(dead.LastError, 80))
+	}
+}
+
+func show(ctx context.Context, dlq *queue.DeadLetterQueue, id string) {
+	dead, err := dlq.Get(ctx, id)
+	if err != nil {
+		log.Fatalf("读取死信 %s 失败: %v", id, err)
+	}
+	data, err := json.MarshalIndent(dead, "", "  ")
+	if err != nil {
+		log.Fatalf("%v", err)
+	}
+	fmt.Println(string(data))
+}
+
+func replay(ctx context.Context, dlq *queue.DeadLetterQueue, ids []string, all bool) {
+	if all {
+		var err error
+		if ids, err = allIDs(ctx, dlq); err != nil {
+			log.Fatalf("读取死信队列失败: %v", err)
+		}
+	}
+	failed := 0
+	for _, id := range ids {
+		dead, err := dlq.Replay(ctx, id)
+		if err != nil {
+			log.Printf("重放死信 %s 失败: %v", id, err)
+			failed++
+			continue
+		}
+		log.Printf("已将 %s (%s) 重新加入评估队列", id, dead.Username)
+	}
+	log.Printf("重放 %d 个死信，失败 %d 个", len(ids)-failed, failed)
+	if failed > 0 {
+		os.Exit(1)
+	}
+}
+
+func purge(ctx context.Context, dlq *queue.DeadLetterQueue, ids []string, all bool) {
+	if all {
+		purged, err := dlq.PurgeAll(ctx)
+		if err != nil {
+			log.Fatalf("清空死信队列失败: %v", err)
+		}
+		log.Printf("已删除 %d 个死信", purged)
+		return
+	}
+	failed := 0
+	for _, id := range ids {
+		if err := dlq.Purge(ctx, id); err != nil {
+			log.Printf("删除死信 %s 失败: %v", id, err)
+			failed++
+		}
+	}
+	log.Printf("已删除 %d 个死信，失败 %d 个", len(ids)-failed, failed)
+	if failed > 0 {
+		os.Exit(1)
+	}
+}
+
+// allIDs 当前全部死信的 ID，重放期间新进入的死信不包含在内
+func allIDs(ctx context.Context, dlq *queue.DeadLetterQueue) ([]string, error) {
+	_, total, err := dlq.List(ctx, 1)
+	if err != nil || total == 0 {
+		return nil, err
+	}
+	letters, _, err := dlq.List(ctx, total)
+	if err != nil {
+		return nil, err
+	}
+	ids := make([]string, 0, len(letters))
+	for _, dead := range letters {
+		ids = append(ids, dead.ID)
+	}
+	return ids, nil
+}
+
+func oneLine(s string, n int) string {
+	s = strings.Join(strings.Fields(s), " ")
+	if runes := []rune(s); len(runes) > n {
+		return string(runes[:n]) + "…"
+	}
+	return s
+}
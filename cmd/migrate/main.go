@@ -0,0 +1,81 @@
+package main
+
+import (
+	"flag"
+	"log"
+	"qinniu/internal/crawler"
+	"qinniu/internal/models"
+	"time"
+
+	"qinniu/internal/pkg/initconfig"
+)
+
+// 开发者数据迁移：其他平台开发者改用带前缀的用户名、回填 GitHub ID、合并重复记录、创建唯一索引、补上旧评估的审核状态
+func main() {
+	backfill := flag.Bool("backfill", true, "Look up GitHub IDs for records that do not have one")
+	dryRun := flag.Bool("dry-run", false, "Only report duplicates, do not modify the database")
+	flag.Parse()
+
+	initconfig.Init()
+
+	// 0. 以其他平台账号为主的旧记录改用带平台前缀的用户名，避免与 GitHub 开发者重名，也不参与 GitHub ID 回填
+	if !*dryRun {
+		renamed, err := models.NamespacePlatformUsernames()
+		if err != nil {
+			log.Fatalf("修改其他平台开发者的用户名失败: %v", err)
+		}
+		log.Printf("%d 个以其他平台账号为主的开发者改用带平台前缀的用户名", renamed)
+	}
+
+	// 1. 回填旧记录缺失的 GitHub ID，之后才能按 ID 合并
+	if *backfill {
+		usernames, err := models.FindUsernamesWithoutGitHubID()
+		if err != nil {
+			log.Fatalf("查询缺少 GitHub ID 的记录失败: %v", err)
+		}
+		log.Printf("共有 %d 个用户名需要回填 GitHub ID", len(usernames))
+
+		if len(usernames) > 0 {
+			crawlerInstance := crawler.NewGitHubCrawler()
+			for _, username := range usernames {
+				githubID, err := crawlerInstance.LookupGitHubID(username)
+				if err != nil {
+					log.Printf("Warning: 无法获取 %s 的 GitHub ID: %v", username, err)
+					continue
+				}
+				if *dryRun {
+					log.Printf("[dry-run] %s -> %d", username, githubID)
+					continue
+				}
+				if err := models.SetGitHubIDForUsername(username, githubID); err != nil {
+					log.Printf("Warning: 回填 %s 失败: %v", username, err)
+				}
+				time.Sleep(100 * time.Millisecond) // 避免触发 GitHub 二级限流
+			}
+		}
+	}
+
+	// 2. 合并重复记录
+	report, err := models.MergeDuplicateDevelopers(*dryRun)
+	if err != nil {
+		log.Fatalf("合并重复记录失败: %v", err)
+	}
+	log.Printf("合并完成: %d 组重复, 删除 %d 条记录", report.Groups, report.Removed)
+
+	if *dryRun {
+		return
+	}
+
+	// 3. 创建唯一索引
+	if err := models.EnsureIndexes(); err != nil {
+		log.Fatalf("%v", err)
+	}
+	log.Println("唯一索引已创建")
+
+	// 4. 旧评估没有审核状态，对 viewer 不可见；同时为通过审核的评估生成全文搜索内容
+	autoApproved, needsReview, err := models.BackfillEvaluationStatus()
+	if err != nil {
+		log.Fatalf("补充评估审核状态失败: %v", err)
+	}
+	log.Printf("评估审核状态已补充: %d 条自动通过, %d 条待审核", autoApproved, needsReview)
+}
@@ -7,9 +7,13 @@ import (
 	"os"
 	"os/signal"
 	"qinniu/internal/api"
+	"qinniu/internal/api/handlers"
+	"qinniu/internal/crawler"
 	"qinniu/internal/pkg/ai"
 	"qinniu/internal/pkg/queue"
+	"qinniu/internal/webhook"
 	"qinniu/internal/worker"
+	"strings"
 	"syscall"
 	"time"
 
@@ -21,23 +25,37 @@ import (
 func main() {
 	initconfig.Init()
 
+	// 开发者创建、TalentRank 变化和评估完成时生成 webhook 投递
+	webhook.Register()
+	dispatcher := webhook.NewDispatcher()
+	if err := dispatcher.Start(); err != nil {
+		log.Fatalf("Failed to start webhook dispatcher: %v", err)
+	}
+
 	// 创建Gin引擎
 	r := gin.Default()
 
+	// 只信任 TRUSTED_PROXIES 中的代理设置的 X-Forwarded-For，未配置时按连接的对端地址识别客户端，
+	// 否则任何客户端都可以伪造该请求头绕过按 IP 的限流
+	if err := r.SetTrustedProxies(trustedProxies()); err != nil {
+		log.Fatalf("无效的 TRUSTED_PROXIES: %v", err)
+	}
+
 	// 设置路由
 	api.SetupRoutes(r)
 
-	// 初始化 AI 客户端
-	apiKey := os.Getenv("AI_API_KEY")
-	if apiKey == "" {
-		log.Fatal("AI_API_KEY environment variable not set")
+	// 初始化 AI 客户端，后端由 AI_PROVIDER 选择
+	aiClient, err := ai.NewClientFromEnv()
+	if err != nil {
+		log.Fatalf("Failed to initialize AI client: %v", err)
 	}
 
-	log.Printf("Starting evaluator service with API key: %s...", apiKey[:10])
+	// 所有后台爬取共享同一个爬虫实例，同时为评估提供 README、源码等工作证据
+	githubCrawler := crawler.NewGitHubCrawler()
 
-	aiClient := ai.NewClient(apiKey)
 	queueClient := queue.NewQueue()
 	evaluator := worker.NewEvaluator(aiClient, queueClient)
+	evaluator.SetEvidenceBuilder(ai.NewEvidenceBuilder(githubCrawler, ai.LoadEvidenceConfig()))
 
 	// 启动评估服务在一个新的协程中
 	go func() {
@@ -48,6 +66,23 @@ func main() {
 		log.Println("Evaluator service is running...")
 	}()
 
+	// 启动异步爬取任务执行器
+	crawlJobs := worker.NewCrawlJobRunner(githubCrawler, worker.LoadCrawlWorkers())
+	if err := crawlJobs.Start(); err != nil {
+		log.Printf("Error resuming crawl jobs: %v", err)
+	}
+	handlers.SetCrawlJobRunner(crawlJobs)
+	handlers.SetDeadLetterQueue(queue.NewDeadLetterQueue())
+
+	// 启动后台定时刷新
+	var scheduler *worker.Scheduler
+	if os.Getenv("SCHEDULER_ENABLED") == "true" {
+		scheduler = worker.NewScheduler(worker.LoadSchedulerConfig(), githubCrawler, queue.NewRefreshQueue())
+		if err := scheduler.Start(); err != nil {
+			log.Fatalf("Failed to start scheduler: %v", err)
+		}
+	}
+
 	// 获取服务器端口
 	port := os.Getenv("SERVER_PORT")
 	if port == "" {
@@ -80,6 +115,23 @@ func main() {
 		log.Printf("Error shutting down evaluator: %v", err)
 	}
 
+	// 停止异步爬取任务，未完成的任务在下次启动时继续
+	if err := crawlJobs.Stop(); err != nil {
+		log.Printf("Error shutting down crawl job runner: %v", err)
+	}
+
+	// 停止后台定时刷新
+	if scheduler != nil {
+		if err := scheduler.Stop(); err != nil {
+			log.Printf("Error shutting down scheduler: %v", err)
+		}
+	}
+
+	// 停止 webhook 投递，未完成的投递在下次启动时继续
+	if err := dispatcher.Stop(); err != nil {
+		log.Printf("Error shutting down webhook dispatcher: %v", err)
+	}
+
 	// 优雅关闭HTTP服务器
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
@@ -89,3 +141,14 @@ func main() {
 
 	log.Println("Application has been shut down.")
 }
+
+// trustedProxies 读取 TRUSTED_PROXIES（逗号分隔的 IP 或 CIDR）
+func trustedProxies() []string {
+	var proxies []string
+	for _, p := range strings.Split(os.Getenv("TRUSTED_PROXIES"), ",") {
+		if p = strings.TrimSpace(p); p != "" {
+			proxies = append(proxies, p)
+		}
+	}
+	return proxies
+}
@@ -1,15 +1,11 @@
 package handlers
 
 import (
-	"fmt"
 	"net/http"
 	"qinniu/internal/models"
+	"qinniu/internal/worker"
 	"strings"
 
-	"sync"
-
-	githubcrawler "qinniu/internal/crawler"
-
 	"github.com/gin-gonic/gin"
 )
 
@@ -17,15 +13,19 @@ import (
 type RunCrawlerRequest struct {
 	Usernames   string `json:"usernames" binding:"required"`   // 逗号分隔的GitHub用户名
 	Concurrency int    `json:"concurrency" binding:"required"` // 并发数
+	Full        bool   `json:"full"`                           // 忽略增量游标，完整重新爬取
 }
 
-type CrawlResult struct {
-	Username string `json:"username"`
-	Success  bool   `json:"success"`
-	Error    string `json:"error,omitempty"`
+// crawlJobs 服务启动时创建的共享任务执行器
+var crawlJobs *worker.CrawlJobRunner
+
+// SetCrawlJobRunner 设置处理异步爬取任务的执行器
+func SetCrawlJobRunner(runner *worker.CrawlJobRunner) {
+	crawlJobs = runner
 }
 
 // RunCrawlerHandler 处理 /run-crawler 路由的请求
+// 创建异步爬取任务并立即返回任务 ID，通过 /api/jobs/:id 查询进度
 func RunCrawlerHandler(c *gin.Context) {
 
 	var req RunCrawlerRequest
@@ -39,90 +39,46 @@ func RunCrawlerHandler(c *gin.Context) {
 		return
 	}
 
-	userList := strings.Split(req.Usernames, ",")
-	for i, username := range userList {
-		userList[i] = strings.TrimSpace(username)
+	var userList []string
+	seen := make(map[string]bool)
+	for _, username := range strings.Split(req.Usernames, ",") {
+		username = strings.TrimSpace(username)
+		if username == "" || seen[strings.ToLower(username)] {
+			continue
+		}
+		seen[strings.ToLower(username)] = true
+		userList = append(userList, username)
 	}
-
-	crawlerInstance := githubcrawler.NewGitHubCrawler()
-	userChan := make(chan string)
-	var wg sync.WaitGroup
-	var results []CrawlResult
-	var mu sync.Mutex
-
-	// 启动工作协程
-	for i := 0; i < req.Concurrency; i++ {
-		wg.Add(1)
-		go func() {
-			defer wg.Done()
-			for username := range userChan {
-				developer, err := crawlerInstance.GetUserData(username)
-				if err != nil {
-					mu.Lock()
-					results = append(results, CrawlResult{
-						Username: username,
-						Success:  false,
-						Error:    err.Error(),
-					})
-					mu.Unlock()
-					continue
-				}
-				PrintResult(developer)
-			}
-		}()
+	if len(userList) == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "usernames is empty"})
+		return
 	}
 
-	// 发送用户名到通道
-	go func() {
-		for _, username := range userList {
-			if username != "" {
-				userChan <- username
-			}
-		}
-		close(userChan)
-	}()
-
-	// 等待所有工作完成
-	wg.Wait()
-
-	c.JSON(http.StatusOK, gin.H{
-		"status":  "Crawling completed",
-		"results": results,
-	})
-}
-func PrintResult(developer *models.Developer) {
-	if developer == nil {
+	if crawlJobs == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "crawl job runner is not available"})
 		return
 	}
 
-	fmt.Println("\n====================================")
-	fmt.Printf("Username: %s\n", developer.Username)
-	if developer.Name != "" {
-		fmt.Printf("Name: %s\n", developer.Name)
-	}
-	fmt.Printf("Location: %s\n", developer.Location)
-	if developer.Nation != "" {
-		fmt.Printf("Nation: %s (置信度: %.2f%%)\n", developer.Nation, developer.NationConfidence)
+	job := models.NewCrawlJob(userList, req.Full, req.Concurrency)
+	if err := crawlJobs.Submit(job); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
 	}
-	fmt.Printf("TalentRank: %.2f\n", developer.TalentRank)
-	fmt.Printf("Confidence: %.2f%%\n", developer.Confidence)
 
-	// 添加更多的调试信息
-	fmt.Printf("Avatar URL: %s\n", developer.Avatar)
-	if developer.Avatar == "" {
-		fmt.Println("Warning: Avatar URL is empty!")
-	}
+	c.JSON(http.StatusAccepted, gin.H{
+		"job_id": job.ID.Hex(),
+		"status": job.Status,
+		"total":  job.Total,
+	})
+}
 
-	if len(developer.Skills) > 0 {
-		fmt.Printf("Skills: %v\n", developer.Skills)
+// GetCrawlJob 查询爬取任务的状态和每个用户的结果
+func GetCrawlJob(c *gin.Context) {
+	job, err := models.FindJobByID(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "任务不存在"})
+		return
 	}
-	fmt.Printf("Number of Repositories: %d\n", len(developer.Repositories))
-
-	// 添加数据库操作的调试信息
-	fmt.Printf("Database ID: %s\n", developer.ID.Hex())
-	fmt.Printf("Created At: %v\n", developer.CreatedAt)
-	fmt.Printf("Updated At: %v\n", developer.UpdatedAt)
 
-	fmt.Println("Saved to database successfully!")
-	fmt.Println("====================================\n")
+	c.JSON(http.StatusOK, job)
 }
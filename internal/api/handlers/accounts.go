@@ -0,0 +1,82 @@
+package handlers
+
+import (
+	"net/http"
+	"qinniu/internal/models"
+	"strings"
+
+	githubcrawler "qinniu/internal/crawler"
+
+	"github.com/gin-gonic/gin"
+)
+
+// LinkAccountRequest 关联平台账号的请求
+type LinkAccountRequest struct {
+	Platform string `json:"platform" binding:"required"` // gitlab 或 gitee
+	Login    string `json:"login" binding:"required"`
+	BaseURL  string `json:"base_url"` // 自建实例的 API 地址，可选
+}
+
+// LinkDeveloperAccount 为开发者关联 GitLab / Gitee 账号并合并 TalentRank
+func LinkDeveloperAccount(c *gin.Context) {
+	developer, err := models.FindByID(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "开发者不存在"})
+		return
+	}
+
+	var req LinkAccountRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	// GitHub 账号由爬虫根据 GitHub ID 维护，不支持手工关联
+	platformName := strings.ToLower(req.Platform)
+	if platformName != githubcrawler.PlatformGitLab && platformName != githubcrawler.PlatformGitee {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "platform must be gitlab or gitee"})
+		return
+	}
+
+	platform, err := githubcrawler.NewPlatform(platformName, req.BaseURL)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	account, err := githubcrawler.LinkAccount(platform, developer, req.Login)
+	if err != nil {
+		c.JSON(http.StatusBadGateway, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"account":     account,
+		"talent_rank": developer.TalentRank,
+	})
+}
+
+// UnlinkDeveloperAccount 取消关联平台账号
+func UnlinkDeveloperAccount(c *gin.Context) {
+	developer, err := models.FindByID(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "开发者不存在"})
+		return
+	}
+
+	if !developer.RemoveAccount(c.Param("key")) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "账号不存在"})
+		return
+	}
+
+	developer.ApplyAccounts()
+	if err := developer.Update(); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":     "账号已取消关联",
+		"talent_rank": developer.TalentRank,
+	})
+}
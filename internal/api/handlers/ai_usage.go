@@ -0,0 +1,88 @@
+package handlers
+
+import (
+	"net/http"
+	"qinniu/internal/models"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// 费用报表最多统计的天数
+const maxUsageReportDays = 366
+
+// GetAICosts 按天和模型汇总模型调用的 token 用量和费用
+// from、to 为 YYYY-MM-DD（含 to 当天），默认最近 30 天；tz 为统计日期使用的时区，默认 UTC
+func GetAICosts(c *gin.Context) {
+	location := time.UTC
+	if tz := c.Query("tz"); tz != "" {
+		loc, err := time.LoadLocation(tz)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "无效的时区: " + tz})
+			return
+		}
+		location = loc
+	}
+
+	today := time.Now().In(location)
+	to := time.Date(today.Year(), today.Month(), today.Day(), 0, 0, 0, 0, location)
+	if v := c.Query("to"); v != "" {
+		t, err := time.ParseInLocation("2006-01-02", v, location)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "to 格式应为 YYYY-MM-DD"})
+			return
+		}
+		to = t
+	}
+	from := to.AddDate(0, 0, -29)
+	if v := c.Query("from"); v != "" {
+		t, err := time.ParseInLocation("2006-01-02", v, location)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "from 格式应为 YYYY-MM-DD"})
+			return
+		}
+		from = t
+	}
+	if from.After(to) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "from 不能晚于 to"})
+		return
+	}
+	if to.Sub(from) > maxUsageReportDays*24*time.Hour {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "统计范围不能超过 366 天"})
+		return
+	}
+
+	summaries, err := models.AIUsageReport(from, to.AddDate(0, 0, 1), location, c.Query("operation"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	total := models.AIUsageSummary{}
+	byModel := make(map[string]*models.AIUsageSummary)
+	for _, s := range summaries {
+		addUsage(&total, s)
+		if byModel[s.Model] == nil {
+			byModel[s.Model] = &models.AIUsageSummary{Model: s.Model}
+		}
+		addUsage(byModel[s.Model], s)
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"from":     from.Format("2006-01-02"),
+		"to":       to.Format("2006-01-02"),
+		"timezone": location.String(),
+		"days":     summaries,
+		"models":   byModel,
+		"total":    total,
+	})
+}
+
+func addUsage(total *models.AIUsageSummary, s models.AIUsageSummary) {
+	total.Calls += s.Calls
+	total.CachedCalls += s.CachedCalls
+	total.PromptTokens += s.PromptTokens
+	total.CompletionTokens += s.CompletionTokens
+	total.TotalTokens += s.TotalTokens
+	total.Cost += s.Cost
+}
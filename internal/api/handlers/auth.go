@@ -0,0 +1,159 @@
+package handlers
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"qinniu/internal/models"
+	"qinniu/internal/pkg/auth"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// TokenRequest 用 API 密钥换取 JWT
+type TokenRequest struct {
+	APIKey string `json:"api_key" binding:"required"`
+}
+
+// APIKeyRequest 创建 API 密钥的请求
+type APIKeyRequest struct {
+	Name      string `json:"name" binding:"required"`
+	Role      string `json:"role"`       // viewer、recruiter、operator 或 admin，默认 viewer
+	ExpiresIn string `json:"expires_in"` // 有效期，如 720h，不填表示不过期
+}
+
+// IssueToken 校验 API 密钥并签发 JWT，令牌有效期不超过密钥本身的有效期
+func IssueToken(c *gin.Context) {
+	var req TokenRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	principal, key, err := auth.AuthenticateAPIKey(req.APIKey)
+	if err != nil {
+		if errors.Is(err, auth.ErrInvalidAPIKey) || errors.Is(err, auth.ErrAPIKeyInactive) {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+			return
+		}
+		log.Printf("Error authenticating api key: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "认证服务不可用"})
+		return
+	}
+
+	now := time.Now()
+	expiresAt := now.Add(auth.TokenTTL())
+	if key.ExpiresAt != nil && key.ExpiresAt.Before(expiresAt) {
+		expiresAt = *key.ExpiresAt
+	}
+
+	token, err := auth.IssueToken(auth.Claims{
+		Subject:   principal.KeyID,
+		Name:      principal.Name,
+		Role:      principal.Role,
+		IssuedAt:  now.Unix(),
+		ExpiresAt: expiresAt.Unix(),
+	})
+	if err != nil {
+		if errors.Is(err, auth.ErrJWTDisabled) {
+			c.JSON(http.StatusServiceUnavailable, gin.H{"error": "服务端未配置 JWT_SECRET"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"token":      token,
+		"token_type": "Bearer",
+		"expires_at": expiresAt,
+	})
+}
+
+// GetCurrentPrincipal 返回当前调用方
+func GetCurrentPrincipal(c *gin.Context) {
+	c.JSON(http.StatusOK, auth.CurrentPrincipal(c))
+}
+
+// CreateAPIKey 创建 API 密钥，明文只在创建时返回一次
+func CreateAPIKey(c *gin.Context) {
+	var req APIKeyRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if req.Role == "" {
+		req.Role = models.RoleViewer
+	}
+	if !models.IsValidRole(req.Role) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "unsupported role: " + req.Role})
+		return
+	}
+
+	var expiresAt *time.Time
+	if req.ExpiresIn != "" {
+		ttl, err := time.ParseDuration(req.ExpiresIn)
+		if err != nil || ttl <= 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "expires_in must be a positive duration, e.g. 720h"})
+			return
+		}
+		t := time.Now().Add(ttl)
+		expiresAt = &t
+	}
+
+	plain, key, err := auth.GenerateAPIKey(req.Name, req.Role, expiresAt)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	key.CreatedBy = auth.CurrentPrincipal(c).KeyID
+	if err := key.Create(); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"key":     key,
+		"api_key": plain,
+	})
+}
+
+// ListAPIKeys 列出所有 API 密钥，不包含明文和哈希
+func ListAPIKeys(c *gin.Context) {
+	keys, err := models.ListAPIKeys()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"keys": keys})
+}
+
+// RevokeAPIKey 吊销 API 密钥，不允许吊销最后一个管理员密钥
+// 用该密钥签发的 JWT 随之立即失效，每次请求都会重新检查签发密钥
+func RevokeAPIKey(c *gin.Context) {
+	key, err := models.FindAPIKeyByID(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "密钥不存在"})
+		return
+	}
+
+	if key.Role == models.RoleAdmin && key.Active(time.Now()) {
+		count, err := models.CountActiveAdminKeys()
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		if count <= 1 {
+			c.JSON(http.StatusConflict, gin.H{"error": "不能吊销最后一个管理员密钥"})
+			return
+		}
+	}
+
+	if err := key.Revoke(); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, key)
+}
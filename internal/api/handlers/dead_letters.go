@@ -0,0 +1,104 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"qinniu/internal/pkg/queue"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// deadLetters 评估任务的死信队列
+var deadLetters *queue.DeadLetterQueue
+
+// SetDeadLetterQueue 设置评估任务的死信队列
+func SetDeadLetterQueue(q *queue.DeadLetterQueue) {
+	deadLetters = q
+}
+
+// ListDeadLetters 最近进入死信队列的评估任务，最新的在前
+func ListDeadLetters(c *gin.Context) {
+	if deadLetters == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "dead letter queue is not available"})
+		return
+	}
+	limit, err := strconv.ParseInt(c.DefaultQuery("limit", "50"), 10, 64)
+	if err != nil || limit <= 0 || limit > 200 {
+		limit = 50
+	}
+
+	letters, total, err := deadLetters.List(c.Request.Context(), limit)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"total": total, "dead_letters": letters})
+}
+
+// GetDeadLetter 查看死信的任务内容、最后的错误和全部处理记录
+func GetDeadLetter(c *gin.Context) {
+	if deadLetters == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "dead letter queue is not available"})
+		return
+	}
+	dead, err := deadLetters.Get(c.Request.Context(), c.Param("id"))
+	if err != nil {
+		deadLetterError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, dead)
+}
+
+// ReplayDeadLetter 把死信重新放回评估队列
+func ReplayDeadLetter(c *gin.Context) {
+	if deadLetters == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "dead letter queue is not available"})
+		return
+	}
+	dead, err := deadLetters.Replay(c.Request.Context(), c.Param("id"))
+	if err != nil {
+		deadLetterError(c, err)
+		return
+	}
+	c.JSON(http.StatusAccepted, gin.H{"message": "已重新加入评估队列", "username": dead.Username, "replays": dead.Replays + 1})
+}
+
+// PurgeDeadLetter 删除一个死信
+func PurgeDeadLetter(c *gin.Context) {
+	if deadLetters == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "dead letter queue is not available"})
+		return
+	}
+	if err := deadLetters.Purge(c.Request.Context(), c.Param("id")); err != nil {
+		deadLetterError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "已删除"})
+}
+
+// PurgeDeadLetters 清空死信队列，需要 confirm=true 防止误操作
+func PurgeDeadLetters(c *gin.Context) {
+	if deadLetters == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "dead letter queue is not available"})
+		return
+	}
+	if c.Query("confirm") != "true" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "清空死信队列需要 confirm=true"})
+		return
+	}
+	purged, err := deadLetters.PurgeAll(c.Request.Context())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"purged": purged})
+}
+
+func deadLetterError(c *gin.Context, err error) {
+	if errors.Is(err, queue.ErrDeadLetterNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "死信不存在或已被处理"})
+		return
+	}
+	c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+}
@@ -2,9 +2,12 @@ package handlers
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"net/http"
 	"qinniu/internal/models"
+	"qinniu/internal/pkg/auth"
+	"qinniu/internal/pkg/query"
 
 	"strconv"
 
@@ -12,6 +15,7 @@ import (
 	"time"
 
 	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 )
@@ -68,25 +72,169 @@ var domainSkills = map[string][]string{
 }
 
 // SearchDevelopers 搜索开发者
+// 支持 page/page_size 分页，也可以用上一页返回的 next_cursor 作为 cursor 参数取下一页
 func SearchDevelopers(c *gin.Context) {
-	page, _ := strconv.ParseInt(c.DefaultQuery("page", "1"), 10, 64)
-	pageSize, _ := strconv.ParseInt(c.DefaultQuery("page_size", "10"), 10, 64)
+	principal := auth.CurrentPrincipal(c)
+	filter, err := buildSearchQuery(c, principal)
+	if err != nil {
+		respondQueryError(c, err)
+		return
+	}
+	facets, err := requestedFacets(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	// 全文搜索默认按相关度排序
+	text := c.Query("text")
+	defaultSort := "talent_rank"
+	if text != "" {
+		defaultSort = searchScoreField
+	}
+	order, err := parseSearchOrder(c, defaultSort, text != "")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	pager, err := parsePagination(c, order)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	// 全文搜索按相关度排序并返回命中片段
+	if text != "" {
+		searchByText(c, principal, filter, text, order, pager, facets)
+		return
+	}
+
+	// 排序、分页和投影
+	// 修改投影，只包含需要的字段
+	results := append(pager.stages(order), bson.M{"$project": searchProjection()})
+
+	var developers []*models.Developer
+	var total int64
+	var facetResults map[string][]models.FacetBucket
+	if len(facets) > 0 {
+		// 需要分面统计时，结果、总数和分面在同一个 $facet 聚合中查询
+		result, err := models.AggregateFacetedSearch(filter, results, facetPipelines(c, facets))
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		developers, total, facetResults = result.Developers, result.Total, result.Facets
+		orderFacets(facetResults)
+	} else {
+		// 执行聚合查询
+		developers, err = models.AggregateSearch(append([]bson.M{{"$match": filter}}, results...))
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+
+		// 获取总数
+		total, err = models.CountDevelopers(filter)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+	}
 
-	// 构建查询条件
+	// 游标取自脱敏前的结果，排序字段都不是敏感字段
+	var last interface{}
+	if len(developers) > 0 {
+		last = developers[len(developers)-1]
+	}
+	nextCursor, err := pager.nextCursor(order, len(developers), last)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	for _, developer := range developers {
+		auth.RedactDeveloper(principal, developer)
+	}
+
+	// 返回结果
+	response := gin.H{
+		"page":       pager.Page,
+		"page_size":  pager.PageSize,
+		"total":      total,
+		"developers": developers,
+	}
+	if nextCursor != "" {
+		response["next_cursor"] = nextCursor
+	}
+	if facetResults != nil {
+		response["facets"] = facetResults
+	}
+	c.JSON(http.StatusOK, response)
+}
+
+// searchProjection 搜索结果包含的字段
+func searchProjection() bson.M {
+	return bson.M{
+		"_id":               1,
+		"username":          1,
+		"name":              1,
+		"email":             1,
+		"location":          1,
+		"nation":            1,
+		"nation_confidence": bson.M{"$toDouble": "$nation_confidence"},
+		"talent_rank":       bson.M{"$toDouble": "$talent_rank"},
+		"confidence":        bson.M{"$toDouble": "$confidence"},
+		"skills":            1,
+		"repositories":      1,
+		"created_at":        1,
+		"updated_at":        1,
+		"last_active":       1,
+		"commit_count":      bson.M{"$toInt": "$commit_count"},
+		"star_count":        bson.M{"$toInt": "$star_count"},
+		"fork_count":        bson.M{"$toInt": "$fork_count"},
+		"last_updated":      1,
+		"avatar":            1,
+		"profile_url":       1,
+		"repository_urls":   1,
+		"repo_stars":        1,
+		"data_validation":   1,
+		"update_frequency":  1,
+		// 不包含 tech_evaluation 字段，而不是显式排除
+	}
+}
+
+// buildSearchQuery 根据查询参数构建搜索条件，搜索和导出共用
+// q 参数为查询语言，与其他参数之间是 AND 关系
+func buildSearchQuery(c *gin.Context, principal *auth.Principal) (bson.M, error) {
 	conditions := []bson.M{}
 
+	if q := c.Query("q"); q != "" {
+		parsed, err := query.ParseAndCompile(q, query.Options{
+			AllowSensitive: principal.Can(auth.PermViewSensitive),
+			Domains:        domainSkills,
+		})
+		if err != nil {
+			return nil, err
+		}
+		conditions = append(conditions, parsed)
+	}
+
+	// 全文搜索，使用开发者集合的全文索引
+	if text := c.Query("text"); text != "" {
+		conditions = append(conditions, bson.M{"$text": bson.M{"$search": text}})
+	}
+
 	// 1. 基本信息模糊搜索
 	if keyword := c.Query("keyword"); keyword != "" {
-		// 关键词可以匹配用户名、姓名、邮箱或位置
-		keywordQuery := bson.M{
-			"$or": []bson.M{
-				{"username": bson.M{"$regex": keyword, "$options": "i"}},
-				{"name": bson.M{"$regex": keyword, "$options": "i"}},
-				{"email": bson.M{"$regex": keyword, "$options": "i"}},
-				{"location": bson.M{"$regex": keyword, "$options": "i"}},
-			},
+		// 关键词可以匹配用户名、姓名、邮箱或位置，无权查看邮箱时不匹配邮箱，避免通过搜索结果推断邮箱
+		keywordFields := []bson.M{
+			{"username": bson.M{"$regex": keyword, "$options": "i"}},
+			{"name": bson.M{"$regex": keyword, "$options": "i"}},
+			{"location": bson.M{"$regex": keyword, "$options": "i"}},
 		}
-		conditions = append(conditions, keywordQuery)
+		if principal.Can(auth.PermViewSensitive) {
+			keywordFields = append(keywordFields, bson.M{"email": bson.M{"$regex": keyword, "$options": "i"}})
+		}
+		conditions = append(conditions, bson.M{"$or": keywordFields})
 	}
 
 	// 添加姓名模糊查询
@@ -186,80 +334,21 @@ func SearchDevelopers(c *gin.Context) {
 	}
 
 	// 组合查询条件
-	query := bson.M{}
+	filter := bson.M{}
 	if len(conditions) > 0 {
-		query["$and"] = conditions
-	}
-
-	// 排序选项
-	sortField := c.DefaultQuery("sort_by", "talent_rank")
-	sortOrder := -1                    // 默认降序
-	if c.Query("sort_asc") == "true" { // 修改这里，只检查 "true"
-		sortOrder = 1
-	}
-
-	// 构建聚合管道
-	pipeline := []bson.M{
-		{"$match": query},
-		{"$group": bson.M{ // 添加去重
-			"_id": "$username",
-			"doc": bson.M{"$first": "$$ROOT"},
-		}},
-		{"$replaceRoot": bson.M{"newRoot": "$doc"}},
-		{"$sort": bson.M{sortField: sortOrder}},
-		{"$skip": (page - 1) * pageSize},
-		{"$limit": pageSize},
-		// 修改投影，只包含需要的字段
-		{"$project": bson.M{
-			"_id":               1,
-			"username":          1,
-			"name":              1,
-			"email":             1,
-			"location":          1,
-			"nation":            1,
-			"nation_confidence": bson.M{"$toDouble": "$nation_confidence"},
-			"talent_rank":       bson.M{"$toDouble": "$talent_rank"},
-			"confidence":        bson.M{"$toDouble": "$confidence"},
-			"skills":            1,
-			"repositories":      1,
-			"created_at":        1,
-			"updated_at":        1,
-			"last_active":       1,
-			"commit_count":      bson.M{"$toInt": "$commit_count"},
-			"star_count":        bson.M{"$toInt": "$star_count"},
-			"fork_count":        bson.M{"$toInt": "$fork_count"},
-			"last_updated":      1,
-			"avatar":            1,
-			"profile_url":       1,
-			"repository_urls":   1,
-			"repo_stars":        1,
-			"data_validation":   1,
-			"update_frequency":  1,
-			// 不包含 tech_evaluation 字段，而不是显式排除
-		}},
-	}
-
-	// 执行聚合查询
-	developers, err := models.AggregateSearch(pipeline)
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
-		return
+		filter["$and"] = conditions
 	}
+	return filter, nil
+}
 
-	// 获取总数
-	total, err := models.CountDevelopers(query)
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+// respondQueryError 查询语言错误返回 400 和出错位置
+func respondQueryError(c *gin.Context, err error) {
+	var syntaxErr *query.SyntaxError
+	if errors.As(err, &syntaxErr) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": syntaxErr.Error(), "position": syntaxErr.Pos})
 		return
 	}
-
-	// 返回结果
-	c.JSON(http.StatusOK, gin.H{
-		"page":       page,
-		"page_size":  pageSize,
-		"total":      total,
-		"developers": developers,
-	})
+	c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 }
 
 // GetDeveloper 获取单个开发者
@@ -271,17 +360,26 @@ func GetDeveloper(c *gin.Context) {
 		return
 	}
 
+	auth.RedactDeveloper(auth.CurrentPrincipal(c), developer)
 	c.JSON(http.StatusOK, developer)
 }
 
 // CreateDeveloper 创建开发者
 func CreateDeveloper(c *gin.Context) {
 	var developer models.Developer
-	if err := c.ShouldBindJSON(&developer); err != nil {
+	if err := c.ShouldBindBodyWith(&developer, binding.JSON); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
 
+	// 手工创建的记录，请求中提供的字段都视为手工字段
+	fields, err := requestFields(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	developer.MarkManualFields(fields)
+
 	if err := developer.Create(); err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
@@ -299,13 +397,36 @@ func UpdateDeveloper(c *gin.Context) {
 		return
 	}
 
+	existing, err := models.FindByID(id)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "开发者不存在"})
+		return
+	}
+
 	var developer models.Developer
-	if err := c.ShouldBindJSON(&developer); err != nil {
+	if err := c.ShouldBindBodyWith(&developer, binding.JSON); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	fields, err := requestFields(c)
+	if err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
 
+	// 身份相关字段只能由爬虫维护
 	developer.ID = objectID
+	developer.GitHubID = existing.GitHubID
+	developer.PreviousUsernames = existing.PreviousUsernames
+	developer.ManualFields = existing.ManualFields
+	developer.Watched = existing.Watched
+	developer.RepoSummaries = existing.RepoSummaries
+	// 关联账号（含增量游标）和 star 分析由爬虫维护，请求中没有游标，直接写入会丢失
+	developer.Accounts = existing.Accounts
+	developer.StarAnalysis = existing.StarAnalysis
+	developer.NationConsensus = existing.NationConsensus
+	developer.MarkManualFields(fields)
 	if err := developer.Update(); err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
@@ -331,6 +452,44 @@ func DeleteDeveloper(c *gin.Context) {
 	c.JSON(http.StatusOK, gin.H{"message": "开发者已删除"})
 }
 
+// WatchDeveloper 关注开发者，后台定时刷新时优先处理
+func WatchDeveloper(c *gin.Context) {
+	setWatched(c, true)
+}
+
+// UnwatchDeveloper 取消关注开发者
+func UnwatchDeveloper(c *gin.Context) {
+	setWatched(c, false)
+}
+
+func setWatched(c *gin.Context, watched bool) {
+	developer, err := models.FindByID(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "开发者不存在"})
+		return
+	}
+
+	if err := models.SetWatched(developer.ID, watched); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"id": developer.ID, "watched": watched})
+}
+
+// requestFields 返回请求体中出现的顶层字段名
+func requestFields(c *gin.Context) ([]string, error) {
+	var raw map[string]interface{}
+	if err := c.ShouldBindBodyWith(&raw, binding.JSON); err != nil {
+		return nil, err
+	}
+	fields := make([]string, 0, len(raw))
+	for field := range raw {
+		fields = append(fields, field)
+	}
+	return fields, nil
+}
+
 // GetAllNations 获取所有国家
 func GetAllNations(c *gin.Context) {
 	// 使用聚合管道获取去重的国家列表
@@ -0,0 +1,196 @@
+package handlers
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"qinniu/internal/models"
+	"qinniu/internal/pkg/auth"
+	"qinniu/internal/webhook"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// reviewRequest 审核请求，修改评估时可以同时修改结论字段，未提供的字段保持不变
+type reviewRequest struct {
+	Comment      string             `json:"comment"`
+	Specialties  *[]string          `json:"specialties"`
+	Experience   *map[string]string `json:"experience"`
+	AIEvaluation *string            `json:"ai_evaluation"`
+	Reasons      *[]string          `json:"reasons"`
+	Summary      *string            `json:"summary"`
+}
+
+// ListEvaluationQueue 审核队列，status 默认为 needs_review
+func ListEvaluationQueue(c *gin.Context) {
+	status := c.DefaultQuery("status", models.EvaluationNeedsReview)
+	if !models.IsValidEvaluationStatus(status) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的审核状态: " + status})
+		return
+	}
+	limit, err := strconv.ParseInt(c.DefaultQuery("limit", "50"), 10, 64)
+	if err != nil || limit <= 0 || limit > 200 {
+		limit = 50
+	}
+
+	developers, err := models.FindEvaluationsByStatus(status, limit)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	items := make([]gin.H, 0, len(developers))
+	for _, d := range developers {
+		items = append(items, gin.H{
+			"id":                d.ID,
+			"username":          d.Username,
+			"name":              d.Name,
+			"nation":            d.Nation,
+			"nation_confidence": d.NationConfidence,
+			"nation_consensus":  d.NationConsensus,
+			"tech_evaluation":   d.TechEvaluation,
+		})
+	}
+	c.JSON(http.StatusOK, gin.H{"status": status, "developers": items})
+}
+
+// ApproveEvaluation 审核通过
+func ApproveEvaluation(c *gin.Context) {
+	reviewEvaluation(c, models.ReviewApprove)
+}
+
+// EditEvaluation 修改评估结论并通过审核
+func EditEvaluation(c *gin.Context) {
+	reviewEvaluation(c, models.ReviewEdit)
+}
+
+// RejectEvaluation 驳回评估，必须填写原因
+func RejectEvaluation(c *gin.Context) {
+	reviewEvaluation(c, models.ReviewReject)
+}
+
+func reviewEvaluation(c *gin.Context, action string) {
+	developer, err := models.FindByID(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "开发者不存在"})
+		return
+	}
+	if developer.TechEvaluation.AIEvaluation == "" {
+		c.JSON(http.StatusConflict, gin.H{"error": "该开发者还没有 AI 评估"})
+		return
+	}
+
+	var req reviewRequest
+	// 通过和驳回可以不带请求体
+	if err := c.ShouldBindJSON(&req); err != nil && !errors.Is(err, io.EOF) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	req.Comment = strings.TrimSpace(req.Comment)
+	if action != models.ReviewEdit {
+		req = reviewRequest{Comment: req.Comment}
+	}
+
+	review := &models.EvaluationReview{
+		Action:   action,
+		Status:   models.EvaluationApproved,
+		Reviewer: auth.CurrentPrincipal(c).Name,
+		Comment:  req.Comment,
+	}
+	var set bson.M
+	switch action {
+	case models.ReviewReject:
+		if req.Comment == "" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "驳回时必须填写 comment"})
+			return
+		}
+		review.Status = models.EvaluationRejected
+	case models.ReviewEdit:
+		if req.AIEvaluation != nil && strings.TrimSpace(*req.AIEvaluation) == "" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "ai_evaluation 不能为空"})
+			return
+		}
+		set, review.Previous = evaluationChanges(&developer.TechEvaluation, &req)
+		if len(set) == 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "没有需要修改的字段"})
+			return
+		}
+	}
+
+	if err := models.ApplyEvaluationReview(developer, review, set); err != nil {
+		if errors.Is(err, models.ErrEvaluationChanged) {
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	applyEvaluationChanges(&developer.TechEvaluation, &req)
+	webhook.EvaluationReviewed(developer, review)
+	c.JSON(http.StatusOK, gin.H{"tech_evaluation": developer.TechEvaluation, "review": review})
+}
+
+// ListEvaluationReviews 开发者评估的审核历史
+func ListEvaluationReviews(c *gin.Context) {
+	developer, err := models.FindByID(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "开发者不存在"})
+		return
+	}
+
+	limit, err := strconv.ParseInt(c.DefaultQuery("limit", "50"), 10, 64)
+	if err != nil || limit <= 0 || limit > 200 {
+		limit = 50
+	}
+
+	reviews, err := models.ListEvaluationReviews(developer.ID, limit)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"status": developer.TechEvaluation.Status, "reviews": reviews})
+}
+
+// evaluationChanges 返回要更新的字段和这些字段的原值
+func evaluationChanges(evaluation *models.TechEvaluation, req *reviewRequest) (bson.M, map[string]interface{}) {
+	set := bson.M{}
+	previous := make(map[string]interface{})
+	if req.Specialties != nil {
+		set["specialties"], previous["specialties"] = *req.Specialties, evaluation.Specialties
+	}
+	if req.Experience != nil {
+		set["experience"], previous["experience"] = *req.Experience, evaluation.Experience
+	}
+	if req.AIEvaluation != nil {
+		set["ai_evaluation"], previous["ai_evaluation"] = *req.AIEvaluation, evaluation.AIEvaluation
+	}
+	if req.Reasons != nil {
+		set["reasons"], previous["reasons"] = *req.Reasons, evaluation.Reasons
+	}
+	if req.Summary != nil {
+		set["summary"], previous["summary"] = *req.Summary, evaluation.Summary
+	}
+	return set, previous
+}
+
+func applyEvaluationChanges(evaluation *models.TechEvaluation, req *reviewRequest) {
+	if req.Specialties != nil {
+		evaluation.Specialties = *req.Specialties
+	}
+	if req.Experience != nil {
+		evaluation.Experience = *req.Experience
+	}
+	if req.AIEvaluation != nil {
+		evaluation.AIEvaluation = *req.AIEvaluation
+	}
+	if req.Reasons != nil {
+		evaluation.Reasons = *req.Reasons
+	}
+	if req.Summary != nil {
+		evaluation.Summary = *req.Summary
+	}
+}
@@ -0,0 +1,127 @@
+package handlers
+
+import (
+	"encoding/csv"
+	"fmt"
+	"net/http"
+	"qinniu/internal/models"
+	"qinniu/internal/pkg/auth"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const (
+	defaultExportLimit = 1000
+	maxExportLimit     = 10000
+)
+
+// exportColumn 导出的一列，sensitive 列只对有 view_sensitive 权限的调用方导出
+type exportColumn struct {
+	name      string
+	sensitive bool
+	value     func(d *models.Developer) string
+}
+
+var exportColumns = []exportColumn{
+	{name: "id", value: func(d *models.Developer) string { return d.ID.Hex() }},
+	{name: "username", value: func(d *models.Developer) string { return d.Username }},
+	{name: "name", value: func(d *models.Developer) string { return d.Name }},
+	{name: "email", sensitive: true, value: func(d *models.Developer) string { return d.Email }},
+	{name: "location", value: func(d *models.Developer) string { return d.Location }},
+	{name: "nation", value: func(d *models.Developer) string { return d.Nation }},
+	{name: "nation_confidence", value: func(d *models.Developer) string { return formatFloat(d.NationConfidence) }},
+	{name: "talent_rank", value: func(d *models.Developer) string { return formatFloat(d.TalentRank) }},
+	{name: "confidence", value: func(d *models.Developer) string { return formatFloat(d.Confidence) }},
+	{name: "skills", value: func(d *models.Developer) string { return strings.Join(d.Skills, ";") }},
+	{name: "commit_count", value: func(d *models.Developer) string { return strconv.Itoa(d.CommitCount) }},
+	{name: "star_count", value: func(d *models.Developer) string { return strconv.Itoa(d.StarCount) }},
+	{name: "fork_count", value: func(d *models.Developer) string { return strconv.Itoa(d.ForkCount) }},
+	{name: "profile_url", value: func(d *models.Developer) string { return d.ProfileURL }},
+	{name: "specialties", sensitive: true, value: func(d *models.Developer) string {
+		return strings.Join(d.TechEvaluation.Specialties, ";")
+	}},
+	{name: "ai_evaluation", sensitive: true, value: func(d *models.Developer) string { return d.TechEvaluation.AIEvaluation }},
+}
+
+// ExportDevelopers 按搜索条件导出开发者，支持 csv（默认）和 json
+// 查询参数与 /api/search 相同，敏感字段按调用方角色隐藏
+func ExportDevelopers(c *gin.Context) {
+	format := c.DefaultQuery("format", "csv")
+	if format != "csv" && format != "json" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "format must be csv or json"})
+		return
+	}
+
+	limit, err := strconv.ParseInt(c.DefaultQuery("limit", strconv.Itoa(defaultExportLimit)), 10, 64)
+	if err != nil || limit <= 0 {
+		limit = defaultExportLimit
+	}
+	if limit > maxExportLimit {
+		limit = maxExportLimit
+	}
+
+	principal := auth.CurrentPrincipal(c)
+	filter, err := buildSearchQuery(c, principal)
+	if err != nil {
+		respondQueryError(c, err)
+		return
+	}
+	order, err := parseSearchOrder(c, "talent_rank", false)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	developers, err := models.SearchWithOptions(filter, 1, limit, options.Find().SetSort(order.sort()))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	for _, developer := range developers {
+		auth.RedactDeveloper(principal, developer)
+	}
+
+	filename := fmt.Sprintf("developers-%s.%s", time.Now().Format("20060102-150405"), format)
+	c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="%s"`, filename))
+
+	if format == "json" {
+		if developers == nil {
+			developers = make([]*models.Developer, 0)
+		}
+		c.JSON(http.StatusOK, developers)
+		return
+	}
+
+	columns := make([]exportColumn, 0, len(exportColumns))
+	for _, column := range exportColumns {
+		if column.sensitive && !principal.Can(auth.PermViewSensitive) {
+			continue
+		}
+		columns = append(columns, column)
+	}
+
+	c.Header("Content-Type", "text/csv; charset=utf-8")
+	c.Status(http.StatusOK)
+	writer := csv.NewWriter(c.Writer)
+	header := make([]string, len(columns))
+	for i, column := range columns {
+		header[i] = column.name
+	}
+	writer.Write(header)
+	for _, developer := range developers {
+		row := make([]string, len(columns))
+		for i, column := range columns {
+			row[i] = column.value(developer)
+		}
+		writer.Write(row)
+	}
+	writer.Flush()
+}
+
+func formatFloat(v float64) string {
+	return strconv.FormatFloat(v, 'f', 2, 64)
+}
@@ -0,0 +1,248 @@
+package handlers
+
+import (
+	"fmt"
+	"qinniu/internal/models"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+const (
+	defaultFacetSize = 10
+	maxFacetSize     = 50
+)
+
+// facetNames 支持的分面，顺序即 facets=all 时的顺序
+var facetNames = []string{"nations", "skills", "domains", "rank", "stars", "activity"}
+
+// numberRange 数值区间分面的一档，包含 Min，不包含下一档的 Min
+type numberRange struct {
+	Label string
+	Min   float64
+}
+
+var rankRanges = []numberRange{
+	{"0-20", 0}, {"20-40", 20}, {"40-60", 40}, {"60-80", 60}, {"80-100", 80},
+}
+
+var starRanges = []numberRange{
+	{"0-100", 0}, {"100-1000", 100}, {"1000-10000", 1000}, {"10000+", 10000},
+}
+
+// activityRange 最近活跃时间分面的一档，活跃时间距今不超过 Within
+type activityRange struct {
+	Label  string
+	Within time.Duration
+}
+
+var activityRanges = []activityRange{
+	{"7d", 7 * 24 * time.Hour},
+	{"30d", 30 * 24 * time.Hour},
+	{"90d", 90 * 24 * time.Hour},
+	{"365d", 365 * 24 * time.Hour},
+}
+
+// 超过一年未活跃，以及没有活跃时间的开发者
+const (
+	activityOlder   = "older"
+	activityUnknown = "unknown"
+)
+
+// requestedFacets 解析 facets 参数，如 facets=nations,skills 或 facets=all
+func requestedFacets(c *gin.Context) ([]string, error) {
+	param := strings.TrimSpace(c.Query("facets"))
+	if param == "" {
+		return nil, nil
+	}
+	if param == "all" || param == "true" {
+		return facetNames, nil
+	}
+
+	var names []string
+	seen := make(map[string]bool)
+	for _, name := range strings.Split(param, ",") {
+		name = strings.ToLower(strings.TrimSpace(name))
+		if name == "" || seen[name] {
+			continue
+		}
+		if !containsString(facetNames, name) {
+			return nil, fmt.Errorf("unknown facet '%s' (supported: %s)", name, strings.Join(facetNames, ", "))
+		}
+		seen[name] = true
+		names = append(names, name)
+	}
+	return names, nil
+}
+
+// facetPipelines 构建各分面的子管道，在搜索条件过滤后的结果集上统计
+func facetPipelines(c *gin.Context, names []string) map[string][]bson.M {
+	size, err := strconv.Atoi(c.DefaultQuery("facet_size", strconv.Itoa(defaultFacetSize)))
+	if err != nil || size <= 0 {
+		size = defaultFacetSize
+	}
+	if size > maxFacetSize {
+		size = maxFacetSize
+	}
+
+	pipelines := make(map[string][]bson.M, len(names))
+	for _, name := range names {
+		switch name {
+		case "nations":
+			// 与 nations 参数一致，只统计置信度不低于 60 的国家
+			pipelines[name] = []bson.M{
+				{"$match": bson.M{"nation": bson.M{"$nin": bson.A{"", nil}}, "nation_confidence": bson.M{"$gte": 60}}},
+				{"$group": bson.M{"_id": "$nation", "count": bson.M{"$sum": 1}}},
+				{"$sort": bson.D{{Key: "count", Value: -1}, {Key: "_id", Value: 1}}},
+				{"$limit": size},
+			}
+		case "skills":
+			pipelines[name] = []bson.M{
+				{"$unwind": "$skills"},
+				{"$group": bson.M{"_id": "$skills", "count": bson.M{"$sum": 1}}},
+				{"$sort": bson.D{{Key: "count", Value: -1}, {Key: "_id", Value: 1}}},
+				{"$limit": size},
+			}
+		case "domains":
+			pipelines[name] = domainFacet()
+		case "rank":
+			pipelines[name] = numberRangeFacet("$talent_rank", rankRanges)
+		case "stars":
+			pipelines[name] = numberRangeFacet("$star_count", starRanges)
+		case "activity":
+			pipelines[name] = activityFacet(time.Now())
+		}
+	}
+	return pipelines
+}
+
+// domainFacet 按领域统计，技能与领域技能列表有交集即计入该领域，一个开发者可以属于多个领域
+func domainFacet() []bson.M {
+	domains := make([]string, 0, len(domainSkills))
+	for domain := range domainSkills {
+		domains = append(domains, domain)
+	}
+	sort.Strings(domains)
+
+	matched := bson.A{}
+	for _, domain := range domains {
+		matched = append(matched, bson.M{"$cond": bson.A{
+			bson.M{"$gt": bson.A{
+				bson.M{"$size": bson.M{"$setIntersection": bson.A{
+					bson.M{"$ifNull": bson.A{"$skills", bson.A{}}},
+					domainSkills[domain],
+				}}},
+				0,
+			}},
+			domain,
+			nil,
+		}})
+	}
+
+	return []bson.M{
+		{"$project": bson.M{"_id": 0, "domain": matched}},
+		{"$unwind": "$domain"},
+		{"$match": bson.M{"domain": bson.M{"$ne": nil}}},
+		{"$group": bson.M{"_id": "$domain", "count": bson.M{"$sum": 1}}},
+		{"$sort": bson.D{{Key: "count", Value: -1}, {Key: "_id", Value: 1}}},
+	}
+}
+
+// numberRangeFacet 按数值区间统计，缺失的值算作 0
+func numberRangeFacet(field string, ranges []numberRange) []bson.M {
+	value := bson.M{"$ifNull": bson.A{field, 0}}
+	branches := bson.A{}
+	for i := 0; i < len(ranges)-1; i++ {
+		branches = append(branches, bson.M{
+			"case": bson.M{"$lt": bson.A{value, ranges[i+1].Min}},
+			"then": ranges[i].Label,
+		})
+	}
+
+	return []bson.M{
+		{"$group": bson.M{
+			"_id": bson.M{"$switch": bson.M{
+				"branches": branches,
+				"default":  ranges[len(ranges)-1].Label,
+			}},
+			"count": bson.M{"$sum": 1},
+		}},
+	}
+}
+
+// activityFacet 按最近活跃时间统计，各档互不重叠，如 30d 表示 7 到 30 天内活跃
+func activityFacet(now time.Time) []bson.M {
+	value := bson.M{"$ifNull": bson.A{"$last_active", time.Time{}}}
+	// 没有活跃时间的文档保存的是零值时间
+	branches := bson.A{bson.M{
+		"case": bson.M{"$lt": bson.A{value, time.Unix(0, 0)}},
+		"then": activityUnknown,
+	}}
+	for _, r := range activityRanges {
+		branches = append(branches, bson.M{
+			"case": bson.M{"$gte": bson.A{value, now.Add(-r.Within)}},
+			"then": r.Label,
+		})
+	}
+
+	return []bson.M{
+		{"$group": bson.M{
+			"_id": bson.M{"$switch": bson.M{
+				"branches": branches,
+				"default":  activityOlder,
+			}},
+			"count": bson.M{"$sum": 1},
+		}},
+	}
+}
+
+// orderFacets 区间分面按区间顺序返回，没有结果的区间计数为 0
+func orderFacets(facets map[string][]models.FacetBucket) {
+	for name, buckets := range facets {
+		var labels []string
+		switch name {
+		case "rank":
+			labels = rangeLabels(rankRanges)
+		case "stars":
+			labels = rangeLabels(starRanges)
+		case "activity":
+			for _, r := range activityRanges {
+				labels = append(labels, r.Label)
+			}
+			labels = append(labels, activityOlder, activityUnknown)
+		default:
+			continue
+		}
+
+		counts := make(map[string]int64, len(buckets))
+		for _, bucket := range buckets {
+			counts[bucket.Value] = bucket.Count
+		}
+		ordered := make([]models.FacetBucket, 0, len(labels))
+		for _, label := range labels {
+			ordered = append(ordered, models.FacetBucket{Value: label, Count: counts[label]})
+		}
+		facets[name] = ordered
+	}
+}
+
+func rangeLabels(ranges []numberRange) []string {
+	labels := make([]string, 0, len(ranges))
+	for _, r := range ranges {
+		labels = append(labels, r.Label)
+	}
+	return labels
+}
+
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,120 @@
+package handlers
+
+import (
+	"io"
+	"net/http"
+	"qinniu/internal/models"
+	"qinniu/internal/pkg/events"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"golang.org/x/net/websocket"
+)
+
+// 长时间没有事件时发送心跳，避免代理断开连接
+const eventHeartbeatInterval = 15 * time.Second
+
+// StreamCrawlJobEvents 通过 SSE 推送爬取任务的实时进度
+// 连接后先推送一次任务快照，之后推送任务中每个用户的事件，AI 评估可能在任务完成后才结束
+func StreamCrawlJobEvents(c *gin.Context) {
+	// 先订阅再读取快照，避免丢失两者之间的事件
+	sub := events.Subscribe()
+	defer sub.Close()
+
+	job, err := models.FindJobByID(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "任务不存在"})
+		return
+	}
+	matches := jobEventMatcher(job)
+
+	c.Header("Cache-Control", "no-cache")
+	c.Header("Connection", "keep-alive")
+	c.Header("X-Accel-Buffering", "no")
+	c.SSEvent("snapshot", job)
+
+	heartbeat := time.NewTicker(eventHeartbeatInterval)
+	defer heartbeat.Stop()
+
+	c.Stream(func(w io.Writer) bool {
+		select {
+		case event, ok := <-sub.C:
+			if !ok {
+				return false
+			}
+			if matches(event) {
+				c.SSEvent(event.Type, event)
+			}
+			return true
+		case <-heartbeat.C:
+			c.SSEvent("ping", gin.H{"time": time.Now()})
+			return true
+		case <-c.Request.Context().Done():
+			return false
+		}
+	})
+}
+
+// StreamCrawlJobEventsWS 通过 WebSocket 推送爬取任务的实时进度，消息内容与 SSE 相同
+func StreamCrawlJobEventsWS(c *gin.Context) {
+	sub := events.Subscribe()
+	defer sub.Close()
+
+	job, err := models.FindJobByID(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "任务不存在"})
+		return
+	}
+	matches := jobEventMatcher(job)
+
+	server := websocket.Server{Handler: func(ws *websocket.Conn) {
+		defer ws.Close()
+
+		if err := websocket.JSON.Send(ws, gin.H{"type": "snapshot", "job": job}); err != nil {
+			return
+		}
+
+		// 客户端不需要发送消息，读取只用于发现连接关闭
+		closed := make(chan struct{})
+		go func() {
+			defer close(closed)
+			var message string
+			for websocket.Message.Receive(ws, &message) == nil {
+			}
+		}()
+
+		heartbeat := time.NewTicker(eventHeartbeatInterval)
+		defer heartbeat.Stop()
+
+		for {
+			select {
+			case event, ok := <-sub.C:
+				if !ok {
+					return
+				}
+				if !matches(event) {
+					continue
+				}
+				if err := websocket.JSON.Send(ws, event); err != nil {
+					return
+				}
+			case <-heartbeat.C:
+				if err := websocket.JSON.Send(ws, gin.H{"type": "ping", "time": time.Now()}); err != nil {
+					return
+				}
+			case <-closed:
+				return
+			}
+		}
+	}}
+	server.ServeHTTP(c.Writer, c.Request)
+}
+
+// jobEventMatcher 判断事件是否属于任务
+// 任务执行器、爬虫和评估服务的事件都带有任务 ID，后台刷新或其他任务爬取同一用户的事件不会混入
+func jobEventMatcher(job *models.CrawlJob) func(events.Event) bool {
+	jobID := job.ID.Hex()
+	return func(event events.Event) bool {
+		return event.JobID == jobID
+	}
+}
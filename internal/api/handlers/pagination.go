@@ -0,0 +1,286 @@
+package handlers
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+const (
+	defaultPageSize = 10
+	maxPageSize     = 100
+	maxSortKeys     = 3
+)
+
+// sortableFields 可以排序的字段
+var sortableFields = map[string]bool{
+	"talent_rank":       true,
+	"star_count":        true,
+	"commit_count":      true,
+	"fork_count":        true,
+	"confidence":        true,
+	"nation_confidence": true,
+	"last_active":       true,
+	"last_updated":      true,
+	"created_at":        true,
+	"username":          true,
+	"name":              true,
+}
+
+// 全文搜索的默认排序字段，只能在全文搜索中使用
+const searchScoreField = "search_score"
+
+// sortKey 排序字段和方向，1 升序，-1 降序
+type sortKey struct {
+	Field string
+	Order int
+}
+
+// searchOrder 搜索结果的排序，最后总是按 _id 升序，保证相同排序值的结果顺序稳定
+type searchOrder []sortKey
+
+var errInvalidCursor = errors.New("invalid cursor")
+
+// cursorToken 游标中保存上一页最后一条结果的排序值和 _id
+type cursorToken struct {
+	Sort   string             `bson:"s"`
+	Values bson.A             `bson:"v"`
+	ID     primitive.ObjectID `bson:"id"`
+}
+
+// pagination 分页参数，Cursor 不为空时忽略 Page
+type pagination struct {
+	Page     int64
+	PageSize int64
+	Cursor   *cursorToken
+}
+
+// parsePagination 解析 page、page_size 和 cursor 参数
+func parsePagination(c *gin.Context, order searchOrder) (*pagination, error) {
+	page, err := strconv.ParseInt(c.DefaultQuery("page", "1"), 10, 64)
+	if err != nil || page < 1 {
+		page = 1
+	}
+	pageSize, err := strconv.ParseInt(c.DefaultQuery("page_size", strconv.Itoa(defaultPageSize)), 10, 64)
+	if err != nil || pageSize <= 0 {
+		pageSize = defaultPageSize
+	}
+	if pageSize > maxPageSize {
+		pageSize = maxPageSize
+	}
+
+	p := &pagination{Page: page, PageSize: pageSize}
+	if raw := c.Query("cursor"); raw != "" {
+		token, err := decodeCursor(raw)
+		if err != nil {
+			return nil, err
+		}
+		if token.Sort != order.signature() || len(token.Values) != len(order) {
+			return nil, fmt.Errorf("cursor does not match sort_by, request the first page again")
+		}
+		p.Cursor = token
+		p.Page = 1
+	}
+	return p, nil
+}
+
+// stages 分页阶段：有游标时跳过游标之前的结果，否则按页码跳过
+func (p *pagination) stages(order searchOrder) []bson.M {
+	var stages []bson.M
+	if p.Cursor != nil {
+		stages = append(stages, bson.M{"$match": order.after(p.Cursor)})
+	}
+	stages = append(stages, bson.M{"$sort": order.sort()})
+	if p.Cursor == nil && p.Page > 1 {
+		stages = append(stages, bson.M{"$skip": (p.Page - 1) * p.PageSize})
+	}
+	return append(stages, bson.M{"$limit": p.PageSize})
+}
+
+// nextCursor 根据本页最后一条结果生成下一页的游标，结果不满一页时没有下一页
+func (p *pagination) nextCursor(order searchOrder, count int, last interface{}) (string, error) {
+	if int64(count) < p.PageSize || last == nil {
+		return "", nil
+	}
+
+	data, err := bson.Marshal(last)
+	if err != nil {
+		return "", err
+	}
+	raw := bson.Raw(data)
+	token := cursorToken{Sort: order.signature(), Values: bson.A{}}
+	for _, key := range order {
+		var value interface{}
+		if v, err := raw.LookupErr(strings.Split(key.Field, ".")...); err == nil {
+			value = v
+		}
+		token.Values = append(token.Values, value)
+	}
+	id, ok := raw.Lookup("_id").ObjectIDOK()
+	if !ok {
+		return "", fmt.Errorf("结果缺少 _id")
+	}
+	token.ID = id
+
+	encoded, err := bson.Marshal(token)
+	if err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(encoded), nil
+}
+
+func decodeCursor(raw string) (*cursorToken, error) {
+	data, err := base64.RawURLEncoding.DecodeString(raw)
+	if err != nil {
+		return nil, errInvalidCursor
+	}
+	var token cursorToken
+	if err := bson.Unmarshal(data, &token); err != nil || token.ID.IsZero() {
+		return nil, errInvalidCursor
+	}
+	// 游标由客户端传回，只接受标量，避免把文档当作查询运算符
+	for _, value := range token.Values {
+		switch value.(type) {
+		case nil, string, bool, int32, int64, float64, primitive.DateTime:
+		default:
+			return nil, errInvalidCursor
+		}
+	}
+	return &token, nil
+}
+
+// parseSearchOrder 解析 sort_by 参数，如 sort_by=talent_rank:desc,star_count
+// 未指定方向的字段使用 sort_asc（默认降序）；未指定 sort_by 时使用 defaultField
+// allowScore 为 true 时允许按全文搜索分数排序
+func parseSearchOrder(c *gin.Context, defaultField string, allowScore bool) (searchOrder, error) {
+	defaultOrder := -1
+	if c.Query("sort_asc") == "true" {
+		defaultOrder = 1
+	}
+
+	param := strings.TrimSpace(c.Query("sort_by"))
+	if param == "" {
+		return searchOrder{{Field: defaultField, Order: defaultOrder}}, nil
+	}
+
+	var order searchOrder
+	seen := make(map[string]bool)
+	for _, part := range strings.Split(param, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+
+		field, direction := part, ""
+		if i := strings.LastIndex(part, ":"); i >= 0 {
+			field, direction = part[:i], strings.ToLower(part[i+1:])
+		}
+		if !sortableFields[field] && !(allowScore && field == searchScoreField) {
+			return nil, fmt.Errorf("cannot sort by '%s' (supported: %s)", field, strings.Join(sortableFieldNames(allowScore), ", "))
+		}
+		if seen[field] {
+			return nil, fmt.Errorf("duplicate sort field '%s'", field)
+		}
+		seen[field] = true
+
+		key := sortKey{Field: field, Order: defaultOrder}
+		switch direction {
+		case "":
+		case "asc":
+			key.Order = 1
+		case "desc":
+			key.Order = -1
+		default:
+			return nil, fmt.Errorf("invalid sort direction '%s' for '%s', expected asc or desc", direction, field)
+		}
+		order = append(order, key)
+	}
+
+	if len(order) == 0 {
+		return searchOrder{{Field: defaultField, Order: defaultOrder}}, nil
+	}
+	if len(order) > maxSortKeys {
+		return nil, fmt.Errorf("too many sort fields (max %d)", maxSortKeys)
+	}
+	return order, nil
+}
+
+func sortableFieldNames(allowScore bool) []string {
+	names := make([]string, 0, len(sortableFields)+1)
+	for name := range sortableFields {
+		names = append(names, name)
+	}
+	if allowScore {
+		names = append(names, searchScoreField)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// sort $sort 阶段的排序条件
+func (o searchOrder) sort() bson.D {
+	d := make(bson.D, 0, len(o)+1)
+	for _, key := range o {
+		d = append(d, bson.E{Key: key.Field, Value: key.Order})
+	}
+	return append(d, bson.E{Key: "_id", Value: 1})
+}
+
+// signature 排序的标识，游标只能用于生成它的排序
+func (o searchOrder) signature() string {
+	parts := make([]string, 0, len(o))
+	for _, key := range o {
+		parts = append(parts, fmt.Sprintf("%s:%d", key.Field, key.Order))
+	}
+	return strings.Join(parts, ",")
+}
+
+// after 排在游标之后的结果：前面的排序字段相等，且当前字段排在游标值之后
+//
+//	(k1 > v1) OR (k1 = v1 AND k2 > v2) OR ... OR (k1 = v1 AND ... AND _id > id)
+func (o searchOrder) after(token *cursorToken) bson.M {
+	var branches []bson.M
+	equal := bson.M{}
+	for i, key := range o {
+		value := token.Values[i]
+		if cond, ok := beyond(key.Order, value); ok {
+			branch := bson.M{key.Field: cond}
+			for field, v := range equal {
+				branch[field] = v
+			}
+			branches = append(branches, branch)
+		}
+		// 等于 null 时匹配 null 和缺失的字段，与排序时一致
+		equal[key.Field] = value
+	}
+
+	last := bson.M{"_id": bson.M{"$gt": token.ID}}
+	for field, v := range equal {
+		last[field] = v
+	}
+	branches = append(branches, last)
+
+	return bson.M{"$or": branches}
+}
+
+// beyond 排在 value 之后的条件，null 排在所有值之前
+func beyond(order int, value interface{}) (bson.M, bool) {
+	if value == nil {
+		if order == 1 {
+			return bson.M{"$ne": nil}, true
+		}
+		return nil, false // 降序时没有排在 null 之后的值
+	}
+	if order == 1 {
+		return bson.M{"$gt": value}, true
+	}
+	// 降序时排在后面的还包括 null 和缺失的字段
+	return bson.M{"$not": bson.M{"$gte": value}}, true
+}
@@ -0,0 +1,57 @@
+package handlers
+
+import (
+	"net/http"
+	"qinniu/internal/models"
+	"qinniu/internal/pkg/ai"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ListPrompts 列出提示词模板及其版本
+func ListPrompts(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{"prompts": ai.DefaultPrompts().List()})
+}
+
+// RenderPrompt 用数据库中的开发者渲染提示词模板，用于调试提示词
+// developer 参数为开发者 ID 或用户名；summary 模板使用已保存的评估结果
+func RenderPrompt(c *gin.Context) {
+	prompt, err := ai.DefaultPrompts().Get(c.Param("name"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+
+	ref := c.Query("developer")
+	if ref == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "developer is required"})
+		return
+	}
+	developer, err := models.FindByID(ref)
+	if err != nil || developer == nil {
+		developer, err = models.FindByUsername(ref)
+	}
+	if err != nil || developer == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "开发者不存在"})
+		return
+	}
+
+	data := &ai.PromptData{DeveloperProfile: ai.ProfileFromDeveloper(developer)}
+	if evaluation := developer.TechEvaluation; evaluation.AIEvaluation != "" {
+		data.Evaluation = &ai.EvaluationResult{
+			Specialties:   evaluation.Specialties,
+			Experience:    evaluation.Experience,
+			AIEvaluation:  evaluation.AIEvaluation,
+			Reasons:       evaluation.Reasons,
+			PromptVersion: evaluation.PromptVersion,
+			LastEvaluated: evaluation.LastEvaluated,
+		}
+	}
+
+	rendered, err := prompt.Render(data)
+	if err != nil {
+		c.JSON(http.StatusUnprocessableEntity, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, rendered)
+}
@@ -0,0 +1,242 @@
+package handlers
+
+import (
+	"html"
+	"net/http"
+	"os"
+	"qinniu/internal/models"
+	"qinniu/internal/pkg/auth"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+const (
+	defaultRelevanceWeight = 0.7
+	snippetRadius          = 60 // 命中位置前后保留的字符数
+	maxHighlights          = 3
+)
+
+// relevanceWeight 排序时文本相关度所占的权重，其余为 TalentRank
+func relevanceWeight() float64 {
+	if v, err := strconv.ParseFloat(os.Getenv("SEARCH_RELEVANCE_WEIGHT"), 64); err == nil && v >= 0 && v <= 1 {
+		return v
+	}
+	return defaultRelevanceWeight
+}
+
+// searchByText 全文搜索，默认按相关度与 TalentRank 的加权分数排序
+// 相关度 r 归一化为 r/(r+1)，TalentRank 归一化为 talent_rank/100
+func searchByText(c *gin.Context, principal *auth.Principal, filter bson.M, text string, order searchOrder, pager *pagination, facets []string) {
+	weight := relevanceWeight()
+
+	projection := searchProjection()
+	projection["bio"] = 1
+	projection["repo_summaries"] = 1
+	projection["tech_evaluation.ai_evaluation"] = 1
+	projection["tech_evaluation.status"] = 1
+	projection["relevance"] = 1
+	projection["search_score"] = 1
+
+	pipeline := []bson.M{
+		{"$match": filter},
+		{"$addFields": bson.M{"relevance": bson.M{"$meta": "textScore"}}},
+		{"$addFields": bson.M{"search_score": bson.M{"$add": bson.A{
+			bson.M{"$multiply": bson.A{weight, bson.M{"$divide": bson.A{"$relevance", bson.M{"$add": bson.A{"$relevance", 1}}}}}},
+			bson.M{"$multiply": bson.A{1 - weight, bson.M{"$divide": bson.A{bson.M{"$ifNull": bson.A{"$talent_rank", 0}}, 100}}}},
+		}}}},
+	}
+	// search_score 是计算出的字段，游标条件在计算之后匹配
+	pipeline = append(pipeline, pager.stages(order)...)
+	pipeline = append(pipeline, bson.M{"$project": projection})
+
+	developers, err := models.AggregateTextSearch(pipeline)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	var last interface{}
+	if len(developers) > 0 {
+		last = developers[len(developers)-1]
+	}
+	nextCursor, err := pager.nextCursor(order, len(developers), last)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	terms := textSearchTerms(text)
+	allowSensitive := principal.Can(auth.PermViewSensitive)
+	for _, developer := range developers {
+		developer.Highlights = buildHighlights(developer, terms, allowSensitive)
+		// 评估内容只用于生成片段，返回的字段与普通搜索保持一致
+		developer.TechEvaluation = models.TechEvaluation{}
+		auth.RedactDeveloper(principal, &developer.Developer)
+	}
+
+	total, err := models.CountDevelopers(filter)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	response := gin.H{
+		"page":       pager.Page,
+		"page_size":  pager.PageSize,
+		"total":      total,
+		"developers": developers,
+	}
+	if nextCursor != "" {
+		response["next_cursor"] = nextCursor
+	}
+	// 相关度只能在全文搜索的主管道中计算，分面统计单独聚合
+	if len(facets) > 0 {
+		facetResults, err := models.AggregateFacets(filter, facetPipelines(c, facets))
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		orderFacets(facetResults)
+		response["facets"] = facetResults
+	}
+	c.JSON(http.StatusOK, response)
+}
+
+// textSearchTerms 提取用于高亮的词，忽略以 - 开头的排除词
+func textSearchTerms(text string) []string {
+	var terms []string
+	for _, field := range strings.Fields(strings.ReplaceAll(text, `"`, " ")) {
+		if strings.HasPrefix(field, "-") {
+			continue
+		}
+		terms = append(terms, strings.ToLower(field))
+	}
+	return terms
+}
+
+// buildHighlights 在各字段中查找命中的词，生成带 <em> 标记的片段
+// 没有 view_sensitive 权限时不从 AI 评估中生成片段
+func buildHighlights(developer *models.ScoredDeveloper, terms []string, allowSensitive bool) []models.Highlight {
+	type source struct {
+		field string
+		text  string
+	}
+	sources := []source{
+		{"name", developer.Name},
+		{"username", developer.Username},
+		{"bio", developer.Bio},
+	}
+	for _, repo := range developer.RepoSummaries {
+		text := repo.Name
+		if repo.Description != "" {
+			text += ": " + repo.Description
+		}
+		if len(repo.Topics) > 0 {
+			text += " [" + strings.Join(repo.Topics, ", ") + "]"
+		}
+		sources = append(sources, source{"repositories", text})
+	}
+	// 没有 view_sensitive 权限时只在通过审核的评估中生成片段
+	if allowSensitive || developer.TechEvaluation.IsApproved() {
+		sources = append(sources, source{"tech_evaluation.ai_evaluation", developer.TechEvaluation.AIEvaluation})
+	}
+
+	var highlights []models.Highlight
+	for _, src := range sources {
+		if snippet, ok := highlightSnippet(src.text, terms); ok {
+			highlights = append(highlights, models.Highlight{Field: src.field, Snippet: snippet})
+			if len(highlights) >= maxHighlights {
+				break
+			}
+		}
+	}
+	return highlights
+}
+
+// highlightSnippet 截取第一个命中位置附近的文本，转义 HTML 后用 <em> 标记命中的词
+// 在原文上逐字符忽略大小写匹配，不使用转小写后的副本，转小写可能改变字节长度导致位置错位
+func highlightSnippet(text string, terms []string) (string, bool) {
+	if text == "" || len(terms) == 0 {
+		return "", false
+	}
+
+	runes := []rune(text)
+	termRunes := make([][]rune, 0, len(terms))
+	for _, term := range terms {
+		if term != "" {
+			termRunes = append(termRunes, []rune(term))
+		}
+	}
+
+	first := -1
+	for i := range runes {
+		if matchLen(runes, i, termRunes) > 0 {
+			first = i
+			break
+		}
+	}
+	if first < 0 {
+		return "", false
+	}
+
+	// 按字符截取，避免切断多字节字符
+	start := first - snippetRadius
+	if start < 0 {
+		start = 0
+	}
+	end := first + snippetRadius
+	if end > len(runes) {
+		end = len(runes)
+	}
+
+	var sb strings.Builder
+	if start > 0 {
+		sb.WriteString("…")
+	}
+	sb.WriteString(markTerms(runes[start:end], termRunes))
+	if end < len(runes) {
+		sb.WriteString("…")
+	}
+	return sb.String(), true
+}
+
+// markTerms 转义 HTML 并标记所有命中的词
+func markTerms(runes []rune, terms [][]rune) string {
+	var sb strings.Builder
+	for i := 0; i < len(runes); {
+		if matched := matchLen(runes, i, terms); matched > 0 {
+			sb.WriteString("<em>")
+			sb.WriteString(html.EscapeString(string(runes[i : i+matched])))
+			sb.WriteString("</em>")
+			i += matched
+			continue
+		}
+		sb.WriteString(html.EscapeString(string(runes[i])))
+		i++
+	}
+	return sb.String()
+}
+
+// matchLen 从第 i 个字符开始命中的最长词的字符数，没有命中时返回 0
+func matchLen(runes []rune, i int, terms [][]rune) int {
+	matched := 0
+	for _, term := range terms {
+		if len(term) <= matched || i+len(term) > len(runes) {
+			continue
+		}
+		ok := true
+		for j, r := range term {
+			if !strings.EqualFold(string(runes[i+j]), string(r)) {
+				ok = false
+				break
+			}
+		}
+		if ok {
+			matched = len(term)
+		}
+	}
+	return matched
+}
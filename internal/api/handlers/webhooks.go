@@ -0,0 +1,173 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"qinniu/internal/models"
+	"qinniu/internal/webhook"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// WebhookRequest 创建或更新 webhook 订阅的请求
+type WebhookRequest struct {
+	URL         string                `json:"url" binding:"required"`
+	Events      []string              `json:"events" binding:"required"`
+	Filters     models.WebhookFilters `json:"filters"`
+	Secret      string                `json:"secret"` // 不填时自动生成
+	Description string                `json:"description"`
+	Active      *bool                 `json:"active"`
+}
+
+// validate 校验订阅地址和事件类型
+func (r *WebhookRequest) validate() error {
+	u, err := url.Parse(r.URL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return fmt.Errorf("url must be an absolute http(s) URL")
+	}
+	if len(r.Events) == 0 {
+		return fmt.Errorf("events is empty")
+	}
+	for _, event := range r.Events {
+		if !webhook.IsValidEvent(event) {
+			return fmt.Errorf("unsupported event: %s", event)
+		}
+	}
+	return nil
+}
+
+// CreateWebhook 创建订阅，密钥只在创建时返回一次
+func CreateWebhook(c *gin.Context) {
+	var req WebhookRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err := req.validate(); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	secret := req.Secret
+	if secret == "" {
+		generated, err := webhook.GenerateSecret()
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		secret = generated
+	}
+
+	subscription := &models.WebhookSubscription{
+		URL:         req.URL,
+		Events:      req.Events,
+		Filters:     req.Filters,
+		Secret:      secret,
+		Description: req.Description,
+		Active:      req.Active == nil || *req.Active,
+	}
+	if err := subscription.Create(); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	webhook.InvalidateCache()
+
+	c.JSON(http.StatusCreated, gin.H{
+		"webhook": subscription,
+		"secret":  secret,
+	})
+}
+
+// ListWebhooks 列出所有订阅
+func ListWebhooks(c *gin.Context) {
+	subscriptions, err := models.ListWebhooks()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"webhooks": subscriptions})
+}
+
+// GetWebhook 获取订阅详情
+func GetWebhook(c *gin.Context) {
+	subscription, err := models.FindWebhookByID(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "订阅不存在"})
+		return
+	}
+	c.JSON(http.StatusOK, subscription)
+}
+
+// UpdateWebhook 更新订阅，密钥不能修改
+func UpdateWebhook(c *gin.Context) {
+	subscription, err := models.FindWebhookByID(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "订阅不存在"})
+		return
+	}
+
+	var req WebhookRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err := req.validate(); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	subscription.URL = req.URL
+	subscription.Events = req.Events
+	subscription.Filters = req.Filters
+	subscription.Description = req.Description
+	if req.Active != nil {
+		subscription.Active = *req.Active
+	}
+	if err := subscription.Update(); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	webhook.InvalidateCache()
+
+	c.JSON(http.StatusOK, subscription)
+}
+
+// DeleteWebhook 删除订阅
+func DeleteWebhook(c *gin.Context) {
+	subscription, err := models.FindWebhookByID(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "订阅不存在"})
+		return
+	}
+
+	if err := subscription.Delete(); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	webhook.InvalidateCache()
+
+	c.JSON(http.StatusOK, gin.H{"message": "订阅已删除"})
+}
+
+// ListWebhookDeliveries 查询订阅的投递记录
+func ListWebhookDeliveries(c *gin.Context) {
+	subscription, err := models.FindWebhookByID(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "订阅不存在"})
+		return
+	}
+
+	limit, err := strconv.ParseInt(c.DefaultQuery("limit", "50"), 10, 64)
+	if err != nil || limit <= 0 || limit > 500 {
+		limit = 50
+	}
+
+	deliveries, err := models.ListDeliveries(subscription.ID, c.Query("status"), limit)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"deliveries": deliveries})
+}
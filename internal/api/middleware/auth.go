@@ -1,13 +1,82 @@
 package middleware
 
 import (
+	"errors"
+	"log"
+	"net/http"
+	"qinniu/internal/pkg/auth"
+
 	"github.com/gin-gonic/gin"
 )
 
+// AuthRequired 要求请求携带有效的 API 密钥或 JWT
 func AuthRequired() gin.HandlerFunc {
 	return func(c *gin.Context) {
-		// 这里应该实现实际的认证逻辑
-		// 现在我们只是返回一个示例响应
+		if abortAuthFailureLimited(c) {
+			return
+		}
+		principal, err := auth.Authenticate(c)
+		if err != nil {
+			abortUnauthenticated(c, err)
+			return
+		}
+
+		auth.SetPrincipal(c, principal)
+		c.Next()
+	}
+}
+
+// OptionalAuth 用于公开接口：没有凭证时按匿名用户处理，凭证无效时仍然拒绝
+func OptionalAuth() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if abortAuthFailureLimited(c) {
+			return
+		}
+		principal, err := auth.Authenticate(c)
+		if err != nil {
+			if errors.Is(err, auth.ErrMissingCredentials) {
+				c.Next()
+				return
+			}
+			abortUnauthenticated(c, err)
+			return
+		}
+
+		auth.SetPrincipal(c, principal)
+		c.Next()
+	}
+}
+
+// RequirePermission 要求调用方的角色拥有指定权限，需要放在 AuthRequired 之后
+func RequirePermission(permission auth.Permission) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		principal := auth.CurrentPrincipal(c)
+		if !principal.Can(permission) {
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{
+				"error":      "权限不足",
+				"role":       principal.Role,
+				"permission": permission,
+			})
+			return
+		}
 		c.Next()
 	}
 }
+
+func abortUnauthenticated(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, auth.ErrMissingCredentials):
+		c.Header("WWW-Authenticate", `Bearer realm="api"`)
+		c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "缺少认证信息"})
+	case errors.Is(err, auth.ErrInvalidAPIKey), errors.Is(err, auth.ErrAPIKeyInactive),
+		errors.Is(err, auth.ErrInvalidToken), errors.Is(err, auth.ErrTokenExpired),
+		errors.Is(err, auth.ErrJWTDisabled):
+		// 认证中间件在限流之前执行，凭证无效的请求按 IP 单独限流
+		recordAuthFailure(c)
+		c.Header("WWW-Authenticate", `Bearer realm="api", error="invalid_token"`)
+		c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+	default:
+		log.Printf("Error authenticating request: %v", err)
+		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": "认证服务不可用"})
+	}
+}
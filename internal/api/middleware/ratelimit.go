@@ -0,0 +1,95 @@
+package middleware
+
+import (
+	"math"
+	"net/http"
+	"qinniu/internal/pkg/auth"
+	"qinniu/internal/pkg/ratelimit"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+var (
+	apiLimiter         = ratelimit.NewLimiter("api")
+	crawlLimiter       = ratelimit.NewLimiter("crawl")
+	authFailureLimiter = ratelimit.NewLimiter("auth_failure")
+)
+
+// RateLimit 通用接口限流，已认证的请求按 API 密钥计数，未认证的按客户端 IP 计数
+// 配额由 RATE_LIMIT 和 RATE_LIMIT_DURATION 配置，需要放在认证中间件之后
+func RateLimit() gin.HandlerFunc {
+	return rateLimit(apiLimiter, ratelimit.LoadLimit("RATE_LIMIT", "RATE_LIMIT_DURATION", 100, time.Hour))
+}
+
+// CrawlRateLimit 会触发爬取、消耗 GitHub 配额的接口单独限流，配额更严格
+func CrawlRateLimit() gin.HandlerFunc {
+	return rateLimit(crawlLimiter, ratelimit.LoadLimit("CRAWL_RATE_LIMIT", "CRAWL_RATE_LIMIT_DURATION", 10, time.Hour))
+}
+
+func rateLimit(limiter *ratelimit.Limiter, limit ratelimit.Limit) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		result := limiter.Allow(c.Request.Context(), clientKey(c), limit, 1)
+
+		c.Header("RateLimit-Policy", limit.Policy())
+		c.Header("RateLimit-Limit", strconv.Itoa(result.Limit))
+		c.Header("RateLimit-Remaining", strconv.Itoa(result.Remaining))
+		c.Header("RateLimit-Reset", strconv.Itoa(ceilSeconds(result.Reset)))
+
+		if !result.Allowed {
+			retryAfter := ceilSeconds(result.RetryAfter)
+			c.Header("Retry-After", strconv.Itoa(retryAfter))
+			c.AbortWithStatusJSON(http.StatusTooManyRequests, gin.H{
+				"error":       "请求过于频繁，请稍后重试",
+				"retry_after": retryAfter,
+			})
+			return
+		}
+		c.Next()
+	}
+}
+
+// authFailureLimit 同一 IP 认证失败的配额，由 AUTH_FAILURE_LIMIT 和 AUTH_FAILURE_LIMIT_DURATION 配置
+func authFailureLimit() ratelimit.Limit {
+	return ratelimit.LoadLimit("AUTH_FAILURE_LIMIT", "AUTH_FAILURE_LIMIT_DURATION", 20, time.Hour)
+}
+
+// abortAuthFailureLimited 同一 IP 认证失败的配额用完时，携带凭证的请求返回 429
+// 在校验凭证之前调用，防止猜测密钥；只查看剩余配额，不消耗令牌
+func abortAuthFailureLimited(c *gin.Context) bool {
+	if !auth.HasCredentials(c) {
+		return false
+	}
+	limit := authFailureLimit()
+	result := authFailureLimiter.Allow(c.Request.Context(), "ip:"+c.ClientIP(), limit, 0)
+	if result.Remaining >= 1 {
+		return false
+	}
+
+	// 补充一个令牌所需的时间
+	retryAfter := ceilSeconds(limit.Period / time.Duration(limit.Capacity))
+	c.Header("Retry-After", strconv.Itoa(retryAfter))
+	c.AbortWithStatusJSON(http.StatusTooManyRequests, gin.H{
+		"error":       "认证失败次数过多，请稍后重试",
+		"retry_after": retryAfter,
+	})
+	return true
+}
+
+// recordAuthFailure 记录一次凭证无效的认证失败
+func recordAuthFailure(c *gin.Context) {
+	authFailureLimiter.Allow(c.Request.Context(), "ip:"+c.ClientIP(), authFailureLimit(), 1)
+}
+
+// clientKey 限流计数的对象
+func clientKey(c *gin.Context) string {
+	if principal := auth.CurrentPrincipal(c); principal.KeyID != "" {
+		return "key:" + principal.KeyID
+	}
+	return "ip:" + c.ClientIP()
+}
+
+func ceilSeconds(d time.Duration) int {
+	return int(math.Ceil(d.Seconds()))
+}
@@ -3,6 +3,7 @@ package api
 import (
 	"qinniu/internal/api/handlers"
 	"qinniu/internal/api/middleware"
+	"qinniu/internal/pkg/auth"
 
 	"github.com/gin-gonic/gin"
 )
@@ -13,19 +14,93 @@ func SetupRoutes(r *gin.Engine) {
 
 	api := r.Group("/api")
 	{
-		api.GET("/developers/:id", handlers.GetDeveloper)
-		api.GET("/search", handlers.SearchDevelopers)
+		api.POST("/auth/token", middleware.RateLimit(), handlers.IssueToken)
+
+		// 公开接口，携带凭证时按角色决定是否显示敏感字段
+		public := api.Group("/")
+		public.Use(middleware.OptionalAuth(), middleware.RateLimit())
+		{
+			public.GET("/developers/:id", handlers.GetDeveloper)
+			public.GET("/search", handlers.SearchDevelopers)
+			public.GET("/nations", handlers.GetAllNations)
+		}
 
 		// 需要认证的路由
 		authorized := api.Group("/")
-		authorized.Use(middleware.AuthRequired())
+		authorized.Use(middleware.AuthRequired(), middleware.RateLimit())
 		{
-			authorized.POST("/developers", handlers.CreateDeveloper)
-			authorized.PUT("/developers/:id", handlers.UpdateDeveloper)
-			authorized.DELETE("/developers/:id", handlers.DeleteDeveloper)
-			authorized.POST("/run-crawler", handlers.RunCrawlerHandler)
-		}
+			authorized.GET("/auth/me", handlers.GetCurrentPrincipal)
+
+			authorized.GET("/export/developers", middleware.RequirePermission(auth.PermExport), handlers.ExportDevelopers)
+
+			// 触发爬取的接口额外使用更严格的配额
+			crawlQuota := middleware.CrawlRateLimit()
+
+			edit := middleware.RequirePermission(auth.PermEdit)
+			authorized.POST("/developers", edit, handlers.CreateDeveloper)
+			authorized.PUT("/developers/:id", edit, handlers.UpdateDeveloper)
+			authorized.POST("/developers/:id/accounts", edit, crawlQuota, handlers.LinkDeveloperAccount)
+			authorized.DELETE("/developers/:id/accounts/:key", edit, handlers.UnlinkDeveloperAccount)
+			authorized.DELETE("/developers/:id", middleware.RequirePermission(auth.PermDelete), handlers.DeleteDeveloper)
+
+			watch := middleware.RequirePermission(auth.PermWatch)
+			authorized.PUT("/developers/:id/watch", watch, handlers.WatchDeveloper)
+			authorized.DELETE("/developers/:id/watch", watch, handlers.UnwatchDeveloper)
 
-		api.GET("/nations", handlers.GetAllNations)
+			crawl := middleware.RequirePermission(auth.PermCrawl)
+			authorized.POST("/run-crawler", crawl, crawlQuota, handlers.RunCrawlerHandler)
+			authorized.GET("/jobs/:id", crawl, handlers.GetCrawlJob)
+			authorized.GET("/jobs/:id/events", crawl, handlers.StreamCrawlJobEvents)
+			authorized.GET("/jobs/:id/ws", crawl, handlers.StreamCrawlJobEventsWS)
+
+			webhooks := authorized.Group("/webhooks")
+			webhooks.Use(middleware.RequirePermission(auth.PermManageWebhooks))
+			{
+				webhooks.POST("", handlers.CreateWebhook)
+				webhooks.GET("", handlers.ListWebhooks)
+				webhooks.GET("/:id", handlers.GetWebhook)
+				webhooks.PUT("/:id", handlers.UpdateWebhook)
+				webhooks.DELETE("/:id", handlers.DeleteWebhook)
+				webhooks.GET("/:id/deliveries", handlers.ListWebhookDeliveries)
+			}
+
+			// 提示词模板调试，渲染结果包含敏感字段
+			prompts := authorized.Group("/prompts")
+			prompts.Use(edit)
+			{
+				prompts.GET("", handlers.ListPrompts)
+				prompts.GET("/:name/render", handlers.RenderPrompt)
+			}
+
+			// AI 评估的人工审核
+			review := middleware.RequirePermission(auth.PermReview)
+			authorized.GET("/evaluations/queue", review, handlers.ListEvaluationQueue)
+			authorized.POST("/developers/:id/evaluation/approve", review, handlers.ApproveEvaluation)
+			authorized.PUT("/developers/:id/evaluation", review, handlers.EditEvaluation)
+			authorized.POST("/developers/:id/evaluation/reject", review, handlers.RejectEvaluation)
+			authorized.GET("/developers/:id/evaluation/reviews", review, handlers.ListEvaluationReviews)
+
+			// 评估任务的死信队列
+			deadLetters := authorized.Group("/queue/dead-letters")
+			deadLetters.Use(middleware.RequirePermission(auth.PermManageQueue))
+			{
+				deadLetters.GET("", handlers.ListDeadLetters)
+				deadLetters.DELETE("", handlers.PurgeDeadLetters)
+				deadLetters.GET("/:id", handlers.GetDeadLetter)
+				deadLetters.POST("/:id/replay", handlers.ReplayDeadLetter)
+				deadLetters.DELETE("/:id", handlers.PurgeDeadLetter)
+			}
+
+			// 模型调用的 token 用量和费用
+			authorized.GET("/ai/costs", edit, handlers.GetAICosts)
+
+			keys := authorized.Group("/keys")
+			keys.Use(middleware.RequirePermission(auth.PermManageKeys))
+			{
+				keys.POST("", handlers.CreateAPIKey)
+				keys.GET("", handlers.ListAPIKeys)
+				keys.DELETE("/:id", handlers.RevokeAPIKey)
+			}
+		}
 	}
 }
@@ -0,0 +1,428 @@
+package crawler
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"qinniu/internal/models"
+	"qinniu/internal/pkg/ai"
+	"qinniu/internal/pkg/events"
+	"qinniu/internal/pkg/queue"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/google/go-github/v45/github"
+)
+
+// GitHubCrawler 实现 Platform 接口
+
+func (gc *GitHubCrawler) Name() string {
+	return PlatformGitHub
+}
+
+// githubAPIBaseURL GitHub 客户端默认的 API 地址，账号标识中的主机为 api.github.com
+var githubAPIBaseURL = github.NewClient(nil).BaseURL.String()
+
+func (gc *GitHubCrawler) BaseURL() string {
+	return gc.client.BaseURL.String()
+}
+
+func (gc *GitHubCrawler) GetProfile(ctx context.Context, login string) (*Profile, error) {
+	user, _, err := gc.client.Users.Get(ctx, login)
+	if err != nil {
+		return nil, err
+	}
+	return githubProfile(user), nil
+}
+
+func (gc *GitHubCrawler) ListRepositories(ctx context.Context, profile *Profile) ([]*Repository, error) {
+	repos, err := gc.GetUserRepositories(profile.Login)
+	if err != nil {
+		return nil, err
+	}
+	return githubRepositories(repos), nil
+}
+
+func (gc *GitHubCrawler) ListLanguages(ctx context.Context, repo *Repository) ([]string, error) {
+	// 与提交统计一致，逐个仓库查询语言不受单次爬取的超时限制，仓库多的开发者超时后会漏掉语言
+	languages, _, err := gc.client.Repositories.ListLanguages(gc.ctx, repo.Owner, repo.Name)
+	if err != nil {
+		return nil, err
+	}
+	result := make([]string, 0, len(languages))
+	for lang := range languages {
+		result = append(result, lang)
+	}
+	return result, nil
+}
+
+func (gc *GitHubCrawler) CountUserCommits(ctx context.Context, profile *Profile, repo *Repository) (int, error) {
+	return gc.getUserCommitsInRepo(profile.Login, repo.Owner, repo.Name), nil
+}
+
+// githubProfile 将 GitHub 用户转换为平台无关的资料
+func githubProfile(user *github.User) *Profile {
+	avatarURL := user.GetAvatarURL()
+	if avatarURL == "" && user.AvatarURL != nil {
+		avatarURL = *user.AvatarURL
+	}
+
+	return &Profile{
+		Platform:    PlatformGitHub,
+		ID:          strconv.FormatInt(user.GetID(), 10),
+		Login:       user.GetLogin(),
+		Name:        user.GetName(),
+		Email:       user.GetEmail(),
+		Location:    user.GetLocation(),
+		Bio:         user.GetBio(),
+		Blog:        user.GetBlog(),
+		Company:     user.GetCompany(),
+		AvatarURL:   avatarURL,
+		HTMLURL:     user.GetHTMLURL(),
+		Followers:   user.GetFollowers(),
+		Following:   user.GetFollowing(),
+		PublicRepos: user.GetPublicRepos(),
+		CreatedAt:   user.GetCreatedAt().Time,
+	}
+}
+
+// githubRepositories 将 GitHub 仓库转换为平台无关的仓库模型
+func githubRepositories(repos []*github.Repository) []*Repository {
+	result := make([]*Repository, 0, len(repos))
+	for _, repo := range repos {
+		result = append(result, &Repository{
+			ID:          strconv.FormatInt(repo.GetID(), 10),
+			Name:        repo.GetName(),
+			FullName:    repo.GetFullName(),
+			Owner:       repo.GetOwner().GetLogin(),
+			Description: repo.GetDescription(),
+			Language:    repo.GetLanguage(),
+			Topics:      repo.Topics,
+			HTMLURL:     repo.GetHTMLURL(),
+			Stars:       repo.GetStargazersCount(),
+			Forks:       repo.GetForksCount(),
+			Size:        repo.GetSize(),
+			Fork:        repo.GetFork(),
+			Archived:    repo.GetArchived(),
+			CreatedAt:   repo.GetCreatedAt().Time,
+			UpdatedAt:   repo.GetUpdatedAt().Time,
+			PushedAt:    repo.GetPushedAt().Time,
+		})
+	}
+	return result
+}
+
+// collectAccountMetrics 统计单个平台账号的指标
+func collectAccountMetrics(ctx context.Context, p Platform, profile *Profile, repos []*Repository, starAnalysis *models.StarAnalysis) models.AccountMetrics {
+	// 获取仓库的语言信息
+	skillMap := make(map[string]struct{})
+	for _, repo := range repos {
+		// 获取主语言
+		if repo.Language != "" {
+			skillMap[repo.Language] = struct{}{}
+		}
+
+		// 获取所有使用的语言
+		languages, err := p.ListLanguages(ctx, repo)
+		if err != nil {
+			continue
+		}
+		for _, lang := range languages {
+			skillMap[lang] = struct{}{}
+		}
+	}
+
+	languages := make([]string, 0, len(skillMap))
+	for lang := range skillMap {
+		languages = append(languages, lang)
+	}
+	sort.Strings(languages)
+
+	// 计算总 star 数和 fork 数，跳过 fork 的仓库
+	metrics := models.AccountMetrics{
+		Repos:     len(repos),
+		Followers: profile.Followers,
+		Languages: languages,
+	}
+	for _, repo := range repos {
+		if repo.Fork {
+			continue
+		}
+		metrics.Stars += repo.Stars
+		metrics.Forks += repo.Forks
+		metrics.Commits += repo.Size
+		if repo.PushedAt.After(metrics.LastActive) {
+			metrics.LastActive = repo.PushedAt
+		}
+	}
+
+	log.Printf("[%s] 最终统计结果 - 总 Stars: %d, 总 Forks: %d, 总贡献: %d",
+		p.Name(), metrics.Stars, metrics.Forks, metrics.Commits)
+
+	metrics.AdjustedStars = metrics.Stars
+	if starAnalysis != nil {
+		metrics.AdjustedStars = starAnalysis.AdjustedStars
+	}
+	metrics.ProjectQuality = calculateProjectImportance(repos, starAnalysis)
+	metrics.Recognition = calculateContributionLevel(ctx, p, profile, repos)
+
+	return metrics
+}
+
+// newPlatformAccount 根据平台资料创建账号记录
+func newPlatformAccount(p Platform, profile *Profile, metrics models.AccountMetrics) models.PlatformAccount {
+	account := models.PlatformAccount{
+		Key:         AccountKey(p.Name(), p.BaseURL(), profile.ID),
+		Platform:    p.Name(),
+		AccountID:   profile.ID,
+		Login:       profile.Login,
+		ProfileURL:  profile.HTMLURL,
+		Metrics:     metrics,
+		LastCrawled: time.Now(),
+	}
+	// 官方实例不记录地址，自建实例需要保留以便刷新
+	if p.Name() != PlatformGitHub {
+		account.BaseURL = p.BaseURL()
+	}
+	return account
+}
+
+// publishEvaluationTask 创建并发送评估任务，资料没有变化且评估未过期时跳过
+func publishEvaluationTask(developer *models.Developer, profile *Profile, jobID string) {
+	if evaluationUpToDate(developer, profile) {
+		log.Printf("Profile of %s unchanged since last evaluation, skipping evaluation task", developer.Username)
+		return
+	}
+
+	evaluationTask := &queue.EvaluationTask{
+		DeveloperID:  developer.ID.Hex(),
+		JobID:        jobID,
+		Username:     developer.Username,
+		ProfileURL:   developer.ProfileURL,
+		BlogURL:      profile.Blog,
+		Description:  profile.Bio,
+		Repositories: developer.Repositories,
+		CreatedAt:    time.Now(),
+	}
+
+	// 发送评估任务到队列
+	queueClient := queue.NewQueue()
+	if err := queueClient.Publish(evaluationTask); err != nil {
+		log.Printf("Warning: Failed to publish evaluation task for %s: %v", developer.Username, err)
+	} else {
+		log.Printf("Successfully published evaluation task for %s", developer.Username)
+		emitEvent(jobID, events.EventEvaluationQueued, developer.Username, nil)
+		if err := models.MarkEvaluationPending(developer.Username); err != nil {
+			log.Printf("Warning: Failed to mark evaluation of %s as pending: %v", developer.Username, err)
+		}
+	}
+}
+
+// evaluationUpToDate 与评估 worker 一样构建评估资料，指纹与上次评估时相同且未超过有效期
+func evaluationUpToDate(developer *models.Developer, profile *Profile) bool {
+	evaluation := developer.TechEvaluation
+	if evaluation.ProfileHash == "" || evaluation.LastEvaluated.IsZero() {
+		return false
+	}
+	if maxAge := ai.LoadEvaluationMaxAge(); maxAge > 0 && time.Since(evaluation.LastEvaluated) > maxAge {
+		return false
+	}
+
+	current := ai.ProfileFromDeveloper(developer)
+	if profile.Bio != "" {
+		current.Bio = profile.Bio
+	}
+	if profile.Blog != "" {
+		current.Blog = profile.Blog
+	}
+	return current.Fingerprint() == evaluation.ProfileHash
+}
+
+// emitEvent 发布爬取进度事件，jobID 为空表示不属于爬取任务，如后台刷新和命令行爬取
+func emitEvent(jobID, eventType, username string, data map[string]interface{}) {
+	events.Publish(events.Event{Type: eventType, JobID: jobID, Username: username, Data: data})
+}
+
+// CrawlPlatformUser 爬取 GitLab / Gitee 等平台的用户
+// 账号已关联到某个开发者时只更新该账号的指标，否则以该账号为主创建开发者
+func CrawlPlatformUser(p Platform, login string, opts CrawlOptions) (*models.Developer, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Minute)
+	defer cancel()
+
+	emitEvent(opts.JobID, events.EventStarted, login, map[string]interface{}{"platform": p.Name(), "full": opts.Full})
+
+	profile, err := p.GetProfile(ctx, login)
+	if err != nil {
+		return nil, err
+	}
+	repos, err := p.ListRepositories(ctx, profile)
+	if err != nil {
+		return nil, err
+	}
+	emitEvent(opts.JobID, events.EventReposFetched, login, map[string]interface{}{"repositories": len(repos)})
+
+	key := AccountKey(p.Name(), p.BaseURL(), profile.ID)
+	existingDev, err := models.FindByAccountKey(key)
+	if err != nil {
+		return nil, err
+	}
+
+	// 有游标时只统计有新推送的仓库
+	var previousCursor *models.CrawlCursor
+	if existingDev != nil {
+		if existing := existingDev.Account(key); existing != nil {
+			previousCursor = existing.Cursor
+		}
+	}
+	incremental := newIncrementalPlatform(p, previousCursor, opts)
+
+	metrics := collectAccountMetrics(ctx, incremental, profile, repos, nil)
+	account := newPlatformAccount(p, profile, metrics)
+	account.Cursor = incremental.Cursor(repos, "")
+
+	// 已关联到以其他账号为主的开发者，只合并指标
+	if existingDev != nil && primaryAccountKey(existingDev) != key {
+		existingDev.UpsertAccount(account)
+		existingDev.ApplyAccounts()
+		emitEvent(opts.JobID, events.EventRankComputed, login, map[string]interface{}{"talent_rank": existingDev.TalentRank})
+		existingDev.LastUpdated = time.Now()
+		if err := existingDev.Update(); err != nil {
+			return nil, fmt.Errorf("更新用户失败: %v", err)
+		}
+		clearRefreshFailure(existingDev)
+		return existingDev, nil
+	}
+
+	name := profile.Name
+	if name == "" {
+		name = profile.Login
+	}
+
+	developer := &models.Developer{
+		Username:      platformUsername(p, profile.Login),
+		Name:          name,
+		Email:         profile.Email,
+		Location:      profile.Location,
+		Bio:           profile.Bio,
+		Avatar:        profile.AvatarURL,
+		ProfileURL:    profile.HTMLURL,
+		UpdatedAt:     time.Now(),
+		LastUpdated:   time.Now(),
+		Repositories:  repoNames(repos),
+		RepoSummaries: repoSummaries(repos),
+	}
+	if existingDev != nil {
+		developer.Accounts = existingDev.Accounts
+	}
+	developer.UpsertAccount(account)
+	developer.ApplyAccounts()
+	emitEvent(opts.JobID, events.EventRankComputed, login, map[string]interface{}{"talent_rank": developer.TalentRank})
+
+	// 非 GitHub 平台没有 AI 预测，只使用位置和快速预测
+	nation := extractNation(developer.Location)
+	nationConfidence := 0.0
+	if nation == "" {
+		if pred := QuickPredictNation(profile.Login, profile, repos); pred != nil && pred.Confidence >= 40 {
+			nation = pred.Nation
+			nationConfidence = pred.Confidence
+		}
+	}
+	developer.Nation = nation
+	developer.NationConfidence = nationConfidence
+	emitEvent(opts.JobID, events.EventNationPredicted, login, map[string]interface{}{"nation": nation, "confidence": nationConfidence})
+
+	developer.Confidence = calculateConfidence(metrics.Commits, metrics.Stars, profile.Followers, developer.Location != "")
+	developer.DataValidation = models.ValidationResult{
+		IsValid:       true,
+		Confidence:    developer.Confidence,
+		LastValidated: time.Now(),
+	}
+	developer.UpdateFrequency = updateFrequencyFor(existingDev, developer)
+
+	if existingDev != nil {
+		if err := saveOverExisting(developer, existingDev); err != nil {
+			return nil, err
+		}
+	} else if err := developer.Create(); err != nil {
+		return nil, fmt.Errorf("创建用户失败: %v", err)
+	}
+
+	publishEvaluationTask(developer, profile, opts.JobID)
+	return developer, nil
+}
+
+// LinkAccount 将其他平台的账号关联到已有开发者，并重新计算合并后的 TalentRank
+func LinkAccount(p Platform, developer *models.Developer, login string) (*models.PlatformAccount, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Minute)
+	defer cancel()
+
+	profile, err := p.GetProfile(ctx, login)
+	if err != nil {
+		return nil, err
+	}
+
+	key := AccountKey(p.Name(), p.BaseURL(), profile.ID)
+	owner, err := models.FindByAccountKey(key)
+	if err != nil {
+		return nil, err
+	}
+	if owner != nil && owner.ID != developer.ID {
+		return nil, fmt.Errorf("账号 %s 已关联到开发者 %s", key, owner.Username)
+	}
+
+	repos, err := p.ListRepositories(ctx, profile)
+	if err != nil {
+		return nil, err
+	}
+
+	incremental := newIncrementalPlatform(p, nil, CrawlOptions{Full: true})
+	account := newPlatformAccount(p, profile, collectAccountMetrics(ctx, incremental, profile, repos, nil))
+	account.Cursor = incremental.Cursor(repos, "")
+	developer.UpsertAccount(account)
+	developer.ApplyAccounts()
+	if err := developer.Update(); err != nil {
+		return nil, fmt.Errorf("更新用户失败: %v", err)
+	}
+
+	return developer.Account(key), nil
+}
+
+// platformUsername 开发者的用户名：GitHub 账号直接使用登录名，其他平台加上平台前缀（如 gitlab:login），
+// 避免与同名的 GitHub 开发者冲突，评估任务和按用户名的查找都依赖用户名唯一
+func platformUsername(p Platform, login string) string {
+	if p.Name() == PlatformGitHub {
+		return login
+	}
+	return p.Name() + ":" + login
+}
+
+// fromOtherPlatform 开发者以 GitLab、Gitee 等其他平台的账号为主，同名的 GitHub 账号不是同一个人
+func fromOtherPlatform(developer *models.Developer) bool {
+	if developer.GitHubID > 0 || len(developer.Accounts) == 0 {
+		return false
+	}
+	for _, account := range developer.Accounts {
+		if account.Platform == PlatformGitHub {
+			return false
+		}
+	}
+	return true
+}
+
+// primaryAccountKey 返回开发者的主账号标识，GitHub 账号优先
+func primaryAccountKey(developer *models.Developer) string {
+	for _, account := range developer.Accounts {
+		if account.Platform == PlatformGitHub {
+			return account.Key
+		}
+	}
+	// 旧记录只有 GitHub ID，按 GitHub 平台账号的主机生成标识，与 newPlatformAccount 生成的一致
+	if developer.GitHubID > 0 {
+		return AccountKey(PlatformGitHub, githubAPIBaseURL, strconv.FormatInt(developer.GitHubID, 10))
+	}
+	if len(developer.Accounts) > 0 {
+		return developer.Accounts[0].Key
+	}
+	return ""
+}
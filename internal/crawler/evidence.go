@@ -0,0 +1,341 @@
+package crawler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"path"
+	"qinniu/internal/pkg/ai"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/google/go-github/v45/github"
+)
+
+// 每类证据收集的上限，最终按 token 预算挑选
+const (
+	evidenceReadmeChars  = 4000 // README 摘录的字符数
+	evidenceSnippetLines = 80   // 源码片段的行数
+	evidenceSnippets     = 2    // 每个仓库的源码片段数
+	evidenceCommits      = 10   // 每个仓库的提交数
+	evidencePulls        = 8    // PR 数，不限仓库
+	evidencePullChars    = 1500 // PR 描述的字符数
+)
+
+// 作为代表性源码的文件扩展名
+var sourceExtensions = map[string]bool{
+	".go": true, ".rs": true, ".py": true, ".java": true, ".kt": true, ".scala": true,
+	".c": true, ".cc": true, ".cpp": true, ".h": true, ".hpp": true, ".cs": true,
+	".js": true, ".jsx": true, ".ts": true, ".tsx": true, ".vue": true, ".svelte": true,
+	".rb": true, ".php": true, ".swift": true, ".m": true, ".dart": true, ".ex": true,
+	".exs": true, ".erl": true, ".hs": true, ".ml": true, ".clj": true, ".lua": true,
+	".zig": true, ".nim": true, ".jl": true, ".r": true, ".sh": true,
+}
+
+// 生成代码、依赖和测试目录下的文件不能代表开发者的水平
+var skippedSourcePath = regexp.MustCompile(`(?i)(^|/)(vendor|node_modules|third_party|thirdparty|dist|build|out|target|generated|gen|mocks?|testdata|examples?|docs?)/|(_test\.go|\.test\.[jt]sx?|\.spec\.[jt]sx?|\.min\.js|\.pb\.go|_pb2\.py|\.d\.ts)$`)
+
+// README 中没有信息量的行：徽章、图片、HTML 标签
+var readmeNoise = regexp.MustCompile(`^\s*(\[!\[|!\[|<(img|p|a|div|br|picture|source|h\d)\b|</)`)
+
+// CollectEvidence 收集开发者工作证据：前 maxRepos 个仓库的 README、源码片段和提交信息，以及最近的 PR
+// 单个请求失败只跳过对应的证据，全部失败时返回最后一个错误
+func (gc *GitHubCrawler) CollectEvidence(ctx context.Context, profile *ai.DeveloperProfile, maxRepos int) ([]ai.Evidence, error) {
+	ctx, cancel := context.WithTimeout(ctx, 60*time.Second)
+	defer cancel()
+
+	var (
+		items   []ai.Evidence
+		lastErr error
+	)
+	collect := func(found []ai.Evidence, err error) {
+		if err != nil {
+			lastErr = err
+			return
+		}
+		items = append(items, found...)
+	}
+
+	repos := profile.Repositories
+	if len(repos) > maxRepos {
+		repos = repos[:maxRepos]
+	}
+	for _, repo := range repos {
+		owner := repo.Owner
+		if owner == "" {
+			owner = profile.Username
+		}
+		collect(gc.readmeEvidence(ctx, owner, repo))
+		collect(gc.codeEvidence(ctx, owner, repo))
+		collect(gc.commitEvidence(ctx, owner, repo, profile.Username))
+	}
+	collect(gc.pullRequestEvidence(ctx, profile.Username, profile.Repositories))
+
+	if len(items) == 0 && lastErr != nil {
+		return nil, fmt.Errorf("收集 %s 的工作证据失败: %v", profile.Username, lastErr)
+	}
+	if lastErr != nil {
+		log.Printf("Warning: 部分工作证据收集失败 (%s): %v", profile.Username, lastErr)
+	}
+	return items, nil
+}
+
+// readmeEvidence README 摘录，去掉徽章、图片和 HTML
+func (gc *GitHubCrawler) readmeEvidence(ctx context.Context, owner string, repo ai.RepoInfo) ([]ai.Evidence, error) {
+	readme, _, err := gc.client.Repositories.GetReadme(ctx, owner, repo.Name, nil)
+	if err != nil {
+		if IsNotFound(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	content, err := readme.GetContent()
+	if err != nil {
+		return nil, err
+	}
+
+	var lines []string
+	blank := false
+	for _, line := range strings.Split(content, "\n") {
+		line = strings.TrimRight(line, " \t\r")
+		if readmeNoise.MatchString(line) {
+			continue
+		}
+		// 连续空行合并为一行
+		if line == "" {
+			if blank {
+				continue
+			}
+			blank = true
+		} else {
+			blank = false
+		}
+		lines = append(lines, line)
+	}
+	excerpt := truncateRunes(strings.TrimSpace(strings.Join(lines, "\n")), evidenceReadmeChars)
+	if excerpt == "" {
+		return nil, nil
+	}
+	return []ai.Evidence{{
+		Kind:    ai.EvidenceReadme,
+		Repo:    repo.Name,
+		Title:   readme.GetPath(),
+		URL:     readme.GetHTMLURL(),
+		Content: excerpt,
+		Stars:   repo.Stars,
+	}}, nil
+}
+
+// codeEvidence 代表性源码片段：从仓库文件树中挑选路径较浅、大小适中的源码文件
+func (gc *GitHubCrawler) codeEvidence(ctx context.Context, owner string, repo ai.RepoInfo) ([]ai.Evidence, error) {
+	tree, _, err := gc.client.Git.GetTree(ctx, owner, repo.Name, "HEAD", true)
+	if err != nil {
+		if IsNotFound(err) || isEmptyRepository(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	var files []*github.TreeEntry
+	for _, entry := range tree.Entries {
+		name := entry.GetPath()
+		if entry.GetType() != "blob" || !sourceExtensions[strings.ToLower(path.Ext(name))] || skippedSourcePath.MatchString(name) {
+			continue
+		}
+		// 太小的文件没有内容，太大的多半是生成的
+		if size := entry.GetSize(); size < 800 || size > 60000 {
+			continue
+		}
+		files = append(files, entry)
+	}
+	sort.SliceStable(files, func(i, j int) bool {
+		return sourceFileScore(files[i]) > sourceFileScore(files[j])
+	})
+
+	var items []ai.Evidence
+	for _, file := range files {
+		if len(items) >= evidenceSnippets {
+			break
+		}
+		content, _, _, err := gc.client.Repositories.GetContents(ctx, owner, repo.Name, file.GetPath(), nil)
+		if err != nil || content == nil {
+			continue
+		}
+		source, err := getFileContent(content)
+		if err != nil {
+			continue
+		}
+		snippet := sourceSnippet(source, evidenceSnippetLines)
+		if snippet == "" {
+			continue
+		}
+		items = append(items, ai.Evidence{
+			Kind:    ai.EvidenceCode,
+			Repo:    repo.Name,
+			Title:   file.GetPath(),
+			URL:     content.GetHTMLURL(),
+			Content: snippet,
+			Stars:   repo.Stars,
+		})
+	}
+	return items, nil
+}
+
+// sourceFileScore 路径越浅越能代表项目的核心，大小在 2-20KB 之间的文件最合适
+func sourceFileScore(entry *github.TreeEntry) float64 {
+	depth := strings.Count(entry.GetPath(), "/")
+	score := 1 / float64(1+depth)
+	if size := entry.GetSize(); size >= 2000 && size <= 20000 {
+		score += 0.5
+	}
+	base := strings.ToLower(path.Base(entry.GetPath()))
+	if strings.HasPrefix(base, "main.") || strings.HasPrefix(base, "index.") || strings.HasPrefix(base, "setup.") {
+		score -= 0.3 // 入口文件通常只有胶水代码
+	}
+	return score
+}
+
+// sourceSnippet 跳过文件开头的许可证注释，取前 maxLines 行
+func sourceSnippet(source string, maxLines int) string {
+	lines := strings.Split(strings.ReplaceAll(source, "\r\n", "\n"), "\n")
+
+	start := 0
+	head := strings.ToLower(strings.Join(lines[:min(len(lines), 20)], "\n"))
+	if strings.Contains(head, "copyright") || strings.Contains(head, "license") {
+		for i, line := range lines {
+			if strings.TrimSpace(line) == "" {
+				start = i + 1
+				break
+			}
+			if i >= 40 {
+				break
+			}
+		}
+	}
+	end := min(len(lines), start+maxLines)
+	return strings.TrimSpace(strings.Join(lines[start:end], "\n"))
+}
+
+// commitEvidence 开发者在仓库中最近的提交信息，合并为一条证据
+func (gc *GitHubCrawler) commitEvidence(ctx context.Context, owner string, repo ai.RepoInfo, username string) ([]ai.Evidence, error) {
+	commits, _, err := gc.client.Repositories.ListCommits(ctx, owner, repo.Name, &github.CommitsListOptions{
+		Author:      username,
+		ListOptions: github.ListOptions{PerPage: evidenceCommits},
+	})
+	if err != nil {
+		if IsNotFound(err) || isEmptyRepository(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	var (
+		lines  []string
+		latest time.Time
+	)
+	for _, commit := range commits {
+		message := strings.TrimSpace(commit.GetCommit().GetMessage())
+		if message == "" || strings.HasPrefix(message, "Merge ") {
+			continue
+		}
+		date := commit.GetCommit().GetAuthor().GetDate()
+		if date.After(latest) {
+			latest = date
+		}
+		// 标题行加上正文的开头
+		parts := strings.SplitN(message, "\n", 2)
+		line := fmt.Sprintf("- %s %s", date.Format("2006-01-02"), parts[0])
+		if len(parts) > 1 {
+			if body := truncateRunes(strings.Join(strings.Fields(parts[1]), " "), 200); body != "" {
+				line += "：" + body
+			}
+		}
+		lines = append(lines, line)
+	}
+	if len(lines) == 0 {
+		return nil, nil
+	}
+	return []ai.Evidence{{
+		Kind:    ai.EvidenceCommits,
+		Repo:    repo.Name,
+		Title:   "最近的提交",
+		Content: strings.Join(lines, "\n"),
+		Stars:   repo.Stars,
+		Time:    latest,
+	}}, nil
+}
+
+// pullRequestEvidence 开发者最近提交的 PR，包括给其他项目的贡献
+func (gc *GitHubCrawler) pullRequestEvidence(ctx context.Context, username string, repos []ai.RepoInfo) ([]ai.Evidence, error) {
+	result, _, err := gc.client.Search.Issues(ctx, fmt.Sprintf("type:pr author:%s", username), &github.SearchOptions{
+		Sort:        "updated",
+		Order:       "desc",
+		ListOptions: github.ListOptions{PerPage: evidencePulls},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	stars := make(map[string]int)
+	for _, repo := range repos {
+		stars[repo.Name] = repo.Stars
+	}
+
+	var items []ai.Evidence
+	for _, issue := range result.Issues {
+		// repository_url 形如 https://api.github.com/repos/<owner>/<repo>
+		repoName := strings.TrimPrefix(issue.GetRepositoryURL(), "https://api.github.com/repos/")
+		short := path.Base(repoName)
+		if repoOwner := path.Dir(repoName); repoOwner == username {
+			repoName = short
+		}
+
+		content := issue.GetTitle()
+		if body := truncateRunes(strings.TrimSpace(issue.GetBody()), evidencePullChars); body != "" {
+			content += "\n" + body
+		}
+		items = append(items, ai.Evidence{
+			Kind:    ai.EvidencePullRequest,
+			Repo:    repoName,
+			Title:   fmt.Sprintf("#%d（%s）", issue.GetNumber(), issue.GetState()),
+			URL:     issue.GetHTMLURL(),
+			Content: content,
+			Stars:   stars[short],
+			Time:    issue.GetUpdatedAt(),
+		})
+	}
+	return items, nil
+}
+
+// IsNotFound 判断错误是否为用户、仓库等资源不存在，如账号已被删除或改名
+func IsNotFound(err error) bool {
+	var ghErr *github.ErrorResponse
+	if errors.As(err, &ghErr) && ghErr.Response != nil {
+		return ghErr.Response.StatusCode == http.StatusNotFound
+	}
+	var restErr *statusError
+	if errors.As(err, &restErr) {
+		return restErr.statusCode == http.StatusNotFound
+	}
+	return false
+}
+
+// isEmptyRepository 空仓库请求文件树和提交时返回 409
+func isEmptyRepository(err error) bool {
+	if e, ok := err.(*github.ErrorResponse); ok && e.Response != nil {
+		return e.Response.StatusCode == 409
+	}
+	return false
+}
+
+func truncateRunes(s string, n int) string {
+	runes := []rune(s)
+	if len(runes) <= n {
+		return s
+	}
+	return string(runes[:n]) + "…"
+}
@@ -0,0 +1,180 @@
+package crawler
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+)
+
+// GiteeClient Gitee API v5 客户端
+type GiteeClient struct {
+	rest *restClient
+}
+
+func NewGiteeClient(baseURL, token string) *GiteeClient {
+	return &GiteeClient{
+		rest: newRestClient(baseURL, func(req *http.Request) {
+			if token == "" {
+				return
+			}
+			// Gitee 使用 access_token 查询参数认证
+			q := req.URL.Query()
+			q.Set("access_token", token)
+			req.URL.RawQuery = q.Encode()
+		}),
+	}
+}
+
+type giteeUser struct {
+	ID          int64     `json:"id"`
+	Login       string    `json:"login"`
+	Name        string    `json:"name"`
+	Email       string    `json:"email"`
+	Bio         string    `json:"bio"`
+	Blog        string    `json:"blog"`
+	Company     string    `json:"company"`
+	AvatarURL   string    `json:"avatar_url"`
+	HTMLURL     string    `json:"html_url"`
+	Followers   int       `json:"followers"`
+	Following   int       `json:"following"`
+	PublicRepos int       `json:"public_repos"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+type giteeRepo struct {
+	ID              int64     `json:"id"`
+	Name            string    `json:"name"`
+	Path            string    `json:"path"`
+	FullName        string    `json:"full_name"`
+	Description     string    `json:"description"`
+	Language        string    `json:"language"`
+	HTMLURL         string    `json:"html_url"`
+	StargazersCount int       `json:"stargazers_count"`
+	ForksCount      int       `json:"forks_count"`
+	Fork            bool      `json:"fork"`
+	CreatedAt       time.Time `json:"created_at"`
+	UpdatedAt       time.Time `json:"updated_at"`
+	PushedAt        time.Time `json:"pushed_at"`
+	Owner           struct {
+		Login string `json:"login"`
+	} `json:"owner"`
+	ProjectLabels []struct {
+		Name string `json:"name"`
+	} `json:"project_labels"`
+}
+
+func (ge *GiteeClient) Name() string {
+	return PlatformGitee
+}
+
+func (ge *GiteeClient) BaseURL() string {
+	return ge.rest.baseURL
+}
+
+// GetProfile 获取 Gitee 用户资料，Gitee 不提供位置信息
+func (ge *GiteeClient) GetProfile(ctx context.Context, login string) (*Profile, error) {
+	var user giteeUser
+	if _, err := ge.rest.getJSON(ctx, "/users/"+url.PathEscape(login), nil, &user); err != nil {
+		return nil, err
+	}
+
+	return &Profile{
+		Platform:    PlatformGitee,
+		BaseURL:     ge.BaseURL(),
+		ID:          strconv.FormatInt(user.ID, 10),
+		Login:       user.Login,
+		Name:        user.Name,
+		Email:       user.Email,
+		Bio:         user.Bio,
+		Blog:        user.Blog,
+		Company:     user.Company,
+		AvatarURL:   user.AvatarURL,
+		HTMLURL:     user.HTMLURL,
+		Followers:   user.Followers,
+		Following:   user.Following,
+		PublicRepos: user.PublicRepos,
+		CreatedAt:   user.CreatedAt,
+	}, nil
+}
+
+// ListRepositories 获取用户拥有的仓库，通过 total_page 响应头分页
+func (ge *GiteeClient) ListRepositories(ctx context.Context, profile *Profile) ([]*Repository, error) {
+	var repos []*Repository
+	for page := 1; ; page++ {
+		var items []giteeRepo
+		header, err := ge.rest.getJSON(ctx, "/users/"+url.PathEscape(profile.Login)+"/repos", url.Values{
+			"type":     {"owner"},
+			"sort":     {"pushed"},
+			"per_page": {"100"},
+			"page":     {strconv.Itoa(page)},
+		}, &items)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, r := range items {
+			topics := make([]string, 0, len(r.ProjectLabels))
+			for _, label := range r.ProjectLabels {
+				topics = append(topics, label.Name)
+			}
+			repos = append(repos, &Repository{
+				ID:          strconv.FormatInt(r.ID, 10),
+				Name:        r.Path,
+				FullName:    r.FullName,
+				Owner:       r.Owner.Login,
+				Description: r.Description,
+				Language:    r.Language,
+				Topics:      topics,
+				HTMLURL:     r.HTMLURL,
+				Stars:       r.StargazersCount,
+				Forks:       r.ForksCount,
+				Fork:        r.Fork,
+				CreatedAt:   r.CreatedAt,
+				UpdatedAt:   r.UpdatedAt,
+				PushedAt:    r.PushedAt,
+			})
+		}
+
+		totalPages, _ := strconv.Atoi(header.Get("total_page"))
+		if len(items) == 0 || page >= totalPages {
+			break
+		}
+	}
+	return repos, nil
+}
+
+// ListLanguages Gitee 只提供仓库的主语言
+func (ge *GiteeClient) ListLanguages(ctx context.Context, repo *Repository) ([]string, error) {
+	if repo.Language == "" {
+		return nil, nil
+	}
+	return []string{repo.Language}, nil
+}
+
+// CountUserCommits 统计用户提交数，优先读取 total_count 响应头
+func (ge *GiteeClient) CountUserCommits(ctx context.Context, profile *Profile, repo *Repository) (int, error) {
+	total := 0
+	for page := 1; ; page++ {
+		var commits []struct {
+			SHA string `json:"sha"`
+		}
+		header, err := ge.rest.getJSON(ctx, "/repos/"+url.PathEscape(repo.Owner)+"/"+url.PathEscape(repo.Name)+"/commits", url.Values{
+			"author":   {profile.Login},
+			"per_page": {"100"},
+			"page":     {strconv.Itoa(page)},
+		}, &commits)
+		if err != nil {
+			return total, err
+		}
+		if n, err := strconv.Atoi(header.Get("total_count")); err == nil {
+			return n, nil
+		}
+		total += len(commits)
+		if len(commits) < 100 {
+			break
+		}
+	}
+	return total, nil
+}
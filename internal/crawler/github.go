@@ -9,9 +9,10 @@ import (
 	"os"
 	"qinniu/internal/models"
 	"qinniu/internal/pkg/cache"
-	"qinniu/internal/pkg/queue"
+	"qinniu/internal/pkg/events"
 	"regexp"
 	"sort"
+	"strconv"
 	"strings"
 	"sync"
 	"time"
@@ -25,9 +26,10 @@ import (
 )
 
 type GitHubCrawler struct {
-	client   *github.Client
-	ctx      context.Context
-	aiClient *ai.Client
+	client     *github.Client
+	ctx        context.Context
+	aiClient   *ai.Client
+	starConfig StarAnalysisConfig
 }
 
 func NewGitHubCrawler() *GitHubCrawler {
@@ -55,40 +57,49 @@ func NewGitHubCrawler() *GitHubCrawler {
 	log.Println("GitHub token 验证成功")
 
 	// 初始化 AI 客户端
-	aiApiKey := os.Getenv("AI_API_KEY")
-	if aiApiKey == "" {
-		log.Printf("Warning: DEEPSEEK_API_KEY not found, AI prediction will be disabled")
+	aiClient, err := ai.NewClientFromEnv()
+	if err != nil {
+		log.Printf("Warning: %v, AI prediction will be disabled", err)
 		return &GitHubCrawler{
-			client: client,
-			ctx:    ctx,
+			client:     client,
+			ctx:        ctx,
+			starConfig: loadStarAnalysisConfig(),
 		}
 	}
-
-	aiClient := ai.NewClient(aiApiKey)
 	log.Printf("AI client initialized successfully")
 
 	return &GitHubCrawler{
-		client:   client,
-		ctx:      ctx,
-		aiClient: aiClient,
+		client:     client,
+		ctx:        ctx,
+		aiClient:   aiClient,
+		starConfig: loadStarAnalysisConfig(),
 	}
 }
 
 // GetUserData 获取用基本信息
 func (gc *GitHubCrawler) GetUserData(username string) (*models.Developer, error) {
+	return gc.GetUserDataWithOptions(username, CrawlOptions{})
+}
+
+// GetUserDataWithOptions 获取用户信息，默认只增量获取上次爬取后变化的部分
+func (gc *GitHubCrawler) GetUserDataWithOptions(username string, opts CrawlOptions) (*models.Developer, error) {
 	// 1. 首先检查数据库中是否已存在该用户
 	existingDev, err := models.FindByUsername(username)
 	if err != nil && err != mongo.ErrNoDocuments {
 		return nil, err
 	}
+	// 旧版本创建的其他平台开发者可能与该 GitHub 用户同名
+	if existingDev != nil && fromOtherPlatform(existingDev) {
+		existingDev = nil
+	}
 
 	// 如果存在且不需要更新，直返回
-	if existingDev != nil && !existingDev.ShouldUpdate() {
+	if existingDev != nil && !existingDev.ShouldUpdate() && !opts.Full {
 		return existingDev, nil
 	}
 
 	// 如果Redis客户端可用，尝试从缓存获取
-	if cache.RedisClient != nil {
+	if cache.RedisClient != nil && !opts.Full {
 		if cached, err := cache.GetCachedDeveloper(username); err == nil && cached != nil {
 			if developer, ok := cached.(*models.Developer); ok {
 				return developer, nil
@@ -96,6 +107,8 @@ func (gc *GitHubCrawler) GetUserData(username string) (*models.Developer, error)
 		}
 	}
 
+	emitEvent(opts.JobID, events.EventStarted, username, map[string]interface{}{"platform": PlatformGitHub, "full": opts.Full})
+
 	// 加超时控制
 	ctx, cancel := context.WithTimeout(gc.ctx, 30*time.Second)
 	defer cancel()
@@ -117,7 +130,7 @@ func (gc *GitHubCrawler) GetUserData(username string) (*models.Developer, error)
 	// 获取仓库信息
 	go func() {
 		defer wg.Done()
-		repos, repoErr = gc.GetUserRepositories(username)
+		repos, repoErr = gc.listUserRepositories(username)
 	}()
 
 	wg.Wait()
@@ -129,139 +142,105 @@ func (gc *GitHubCrawler) GetUserData(username string) (*models.Developer, error)
 		return nil, repoErr
 	}
 
-	// 获取用户头像 URL - 只在这里获取一次
-	avatarURL := user.GetAvatarURL()
-	if avatarURL == "" && user.AvatarURL != nil {
-		avatarURL = *user.AvatarURL
+	// 以不可变的 GitHub ID 识别身份，处理改名和用户名被他人复用的情况
+	existingDev, err = resolveExistingDeveloper(user, existingDev)
+	if err != nil {
+		return nil, err
 	}
 
-	// 快速处理基本信息
-	name := getPtrValue(user.Name)
-	if name == "" {
-		name = getPtrValue(user.Login)
+	// 根据上次的游标决定增量还是完整爬取
+	profile := githubProfile(user)
+	var previousCursor *models.CrawlCursor
+	if existingDev != nil {
+		if account := existingDev.Account(AccountKey(PlatformGitHub, gc.BaseURL(), profile.ID)); account != nil {
+			previousCursor = account.Cursor
+		}
 	}
+	incremental := newIncrementalPlatform(gc, previousCursor, opts)
 
-	if avatarURL == "" {
-		log.Printf("Warning - No avatar URL found for user: %s", username)
+	eventsETag := ""
+	if previousCursor != nil {
+		eventsETag = previousCursor.EventsETag
+	}
+	newETag, changed, err := gc.userEventsChanged(ctx, profile.Login, eventsETag)
+	if err != nil {
+		log.Printf("Warning: 检查 %s 的公开事件失败: %v", profile.Login, err)
 	} else {
-		log.Printf("Debug - Got avatar URL for user %s: %s", username, avatarURL)
+		eventsETag = newETag
+		incremental.quiet = !changed && !incremental.full
 	}
 
-	// 获取仓库的语言信息
-	var skills []string
-	skillMap := make(map[string]struct{})
-
-	for _, repo := range repos {
-		// 获取主语言
-		if repo.Language != nil && *repo.Language != "" {
-			skillMap[*repo.Language] = struct{}{}
-		}
+	// 只获取有新推送的仓库的详细信息
+	repos = gc.enrichRepositories(username, repos, func(repo *github.Repository) bool {
+		return incremental.unchangedSince(strconv.FormatInt(repo.GetID(), 10), repo.GetPushedAt().Time) != nil
+	})
 
-		// 获取所有使用的语言
-		languages, _, err := gc.client.Repositories.ListLanguages(gc.ctx, username, getPtrValue(repo.Name))
-		if err != nil {
-			continue
-		}
+	// 转换为平台无关的资料和仓库模型
+	platformRepos := githubRepositories(repos)
+	emitEvent(opts.JobID, events.EventReposFetched, username, map[string]interface{}{"repositories": len(platformRepos)})
 
-		for lang := range languages {
-			skillMap[lang] = struct{}{}
-		}
-	}
+	// 获取用户头像 URL - 只在这里获取一次
+	avatarURL := profile.AvatarURL
 
-	// 转换为切片
-	for skill := range skillMap {
-		skills = append(skills, skill)
+	// 快速处理基本信息
+	name := profile.Name
+	if name == "" {
+		name = profile.Login
 	}
 
-	sort.Strings(skills)
-
-	// 计算总 star 数和 fork 数
-	var totalStars int
-	var totalForks int
-	var contributions int
-	for _, repo := range repos {
-		// 跳过 fork 的仓库
-		if repo.GetFork() {
-			log.Printf("跳过 fork 的仓库: %s", repo.GetName())
-			continue
-		}
-
-		// 添加详细的日志
-		log.Printf("处理原创仓库: %s", repo.GetName())
-		log.Printf("Stars: %d, Forks: %d, Size: %d, Fork: %v",
-			repo.GetStargazersCount(),
-			repo.GetForksCount(),
-			repo.GetSize(),
-			repo.GetFork())
-
-		totalStars += repo.GetStargazersCount()
-		totalForks += repo.GetForksCount()
-		contributions += repo.GetSize()
+	if avatarURL == "" {
+		log.Printf("Warning - No avatar URL found for user: %s", username)
+	} else {
+		log.Printf("Debug - Got avatar URL for user %s: %s", username, avatarURL)
 	}
 
-	// 添加汇总日志
-	log.Printf("最终统计结果 - 总 Stars: %d, 总 Forks: %d, 总贡献: %d",
-		totalStars, totalForks, contributions)
-
 	// 创建新的开发者记录
 	developer := &models.Developer{
-		Username:     getPtrValue(user.Login),
-		Name:         name,
-		Email:        getPtrValue(user.Email),
-		Location:     getPtrValue(user.Location),
-		Avatar:       avatarURL,
-		ProfileURL:   user.GetHTMLURL(),
-		UpdatedAt:    time.Now(),
-		LastUpdated:  time.Now(),
-		Skills:       skills,
-		Repositories: repoNames(repos),
-		StarCount:    totalStars,
-		CommitCount:  contributions,
-		ForkCount:    totalForks,
+		GitHubID:      user.GetID(),
+		Username:      profile.Login,
+		Name:          name,
+		Email:         profile.Email,
+		Location:      profile.Location,
+		Bio:           profile.Bio,
+		Avatar:        avatarURL,
+		ProfileURL:    profile.HTMLURL,
+		UpdatedAt:     time.Now(),
+		LastUpdated:   time.Now(),
+		Repositories:  repoNames(platformRepos),
+		RepoSummaries: repoSummaries(platformRepos),
 	}
 	// 添加调试日志，确认 developer 对象中的 Avatar 字段
 	log.Printf("Debug - Developer object created with Avatar URL: %s", developer.Avatar)
 
-	// 假设有函数计算项目重要性和贡献度
-	projectImportance := calculateProjectImportance(repos)
-	contributionLevel := gc.calculateContributionLevel(username, repos)
-
-	// 创建 DeveloperMetrics 对象
-	developerMetrics := &models.DeveloperMetrics{}
-
-	// 设置贡献指标
-	developerMetrics.Contributions.CommitCount = contributions
-	developerMetrics.Contributions.Quality = 0.8 // 默认质量分数
-
-	// 设置项目指标
-	developerMetrics.Projects.StarCount = totalStars
-	developerMetrics.Projects.ForkCount = totalForks
-	developerMetrics.Projects.TotalCount = len(repos)
-	developerMetrics.Projects.Quality = projectImportance
-
-	// 设置影响力指标
-	developerMetrics.Influence.Followers = getPtrValue(user.Followers)
-	developerMetrics.Influence.Recognition = contributionLevel
+	// 保留已关联的其他平台账号
+	if existingDev != nil {
+		developer.Accounts = existingDev.Accounts
+	}
 
-	// 设置活跃度指
-	developerMetrics.Activity.LastActive = time.Now()
-	developerMetrics.Activity.Frequency = calculateActivityFrequency(contributions)
-	developerMetrics.Activity.Consistency = 0.8 // 默认持续性分数
-	developerMetrics.Activity.Growth = calculateGrowthTrend(contributions)
+	// 可选：对热门仓库的 stargazer 采样，折算刷 star 的影响
+	var previousAnalysis *models.StarAnalysis
+	if existingDev != nil {
+		previousAnalysis = existingDev.StarAnalysis
+	}
+	developer.StarAnalysis = gc.analyzeStarQuality(repos, previousAnalysis)
 
-	// 设置专业度指标（可以从其地方获取）
-	developerMetrics.Expertise.Languages = skills
-	developerMetrics.Expertise.Depth = 0.8 // 可以根据实际情况计算
+	// 统计 GitHub 账号的指标，与其他平台账号合并计算 TalentRank
+	accountMetrics := collectAccountMetrics(ctx, incremental, profile, platformRepos, developer.StarAnalysis)
+	account := newPlatformAccount(gc, profile, accountMetrics)
+	account.Cursor = incremental.Cursor(platformRepos, eventsETag)
+	developer.UpsertAccount(account)
+	developer.ApplyAccounts()
+	emitEvent(opts.JobID, events.EventRankComputed, username, map[string]interface{}{"talent_rank": developer.TalentRank})
 
-	// 调用 calculateTalentRank
-	developer.TalentRank = gc.calculateTalentRank(developerMetrics)
+	totalStars := accountMetrics.Stars
+	contributions := accountMetrics.Commits
 
 	// 处理 Nation 信息
 	var nationConfidence float64
 	nation := extractNation(developer.Location)
 
 	if nation == "" {
-		quickPred := QuickPredictNation(username, user, repos)
+		quickPred := QuickPredictNation(username, profile, platformRepos)
 		if quickPred != nil && quickPred.Confidence >= 40 {
 			nation = quickPred.Nation
 			nationConfidence = quickPred.Confidence
@@ -270,7 +249,8 @@ func (gc *GitHubCrawler) GetUserData(username string) (*models.Developer, error)
 
 	if nation == "" {
 		log.Printf("尝试使用 AI 预测国家...")
-		aiNation, aiConfidence := gc.predictNationWithAI(user, repos)
+		aiNation, aiConfidence, consensus := gc.predictNationWithAI(user, repos)
+		developer.NationConsensus = consensus
 		if aiNation != "" {
 			nation = aiNation
 			nationConfidence = aiConfidence
@@ -282,12 +262,13 @@ func (gc *GitHubCrawler) GetUserData(username string) (*models.Developer, error)
 
 	developer.Nation = nation
 	developer.NationConfidence = nationConfidence
+	emitEvent(opts.JobID, events.EventNationPredicted, username, map[string]interface{}{"nation": nation, "confidence": nationConfidence})
 
 	// 计算置信（使用新的方法或移除）
 	developer.Confidence = calculateConfidence(
 		contributions,
 		totalStars,
-		getPtrValue(user.Followers),
+		profile.Followers,
 		developer.Location != "",
 	)
 
@@ -299,43 +280,32 @@ func (gc *GitHubCrawler) GetUserData(username string) (*models.Developer, error)
 		// 不要初始化 Issues 字段，让它保持为 nil
 	}
 
-	// 设置更新频率（根据活跃度调整）
-	if developer.CommitCount > 1000 {
-		developer.UpdateFrequency = 24 * time.Hour // 活跃用户每天更新
-	} else {
-		developer.UpdateFrequency = 7 * 24 * time.Hour // 不活跃用户每周更新
-	}
+	// 设置更新频率（根据活跃度和上次刷新的数据变化调整）
+	developer.UpdateFrequency = updateFrequencyFor(existingDev, developer)
 
 	// 保存到数据库
 	if existingDev != nil {
-		developer.ID = existingDev.ID
-		developer.CreatedAt = existingDev.CreatedAt
-		if err := developer.Update(); err != nil {
-			return nil, fmt.Errorf("更新用户失败: %v", err)
+		if err := saveOverExisting(developer, existingDev); err != nil {
+			return nil, err
 		}
 	} else {
 		if err := developer.Create(); err != nil {
-			return nil, fmt.Errorf("创建用户失败: %v", err)
+			if !mongo.IsDuplicateKeyError(err) {
+				return nil, fmt.Errorf("创建用户失败: %v", err)
+			}
+			// 并发爬取同一用户时，另一协程已经创建了记录
+			existingDev, findErr := models.FindByGitHubID(developer.GitHubID)
+			if findErr != nil || existingDev == nil {
+				return nil, fmt.Errorf("创建用户失败: %v", err)
+			}
+			if err := saveOverExisting(developer, existingDev); err != nil {
+				return nil, err
+			}
 		}
 	}
 
 	// 创建并发送评估任务
-	evaluationTask := &queue.EvaluationTask{
-		Username:     developer.Username,
-		ProfileURL:   developer.ProfileURL,
-		BlogURL:      user.GetBlog(),
-		Description:  user.GetBio(),
-		Repositories: developer.Repositories,
-		CreatedAt:    time.Now(),
-	}
-
-	// 发送评估任务到队列
-	queueClient := queue.NewQueue()
-	if err := queueClient.Publish(evaluationTask); err != nil {
-		log.Printf("Warning: Failed to publish evaluation task for %s: %v", username, err)
-	} else {
-		log.Printf("Successfully published evaluation task for %s", username)
-	}
+	publishEvaluationTask(developer, profile, opts.JobID)
 
 	// 验证保存后的数据
 	savedDev, err := models.FindByUsername(developer.Username)
@@ -359,6 +329,94 @@ func (gc *GitHubCrawler) GetUserData(username string) (*models.Developer, error)
 	return developer, nil
 }
 
+// resolveExistingDeveloper 根据 GitHub ID 找到对应的已有记录
+func resolveExistingDeveloper(user *github.User, byUsername *models.Developer) (*models.Developer, error) {
+	byID, err := models.FindByGitHubID(user.GetID())
+	if err != nil {
+		return nil, err
+	}
+
+	if byID != nil {
+		if byID.Username != user.GetLogin() {
+			log.Printf("检测到 GitHub 账号改名: %s -> %s (id=%d)", byID.Username, user.GetLogin(), user.GetID())
+		}
+		return byID, nil
+	}
+
+	// 同名记录属于另一个 GitHub 账号，说明旧账号改名后用户名被复用
+	if byUsername != nil && byUsername.GitHubID > 0 && byUsername.GitHubID != user.GetID() {
+		log.Printf("Warning: 用户名 %s 已由 id=%d 变更为 id=%d，将创建新记录",
+			user.GetLogin(), byUsername.GitHubID, user.GetID())
+		return nil, nil
+	}
+
+	return byUsername, nil
+}
+
+// saveOverExisting 用新爬取的数据更新已有记录，保留手工字段和改名历史
+func saveOverExisting(developer, existingDev *models.Developer) error {
+	developer.ID = existingDev.ID
+	developer.CreatedAt = existingDev.CreatedAt
+	developer.PreviousUsernames = existingDev.PreviousUsernames
+	developer.Watched = existingDev.Watched
+	developer.RecordRename(existingDev.Username)
+	if err := developer.PreserveManualFields(existingDev); err != nil {
+		log.Printf("Warning: 保留手工字段失败 %s: %v", developer.Username, err)
+	}
+	if err := developer.Update(); err != nil {
+		return fmt.Errorf("更新用户失败: %v", err)
+	}
+	clearRefreshFailure(developer)
+	return nil
+}
+
+// clearRefreshFailure 爬取成功后清除后台刷新失败的记录，手动爬取也能恢复被暂停的自动刷新
+func clearRefreshFailure(developer *models.Developer) {
+	if err := models.ClearRefreshFailure(developer.ID); err != nil {
+		log.Printf("Warning: 清除 %s 的刷新失败记录失败: %v", developer.Username, err)
+	}
+}
+
+// updateFrequencyFor 计算下次刷新间隔
+// 新用户按活跃度设置初始频率，已有用户根据本次数据变化程度自适应调整
+func updateFrequencyFor(existingDev, developer *models.Developer) time.Duration {
+	if existingDev != nil && existingDev.UpdateFrequency > 0 {
+		return models.AdaptUpdateFrequency(existingDev, developer)
+	}
+	if developer.CommitCount > 1000 {
+		return 24 * time.Hour // 活跃用户每天更新
+	}
+	return 7 * 24 * time.Hour // 不活跃用户每周更新
+}
+
+// LookupGitHubID 查询用户名对应的 GitHub 用户 ID
+func (gc *GitHubCrawler) LookupGitHubID(username string) (int64, error) {
+	ctx, cancel := context.WithTimeout(gc.ctx, 10*time.Second)
+	defer cancel()
+
+	user, _, err := gc.client.Users.Get(ctx, username)
+	if err != nil {
+		return 0, err
+	}
+	return user.GetID(), nil
+}
+
+// RateLimitRemaining 查询 GitHub core API 剩余配额及重置时间
+func (gc *GitHubCrawler) RateLimitRemaining() (int, time.Time, error) {
+	ctx, cancel := context.WithTimeout(gc.ctx, 10*time.Second)
+	defer cancel()
+
+	limits, _, err := gc.client.RateLimits(ctx)
+	if err != nil {
+		return 0, time.Time{}, err
+	}
+	core := limits.GetCore()
+	if core == nil {
+		return 0, time.Time{}, fmt.Errorf("GitHub 未返回 core 配额信息")
+	}
+	return core.Remaining, core.Reset.Time, nil
+}
+
 // predictNation 通过其他信息预测用户的国家
 func (gc *GitHubCrawler) predictNation(user *github.User, repos []*github.Repository) string {
 	// 1. 分析提交时间分布
@@ -592,6 +650,15 @@ func (gc *GitHubCrawler) analyzeRepoInfo(repos []*github.Repository) string {
 
 // 修改 GetUserRepositories 方法，使用并发处理
 func (gc *GitHubCrawler) GetUserRepositories(username string) ([]*github.Repository, error) {
+	repos, err := gc.listUserRepositories(username)
+	if err != nil {
+		return nil, err
+	}
+	return gc.enrichRepositories(username, repos, nil), nil
+}
+
+// listUserRepositories 分页获取用户的仓库列表
+func (gc *GitHubCrawler) listUserRepositories(username string) ([]*github.Repository, error) {
 	ctx, cancel := context.WithTimeout(gc.ctx, 20*time.Second)
 	defer cancel()
 
@@ -615,6 +682,13 @@ func (gc *GitHubCrawler) GetUserRepositories(username string) ([]*github.Reposit
 		}
 		opts.Page = resp.NextPage
 	}
+	return allRepos, nil
+}
+
+// enrichRepositories 并发获取仓库详细信息，skip 返回 true 的仓库直接使用列表数据
+func (gc *GitHubCrawler) enrichRepositories(username string, allRepos []*github.Repository, skip func(*github.Repository) bool) []*github.Repository {
+	ctx, cancel := context.WithTimeout(gc.ctx, 20*time.Second)
+	defer cancel()
 
 	// 使用工作池并发获取详细信息
 	results := make([]*github.Repository, len(allRepos))
@@ -622,6 +696,11 @@ func (gc *GitHubCrawler) GetUserRepositories(username string) ([]*github.Reposit
 	semaphore := make(chan struct{}, 5) // 限制并发数
 
 	for i, repo := range allRepos {
+		if skip != nil && skip(repo) {
+			results[i] = repo
+			continue
+		}
+
 		wg.Add(1)
 		go func(i int, repo *github.Repository) {
 			defer wg.Done()
@@ -639,25 +718,7 @@ func (gc *GitHubCrawler) GetUserRepositories(username string) ([]*github.Reposit
 	}
 
 	wg.Wait()
-	return results, nil
-}
-
-// calculateTalentRank 计算开发者的 TalentRank
-func (gc *GitHubCrawler) calculateTalentRank(metrics *models.DeveloperMetrics) float64 {
-	// 调用 models.CalculateTalentRank 进行计算
-	return models.CalculateTalentRank(metrics)
-}
-
-// 计算活动频率
-func calculateActivityFrequency(contributions int) float64 {
-	// 简单的活动频率计算
-	return math.Min(float64(contributions)/1000.0, 1.0)
-}
-
-// 计算增长趋势
-func calculateGrowthTrend(contributions int) float64 {
-	// 简单的增长趋势计算
-	return math.Min(float64(contributions)/500.0, 1.0)
+	return results
 }
 
 // 辅助函数
@@ -696,11 +757,11 @@ func (gc *GitHubCrawler) extractSkills(repos []*github.Repository) []string {
 	return skills
 }
 
-func repoNames(repos []*github.Repository) []string {
+func repoNames(repos []*Repository) []string {
 	// 使用 map 去重
 	nameMap := make(map[string]struct{})
 	for _, repo := range repos {
-		nameMap[getPtrValue(repo.Name)] = struct{}{}
+		nameMap[repo.Name] = struct{}{}
 	}
 
 	// 转换回切片
@@ -714,6 +775,20 @@ func repoNames(repos []*github.Repository) []string {
 	return names
 }
 
+// repoSummaries 提取仓库描述和主题，用于全文搜索
+func repoSummaries(repos []*Repository) []models.RepoSummary {
+	summaries := make([]models.RepoSummary, 0, len(repos))
+	for _, repo := range repos {
+		summaries = append(summaries, models.RepoSummary{
+			Name:        repo.Name,
+			Description: repo.Description,
+			Topics:      repo.Topics,
+			Stars:       repo.Stars,
+		})
+	}
+	return models.SummarizeRepositories(summaries)
+}
+
 // 使用 models 包中的函数
 func extractNation(location string) string {
 	return models.ExtractNation(location)
@@ -873,12 +948,12 @@ func (gc *GitHubCrawler) WarmupCache(usernames []string) error {
 }
 
 // 新增：速预测函数
-func QuickPredictNation(username string, user *github.User, repos []*github.Repository) *models.PredictionResult {
+func QuickPredictNation(username string, profile *Profile, repos []*Repository) *models.PredictionResult {
 	points := make(map[string]float64)
 	factors := make([]string, 0)
 
 	// 1. 检查邮箱域名
-	if email := getPtrValue(user.Email); email != "" {
+	if email := profile.Email; email != "" {
 		switch {
 		case strings.HasSuffix(email, ".cn"):
 			points["CN"] += 2.0
@@ -900,7 +975,7 @@ func QuickPredictNation(username string, user *github.User, repos []*github.Repo
 
 	// 2. 检查用户名和显示名称
 	usernameLower := strings.ToLower(username)
-	nameLower := strings.ToLower(getPtrValue(user.Name))
+	nameLower := strings.ToLower(profile.Name)
 
 	// 检查中文字符
 	if containsChinese(nameLower) {
@@ -925,7 +1000,7 @@ func QuickPredictNation(username string, user *github.User, repos []*github.Repo
 	// 3. 查仓库描述README
 	chineseCount := 0
 	for _, repo := range repos {
-		desc := strings.ToLower(getPtrValue(repo.Description))
+		desc := strings.ToLower(repo.Description)
 
 		// 检查中文内容
 		if containsChinese(desc) {
@@ -946,7 +1021,7 @@ func QuickPredictNation(username string, user *github.User, repos []*github.Repo
 	}
 
 	// 4. 检查公司信息
-	if company := getPtrValue(user.Company); company != "" {
+	if company := profile.Company; company != "" {
 		companyLower := strings.ToLower(company)
 		if containsChinese(company) ||
 			strings.Contains(companyLower, "china") ||
@@ -1003,7 +1078,8 @@ func calculateQuickConfidence(maxScore float64, factorCount int) float64 {
 }
 
 // 计算项目重要性，基于仓库的 star 数、fork 数等
-func calculateProjectImportance(repos []*github.Repository) float64 {
+// 如果提供了 star 质量分析结果，采样过的仓库使用折算后的 star 数
+func calculateProjectImportance(repos []*Repository, starAnalysis *models.StarAnalysis) float64 {
 	if len(repos) == 0 {
 		return 0.0
 	}
@@ -1013,13 +1089,16 @@ func calculateProjectImportance(repos []*github.Repository) float64 {
 
 	for _, repo := range repos {
 		// 跳过 fork 的仓库
-		if repo.GetFork() {
+		if repo.Fork {
 			continue
 		}
 
-		stars := repo.GetStargazersCount()
-		forks := repo.GetForksCount()
-		size := repo.GetSize()
+		stars := repo.Stars
+		if adjusted, ok := starAnalysis.AdjustedStarsFor(repo.Name); ok {
+			stars = adjusted
+		}
+		forks := repo.Forks
+		size := repo.Size
 
 		// 计算单个仓库的得分
 		repoScore := float64(0)
@@ -1040,7 +1119,7 @@ func calculateProjectImportance(repos []*github.Repository) float64 {
 		}
 
 		// 4. 活跃度权重
-		if !repo.GetArchived() && time.Since(repo.GetUpdatedAt().Time) < 180*24*time.Hour { // 半年内有更新
+		if !repo.Archived && time.Since(repo.UpdatedAt) < 180*24*time.Hour { // 半年内有更新
 			repoScore *= 1.2
 		}
 
@@ -1060,22 +1139,19 @@ func calculateProjectImportance(repos []*github.Repository) float64 {
 }
 
 // 计算开发者的贡献度，基 commit 数或其他贡献指标
-func (gc *GitHubCrawler) calculateContributionLevel(username string, repos []*github.Repository) float64 {
+func calculateContributionLevel(ctx context.Context, p Platform, profile *Profile, repos []*Repository) float64 {
 	var totalScore float64
 	var validRepos int
 
 	for _, repo := range repos {
 		// 跳过 fork 的仓库
-		if repo.GetFork() {
+		if repo.Fork {
 			continue
 		}
 
-		owner := repo.GetOwner().GetLogin()
-		repoName := repo.GetName()
-
 		// 1. 获取用户在该仓库的提交数
-		userCommits := gc.getUserCommitsInRepo(username, owner, repoName)
-		if userCommits == 0 {
+		userCommits, err := p.CountUserCommits(ctx, profile, repo)
+		if err != nil || userCommits == 0 {
 			continue
 		}
 
@@ -1083,12 +1159,12 @@ func (gc *GitHubCrawler) calculateContributionLevel(username string, repos []*gi
 		commitScore := math.Log10(float64(userCommits)) * 2
 
 		// 3. 如果是仓库所有者，获得额外加分
-		if owner == username {
+		if repo.Owner == profile.Login {
 			commitScore *= 1.5
 		}
 
 		// 4. 根据仓库质量调整得分
-		repoQuality := float64(repo.GetStargazersCount()) / 100.0
+		repoQuality := float64(repo.Stars) / 100.0
 		if repoQuality > 1.0 {
 			repoQuality = 1.0 + math.Log10(repoQuality) // 对高质量项目进行对数加成
 		}
@@ -1270,36 +1346,56 @@ var languageCountryMap = map[string]string{
 	"canadian":   "CA",
 }
 
-// predictNationWithAI 使用 AI 客户端预测国家
-func (gc *GitHubCrawler) predictNationWithAI(user *github.User, repos []*github.Repository) (string, float64) {
-	info := map[string]interface{}{
-		"username":    getPtrValue(user.Login),
-		"name":        getPtrValue(user.Name),
-		"email":       getPtrValue(user.Email),
-		"location":    getPtrValue(user.Location),
-		"profile_url": user.GetHTMLURL(),
-		"skills":      gc.extractSkills(repos),
-		"languages":   gc.extractLanguages(repos),
-		"repos":       repoNames(repos),
-		"commits":     gc.getTotalCommits(repos),
-		"stars":       getTotalStars(repos),
-		"forks":       getTotalForks(repos),
-		"last_active": getLastActiveTime(repos).Format("2006-01-02"),
-	}
-
-	evaluation, err := gc.aiClient.EvaluateDeveloper(gc.ctx, info)
+// predictNationWithAI 使用 AI 客户端预测国家，未配置 AI 时跳过
+// 配置了多个评估配置时同时返回各自的结论和一致程度，未能预测国家时也返回，便于人工复核
+func (gc *GitHubCrawler) predictNationWithAI(user *github.User, repos []*github.Repository) (string, float64, *models.Consensus) {
+	if gc.aiClient == nil {
+		return "", 0, nil
+	}
+
+	profile := &ai.DeveloperProfile{
+		Username:   getPtrValue(user.Login),
+		Name:       getPtrValue(user.Name),
+		Email:      getPtrValue(user.Email),
+		Location:   getPtrValue(user.Location),
+		Bio:        user.GetBio(),
+		Blog:       user.GetBlog(),
+		ProfileURL: user.GetHTMLURL(),
+		Skills:     gc.extractSkills(repos),
+		Languages:  gc.extractLanguages(repos),
+		Commits:    gc.getTotalCommits(repos),
+		Stars:      getTotalStars(repos),
+		Forks:      getTotalForks(repos),
+		LastActive: getLastActiveTime(repos),
+		CreatedAt:  user.GetCreatedAt().Time,
+	}
+	for _, repo := range repoSummaries(githubRepositories(repos)) {
+		profile.Repositories = append(profile.Repositories, ai.RepoInfo{
+			Name:        repo.Name,
+			Description: repo.Description,
+			Topics:      repo.Topics,
+			Stars:       repo.Stars,
+		})
+	}
+	profile.SortRepositories()
+
+	prediction, err := gc.aiClient.PredictNation(gc.ctx, profile)
 	if err != nil {
-		log.Printf("AI 评估失败: %v", err)
-		return "", 0
+		log.Printf("AI 预测国家失败: %v", err)
+		return "", 0, nil
+	}
+	for _, w := range prediction.InjectionWarnings {
+		log.Printf("Warning: %s 的资料疑似包含提示词注入（%s，%s）: %s", profile.Username, w.Field, w.Pattern, w.Excerpt)
 	}
 
-	if evaluation.Nation == "" {
-		log.Printf("AI 未能预测国家")
-		return "", 0
+	if prediction.Nation == "" {
+		log.Printf("AI 未能预测国家: %v", prediction.Reasons)
+		return "", 0, prediction.Consensus
 	}
 
-	log.Printf("AI 预测结果 - 国家: %s, 置信度: %.2f", evaluation.Nation, evaluation.Confidence)
-	return evaluation.Nation, evaluation.Confidence
+	log.Printf("AI 预测结果 - 国家: %s, 置信度: %.2f, 依据: %v, 模板: %s",
+		prediction.Nation, prediction.Confidence, prediction.Reasons, prediction.PromptVersion)
+	return prediction.Nation, prediction.Confidence, prediction.Consensus
 }
 
 // extractLanguages 提取所有使用的编程语言
@@ -0,0 +1,194 @@
+package crawler
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"time"
+)
+
+// GitLabClient GitLab API v4 客户端，支持 gitlab.com 和自建实例
+type GitLabClient struct {
+	rest *restClient
+}
+
+func NewGitLabClient(baseURL, token string) *GitLabClient {
+	return &GitLabClient{
+		rest: newRestClient(baseURL, func(req *http.Request) {
+			if token != "" {
+				req.Header.Set("PRIVATE-TOKEN", token)
+			}
+		}),
+	}
+}
+
+type gitlabUser struct {
+	ID           int64     `json:"id"`
+	Username     string    `json:"username"`
+	Name         string    `json:"name"`
+	PublicEmail  string    `json:"public_email"`
+	Location     string    `json:"location"`
+	Bio          string    `json:"bio"`
+	WebsiteURL   string    `json:"website_url"`
+	Organization string    `json:"organization"`
+	AvatarURL    string    `json:"avatar_url"`
+	WebURL       string    `json:"web_url"`
+	Followers    int       `json:"followers"`
+	Following    int       `json:"following"`
+	CreatedAt    time.Time `json:"created_at"`
+}
+
+type gitlabProject struct {
+	ID                int64     `json:"id"`
+	Name              string    `json:"name"`
+	PathWithNamespace string    `json:"path_with_namespace"`
+	Description       string    `json:"description"`
+	Topics            []string  `json:"topics"`
+	WebURL            string    `json:"web_url"`
+	StarCount         int       `json:"star_count"`
+	ForksCount        int       `json:"forks_count"`
+	Archived          bool      `json:"archived"`
+	CreatedAt         time.Time `json:"created_at"`
+	LastActivityAt    time.Time `json:"last_activity_at"`
+	Namespace         struct {
+		Path string `json:"path"`
+	} `json:"namespace"`
+	ForkedFromProject *struct {
+		ID int64 `json:"id"`
+	} `json:"forked_from_project"`
+}
+
+func (gl *GitLabClient) Name() string {
+	return PlatformGitLab
+}
+
+func (gl *GitLabClient) BaseURL() string {
+	return gl.rest.baseURL
+}
+
+// GetProfile 先按用户名查找用户 ID，再获取完整资料
+func (gl *GitLabClient) GetProfile(ctx context.Context, login string) (*Profile, error) {
+	var matches []gitlabUser
+	if _, err := gl.rest.getJSON(ctx, "/users", url.Values{"username": {login}}, &matches); err != nil {
+		return nil, err
+	}
+	if len(matches) == 0 {
+		// 按用户名搜索没有结果时与其他平台一样视为 404
+		return nil, &statusError{path: "/users", statusCode: http.StatusNotFound, body: "GitLab 用户不存在: " + login}
+	}
+
+	var user gitlabUser
+	if _, err := gl.rest.getJSON(ctx, fmt.Sprintf("/users/%d", matches[0].ID), nil, &user); err != nil {
+		return nil, err
+	}
+
+	return &Profile{
+		Platform:  PlatformGitLab,
+		BaseURL:   gl.BaseURL(),
+		ID:        strconv.FormatInt(user.ID, 10),
+		Login:     user.Username,
+		Name:      user.Name,
+		Email:     user.PublicEmail,
+		Location:  user.Location,
+		Bio:       user.Bio,
+		Blog:      user.WebsiteURL,
+		Company:   user.Organization,
+		AvatarURL: user.AvatarURL,
+		HTMLURL:   user.WebURL,
+		Followers: user.Followers,
+		Following: user.Following,
+		CreatedAt: user.CreatedAt,
+	}, nil
+}
+
+// ListRepositories 获取用户名下的所有项目
+func (gl *GitLabClient) ListRepositories(ctx context.Context, profile *Profile) ([]*Repository, error) {
+	var repos []*Repository
+	page := "1"
+	for page != "" {
+		var projects []gitlabProject
+		header, err := gl.rest.getJSON(ctx, "/users/"+profile.ID+"/projects", url.Values{
+			"per_page": {"100"},
+			"page":     {page},
+			"order_by": {"last_activity_at"},
+		}, &projects)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, p := range projects {
+			repos = append(repos, &Repository{
+				ID:          strconv.FormatInt(p.ID, 10),
+				Name:        p.Name,
+				FullName:    p.PathWithNamespace,
+				Owner:       p.Namespace.Path,
+				Description: p.Description,
+				Topics:      p.Topics,
+				HTMLURL:     p.WebURL,
+				Stars:       p.StarCount,
+				Forks:       p.ForksCount,
+				Fork:        p.ForkedFromProject != nil,
+				Archived:    p.Archived,
+				CreatedAt:   p.CreatedAt,
+				UpdatedAt:   p.LastActivityAt,
+				PushedAt:    p.LastActivityAt,
+			})
+		}
+		page = header.Get("X-Next-Page")
+	}
+	profile.PublicRepos = len(repos)
+	return repos, nil
+}
+
+// ListLanguages GitLab 返回语言占比，按占比从高到低排序
+func (gl *GitLabClient) ListLanguages(ctx context.Context, repo *Repository) ([]string, error) {
+	var percentages map[string]float64
+	if _, err := gl.rest.getJSON(ctx, "/projects/"+repo.ID+"/languages", nil, &percentages); err != nil {
+		return nil, err
+	}
+
+	languages := make([]string, 0, len(percentages))
+	for lang := range percentages {
+		languages = append(languages, lang)
+	}
+	sort.Slice(languages, func(i, j int) bool {
+		return percentages[languages[i]] > percentages[languages[j]]
+	})
+	if len(languages) > 0 && repo.Language == "" {
+		repo.Language = languages[0]
+	}
+	return languages, nil
+}
+
+// CountUserCommits GitLab 按提交作者名称过滤，优先读取 X-Total 头
+func (gl *GitLabClient) CountUserCommits(ctx context.Context, profile *Profile, repo *Repository) (int, error) {
+	author := profile.Name
+	if author == "" {
+		author = profile.Login
+	}
+
+	total := 0
+	page := "1"
+	for page != "" {
+		var commits []struct {
+			ID string `json:"id"`
+		}
+		header, err := gl.rest.getJSON(ctx, "/projects/"+repo.ID+"/repository/commits", url.Values{
+			"author":   {author},
+			"per_page": {"100"},
+			"page":     {page},
+		}, &commits)
+		if err != nil {
+			return total, err
+		}
+		if n, err := strconv.Atoi(header.Get("X-Total")); err == nil {
+			return n, nil
+		}
+		total += len(commits)
+		page = header.Get("X-Next-Page")
+	}
+	return total, nil
+}
@@ -0,0 +1,242 @@
+package crawler
+
+import (
+	"context"
+	"net/http"
+	"qinniu/internal/models"
+	"sync"
+	"time"
+
+	"github.com/google/go-github/v45/github"
+)
+
+// 超过该时间没有完整爬取时强制完整爬取，修正增量统计可能累积的偏差
+const fullCrawlInterval = 30 * 24 * time.Hour
+
+// CrawlOptions 爬取选项
+type CrawlOptions struct {
+	Full  bool   // 忽略增量游标，完整重新爬取
+	JobID string // 所属的爬取任务，进度事件和评估任务带上任务 ID，供任务的事件流区分
+}
+
+// commitDeltaCounter 支持按提交 SHA 增量统计用户提交数的平台
+type commitDeltaCounter interface {
+	// CountUserCommitsSince 从最新提交开始统计，遇到 stopSHA 时停止
+	// found 表示是否遇到了 stopSHA，未遇到时 count 即为全部提交数
+	CountUserCommitsSince(ctx context.Context, profile *Profile, repo *Repository, stopSHA string) (count int, head string, found bool, err error)
+}
+
+// incrementalPlatform 在平台客户端外包装增量爬取逻辑
+// 没有新推送的仓库直接复用游标中缓存的语言和提交统计，只请求变化的部分
+type incrementalPlatform struct {
+	Platform
+	previous *models.CrawlCursor
+	full     bool
+	quiet    bool // 用户公开事件没有变化，说明没有新的提交
+
+	mu     sync.Mutex
+	repos  map[string]*models.RepoCursor
+	failed map[string]bool
+}
+
+func newIncrementalPlatform(p Platform, previous *models.CrawlCursor, opts CrawlOptions) *incrementalPlatform {
+	full := opts.Full || previous == nil || time.Since(previous.LastFullCrawl) > fullCrawlInterval
+	if full {
+		previous = nil
+	}
+	return &incrementalPlatform{
+		Platform: p,
+		previous: previous,
+		full:     full,
+		repos:    make(map[string]*models.RepoCursor),
+		failed:   make(map[string]bool),
+	}
+}
+
+// unchanged 返回仓库自上次爬取以来没有新推送时的游标
+func (ip *incrementalPlatform) unchanged(repo *Repository) *models.RepoCursor {
+	return ip.unchangedSince(repo.ID, repo.PushedAt)
+}
+
+func (ip *incrementalPlatform) unchangedSince(repoID string, pushedAt time.Time) *models.RepoCursor {
+	prev := ip.previous.Repo(repoID)
+	if prev == nil || pushedAt.After(prev.PushedAt) {
+		return nil
+	}
+	return prev
+}
+
+func (ip *incrementalPlatform) ListLanguages(ctx context.Context, repo *Repository) ([]string, error) {
+	if prev := ip.unchanged(repo); prev != nil {
+		ip.record(repo, func(c *models.RepoCursor) { c.Languages = prev.Languages })
+		return prev.Languages, nil
+	}
+
+	languages, err := ip.Platform.ListLanguages(ctx, repo)
+	if err != nil {
+		ip.markFailed(repo)
+		return nil, err
+	}
+	ip.record(repo, func(c *models.RepoCursor) { c.Languages = languages })
+	return languages, nil
+}
+
+func (ip *incrementalPlatform) CountUserCommits(ctx context.Context, profile *Profile, repo *Repository) (int, error) {
+	prev := ip.previous.Repo(repo.ID)
+
+	// 仓库没有新推送，或者用户没有新的公开活动（推送可能来自其他协作者）
+	if prev != nil && (ip.quiet || ip.unchanged(repo) != nil) {
+		ip.record(repo, func(c *models.RepoCursor) {
+			c.UserCommits = prev.UserCommits
+			c.LastCommitSHA = prev.LastCommitSHA
+		})
+		return prev.UserCommits, nil
+	}
+
+	counter, ok := ip.Platform.(commitDeltaCounter)
+	if !ok {
+		count, err := ip.Platform.CountUserCommits(ctx, profile, repo)
+		if err != nil {
+			ip.markFailed(repo)
+			return count, err
+		}
+		ip.record(repo, func(c *models.RepoCursor) { c.UserCommits = count })
+		return count, nil
+	}
+
+	stopSHA := ""
+	if prev != nil {
+		stopSHA = prev.LastCommitSHA
+	}
+	count, head, found, err := counter.CountUserCommitsSince(ctx, profile, repo, stopSHA)
+	if err != nil {
+		ip.markFailed(repo)
+		return count, err
+	}
+	// 找到了上次的提交，只需要加上新增的部分；否则历史被改写，count 已是完整统计
+	if found {
+		count += prev.UserCommits
+		if head == "" {
+			head = stopSHA
+		}
+	}
+	ip.record(repo, func(c *models.RepoCursor) {
+		c.UserCommits = count
+		c.LastCommitSHA = head
+	})
+	return count, nil
+}
+
+func (ip *incrementalPlatform) record(repo *Repository, update func(*models.RepoCursor)) {
+	ip.mu.Lock()
+	defer ip.mu.Unlock()
+
+	cursor, ok := ip.repos[repo.ID]
+	if !ok {
+		cursor = &models.RepoCursor{RepoID: repo.ID}
+		if prev := ip.previous.Repo(repo.ID); prev != nil {
+			*cursor = *prev
+		}
+		ip.repos[repo.ID] = cursor
+	}
+	cursor.FullName = repo.FullName
+	update(cursor)
+}
+
+func (ip *incrementalPlatform) markFailed(repo *Repository) {
+	ip.mu.Lock()
+	defer ip.mu.Unlock()
+	ip.failed[repo.ID] = true
+}
+
+// Cursor 根据本次爬取结果生成新的游标
+// 请求失败的仓库保留上次的 pushed_at，下次刷新时重新获取
+func (ip *incrementalPlatform) Cursor(repos []*Repository, eventsETag string) *models.CrawlCursor {
+	ip.mu.Lock()
+	defer ip.mu.Unlock()
+
+	cursor := &models.CrawlCursor{EventsETag: eventsETag, LastFullCrawl: time.Now()}
+	if !ip.full {
+		cursor.LastFullCrawl = ip.previous.LastFullCrawl
+	}
+
+	for _, repo := range repos {
+		rc, ok := ip.repos[repo.ID]
+		if !ok {
+			continue
+		}
+		entry := *rc
+		if ip.failed[repo.ID] {
+			entry.PushedAt = time.Time{}
+			if prev := ip.previous.Repo(repo.ID); prev != nil {
+				entry.PushedAt = prev.PushedAt
+			}
+		} else {
+			entry.PushedAt = repo.PushedAt
+		}
+		if entry.PushedAt.After(cursor.LastPushedAt) {
+			cursor.LastPushedAt = entry.PushedAt
+		}
+		cursor.Repos = append(cursor.Repos, entry)
+	}
+	return cursor
+}
+
+// CountUserCommitsSince 按时间倒序遍历用户的提交，遇到上次记录的 SHA 时停止
+func (gc *GitHubCrawler) CountUserCommitsSince(ctx context.Context, profile *Profile, repo *Repository, stopSHA string) (int, string, bool, error) {
+	opts := &github.CommitsListOptions{
+		Author:      profile.Login,
+		ListOptions: github.ListOptions{PerPage: 100},
+	}
+
+	count := 0
+	head := ""
+	for {
+		// 与 getUserCommitsInRepo 一致，提交统计不受单次爬取的超时限制
+		commits, resp, err := gc.client.Repositories.ListCommits(gc.ctx, repo.Owner, repo.Name, opts)
+		if err != nil {
+			// 空仓库返回 409，视为没有提交
+			if resp != nil && resp.StatusCode == http.StatusConflict {
+				return count, head, false, nil
+			}
+			return count, head, false, err
+		}
+
+		for _, commit := range commits {
+			if head == "" {
+				head = commit.GetSHA()
+			}
+			if stopSHA != "" && commit.GetSHA() == stopSHA {
+				return count, head, true, nil
+			}
+			count++
+		}
+
+		if resp.NextPage == 0 {
+			break
+		}
+		opts.Page = resp.NextPage
+	}
+	return count, head, false, nil
+}
+
+// userEventsChanged 使用 ETag 条件请求检查用户公开事件是否有变化
+// 返回 304 时不消耗 GitHub 配额
+func (gc *GitHubCrawler) userEventsChanged(ctx context.Context, login, etag string) (string, bool, error) {
+	req, err := gc.client.NewRequest(http.MethodGet, "users/"+login+"/events/public?per_page=1", nil)
+	if err != nil {
+		return etag, true, err
+	}
+	if etag != "" {
+		req.Header.Set("If-None-Match", etag)
+	}
+
+	resp, err := gc.client.Do(ctx, req, nil)
+	if resp != nil && resp.StatusCode == http.StatusNotModified {
+		return etag, false, nil
+	}
+	if err != nil {
+		return etag, true, err
+	}
+	return resp.Header.Get("ETag"), true, nil
+}
@@ -0,0 +1,109 @@
+package crawler
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+)
+
+// 支持的代码托管平台
+const (
+	PlatformGitHub = "github"
+	PlatformGitLab = "gitlab"
+	PlatformGitee  = "gitee"
+)
+
+// Profile 与平台无关的用户资料
+type Profile struct {
+	Platform    string
+	BaseURL     string
+	ID          string // 平台内不可变的用户 ID
+	Login       string
+	Name        string
+	Email       string
+	Location    string
+	Bio         string
+	Blog        string
+	Company     string
+	AvatarURL   string
+	HTMLURL     string
+	Followers   int
+	Following   int
+	PublicRepos int
+	CreatedAt   time.Time
+}
+
+// Repository 与平台无关的仓库信息
+type Repository struct {
+	ID          string
+	Name        string
+	FullName    string
+	Owner       string
+	Description string
+	Language    string // 主语言
+	Topics      []string
+	HTMLURL     string
+	Stars       int
+	Forks       int
+	Size        int
+	Fork        bool
+	Archived    bool
+	CreatedAt   time.Time
+	UpdatedAt   time.Time
+	PushedAt    time.Time
+}
+
+// Platform 代码托管平台的数据访问接口
+type Platform interface {
+	// Name 返回平台标识，如 github、gitlab、gitee
+	Name() string
+	// BaseURL 返回 API 地址，自建实例各不相同
+	BaseURL() string
+	// GetProfile 获取用户资料
+	GetProfile(ctx context.Context, login string) (*Profile, error)
+	// ListRepositories 获取用户拥有的仓库
+	ListRepositories(ctx context.Context, profile *Profile) ([]*Repository, error)
+	// ListLanguages 获取仓库使用的编程语言
+	ListLanguages(ctx context.Context, repo *Repository) ([]string, error)
+	// CountUserCommits 统计用户在仓库中的提交数
+	CountUserCommits(ctx context.Context, profile *Profile, repo *Repository) (int, error)
+}
+
+// NewPlatform 根据平台名称和 API 地址创建平台客户端
+// baseURL 为空时使用环境变量或官方实例的默认地址
+func NewPlatform(name, baseURL string) (Platform, error) {
+	switch strings.ToLower(name) {
+	case PlatformGitLab:
+		if baseURL == "" {
+			baseURL = envOrDefault("GITLAB_BASE_URL", "https://gitlab.com/api/v4")
+		}
+		return NewGitLabClient(baseURL, os.Getenv("GITLAB_TOKEN")), nil
+	case PlatformGitee:
+		if baseURL == "" {
+			baseURL = envOrDefault("GITEE_BASE_URL", "https://gitee.com/api/v5")
+		}
+		return NewGiteeClient(baseURL, os.Getenv("GITEE_TOKEN")), nil
+	case PlatformGitHub, "":
+		return NewGitHubCrawler(), nil
+	}
+	return nil, fmt.Errorf("不支持的平台: %s", name)
+}
+
+// AccountKey 生成跨平台账号的唯一标识：平台|主机|用户ID
+func AccountKey(platform, baseURL, accountID string) string {
+	host := baseURL
+	if u, err := url.Parse(baseURL); err == nil && u.Host != "" {
+		host = u.Host
+	}
+	return fmt.Sprintf("%s|%s|%s", platform, strings.ToLower(host), accountID)
+}
+
+func envOrDefault(key, fallback string) string {
+	if v := os.Getenv(key); v != "" {
+		return v
+	}
+	return fallback
+}
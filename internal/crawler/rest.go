@@ -0,0 +1,74 @@
+package crawler
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// restClient GitLab / Gitee 等 REST API 的简单封装
+type restClient struct {
+	baseURL    string
+	httpClient *http.Client
+	// authorize 为请求添加认证信息，各平台方式不同
+	authorize func(req *http.Request)
+}
+
+func newRestClient(baseURL string, authorize func(req *http.Request)) *restClient {
+	return &restClient{
+		baseURL:    strings.TrimRight(baseURL, "/"),
+		httpClient: &http.Client{Timeout: 30 * time.Second},
+		authorize:  authorize,
+	}
+}
+
+// statusError 平台 API 返回了非 200 的状态码
+type statusError struct {
+	path       string
+	statusCode int
+	body       string
+}
+
+func (e *statusError) Error() string {
+	return fmt.Sprintf("%s returned status code %d: %s", e.path, e.statusCode, e.body)
+}
+
+// getJSON 发送 GET 请求并解析 JSON 响应，返回响应头用于分页
+func (rc *restClient) getJSON(ctx context.Context, path string, query url.Values, out interface{}) (http.Header, error) {
+	endpoint := rc.baseURL + path
+	if len(query) > 0 {
+		endpoint += "?" + query.Encode()
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %v", err)
+	}
+	req.Header.Set("Accept", "application/json")
+	if rc.authorize != nil {
+		rc.authorize(req)
+	}
+
+	resp, err := rc.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to send request: %v", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return resp.Header, &statusError{path: path, statusCode: resp.StatusCode, body: string(body)}
+	}
+
+	if out != nil {
+		if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+			return resp.Header, fmt.Errorf("failed to decode response: %v", err)
+		}
+	}
+	return resp.Header, nil
+}
@@ -0,0 +1,299 @@
+package crawler
+
+import (
+	"context"
+	"hash/fnv"
+	"log"
+	"math"
+	"math/rand"
+	"os"
+	"qinniu/internal/models"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/go-github/v45/github"
+)
+
+const (
+	// 分数低于该阈值的 stargazer 视为可疑账号
+	genuineScoreThreshold = 0.5
+	// 分析结果的复用时间，避免每次刷新都消耗大量 API 配额
+	starAnalysisTTL = 7 * 24 * time.Hour
+	// 复用期内 star 数的变化超过该比例时重新采样，否则按原有的真实比例重新折算
+	starAnalysisMaxDrift = 0.2
+	// GitHub 对 stargazers 列表最多只允许翻到第 400 页
+	maxStargazerPages = 400
+)
+
+// StarAnalysisConfig star 质量分析的配置
+type StarAnalysisConfig struct {
+	Enabled    bool
+	TopRepos   int // 分析 star 最多的前 N 个仓库
+	SampleSize int // 每个仓库采样的 stargazer 数量
+}
+
+// loadStarAnalysisConfig 从环境变量读取配置，默认关闭
+func loadStarAnalysisConfig() StarAnalysisConfig {
+	cfg := StarAnalysisConfig{
+		Enabled:    strings.EqualFold(os.Getenv("STAR_ANALYSIS_ENABLED"), "true"),
+		TopRepos:   3,
+		SampleSize: 30,
+	}
+	if n, err := strconv.Atoi(os.Getenv("STAR_ANALYSIS_TOP_REPOS")); err == nil && n > 0 {
+		cfg.TopRepos = n
+	}
+	if n, err := strconv.Atoi(os.Getenv("STAR_ANALYSIS_SAMPLE_SIZE")); err == nil && n > 0 {
+		cfg.SampleSize = n
+	}
+	return cfg
+}
+
+// analyzeStarQuality 对 star 最多的几个原创仓库进行 stargazer 采样，估算真实 star 比例
+func (gc *GitHubCrawler) analyzeStarQuality(repos []*github.Repository, previous *models.StarAnalysis) *models.StarAnalysis {
+	if !gc.starConfig.Enabled {
+		return nil
+	}
+
+	// 近期分析过则复用采样结果，stargazer 采样的 API 开销很大
+	if previous != nil && time.Since(previous.AnalyzedAt) < starAnalysisTTL {
+		if analysis := rescaleStarAnalysis(previous, repos); analysis != nil {
+			return analysis
+		}
+		log.Printf("Star 数变化明显（上次 %d），重新进行 star 质量分析", previous.RawStars)
+	}
+
+	candidates := make([]*github.Repository, 0, len(repos))
+	rawStars := 0
+	for _, repo := range repos {
+		if repo.GetFork() {
+			continue
+		}
+		rawStars += repo.GetStargazersCount()
+		if repo.GetStargazersCount() > 0 {
+			candidates = append(candidates, repo)
+		}
+	}
+	sort.Slice(candidates, func(i, j int) bool {
+		return candidates[i].GetStargazersCount() > candidates[j].GetStargazersCount()
+	})
+	if len(candidates) > gc.starConfig.TopRepos {
+		candidates = candidates[:gc.starConfig.TopRepos]
+	}
+
+	analysis := &models.StarAnalysis{
+		AnalyzedAt:    time.Now(),
+		RawStars:      rawStars,
+		AdjustedStars: rawStars,
+		GenuineRatio:  1.0,
+		SampleSize:    gc.starConfig.SampleSize,
+	}
+
+	for _, repo := range candidates {
+		sample, err := gc.sampleRepoStars(repo)
+		if err != nil {
+			log.Printf("Warning: star 采样失败 %s: %v", repo.GetFullName(), err)
+			continue
+		}
+		analysis.Repositories = append(analysis.Repositories, *sample)
+	}
+	summarizeStarSamples(analysis)
+
+	log.Printf("Star 质量分析完成 - 原始: %d, 折算后: %d, 真实比例: %.2f",
+		analysis.RawStars, analysis.AdjustedStars, analysis.GenuineRatio)
+
+	return analysis
+}
+
+// rescaleStarAnalysis 按仓库当前的 star 数和上次采样的真实比例重新折算
+// 总 star 数或某个采样仓库的 star 数变化超过 starAnalysisMaxDrift 时返回 nil，需要重新采样
+func rescaleStarAnalysis(previous *models.StarAnalysis, repos []*github.Repository) *models.StarAnalysis {
+	rawStars := 0
+	current := make(map[string]int, len(repos))
+	for _, repo := range repos {
+		if repo.GetFork() {
+			continue
+		}
+		rawStars += repo.GetStargazersCount()
+		current[repo.GetName()] = repo.GetStargazersCount()
+	}
+	if starsDrifted(previous.RawStars, rawStars) {
+		return nil
+	}
+
+	analysis := *previous
+	analysis.RawStars = rawStars
+	analysis.Repositories = make([]models.RepoStarSample, 0, len(previous.Repositories))
+	for _, sample := range previous.Repositories {
+		stars, ok := current[sample.Repo]
+		if !ok {
+			continue // 仓库已删除或改名
+		}
+		if starsDrifted(sample.Stars, stars) {
+			return nil
+		}
+		sample.Stars = stars
+		sample.AdjustedStars = int(math.Round(float64(stars) * sample.GenuineRatio))
+		analysis.Repositories = append(analysis.Repositories, sample)
+	}
+	summarizeStarSamples(&analysis)
+	return &analysis
+}
+
+// starsDrifted star 数的相对变化是否超过 starAnalysisMaxDrift
+func starsDrifted(before, after int) bool {
+	return math.Abs(float64(after-before)) > starAnalysisMaxDrift*math.Max(float64(before), 1)
+}
+
+// summarizeStarSamples 根据各仓库的采样结果计算整体真实比例和折算后的 star 总数，未采样的仓库按原始 star 数计算
+func summarizeStarSamples(analysis *models.StarAnalysis) {
+	var sampledStars, adjustedSampledStars int
+	for _, sample := range analysis.Repositories {
+		sampledStars += sample.Stars
+		adjustedSampledStars += sample.AdjustedStars
+	}
+
+	analysis.GenuineRatio = 1.0
+	analysis.AdjustedStars = analysis.RawStars
+	if sampledStars > 0 {
+		analysis.GenuineRatio = float64(adjustedSampledStars) / float64(sampledStars)
+		analysis.AdjustedStars = analysis.RawStars - sampledStars + adjustedSampledStars
+	}
+}
+
+// sampleRepoStars 采样单个仓库的 stargazer 并统计真实度
+func (gc *GitHubCrawler) sampleRepoStars(repo *github.Repository) (*models.RepoStarSample, error) {
+	ctx, cancel := context.WithTimeout(gc.ctx, 60*time.Second)
+	defer cancel()
+
+	owner := repo.GetOwner().GetLogin()
+	name := repo.GetName()
+	stars := repo.GetStargazersCount()
+
+	stargazers, err := gc.sampleStargazers(ctx, owner, name, stars)
+	if err != nil {
+		return nil, err
+	}
+
+	sample := &models.RepoStarSample{
+		Repo:  name,
+		Stars: stars,
+	}
+
+	var totalScore, totalAge, totalFollowers float64
+	for _, sg := range stargazers {
+		// stargazers 列表只包含精简的用户信息，需要单独获取详情
+		user, _, err := gc.client.Users.Get(ctx, sg.GetUser().GetLogin())
+		if err != nil {
+			continue
+		}
+
+		score := scoreStargazer(user, sg.GetStarredAt().Time)
+		sample.Sampled++
+		totalScore += score
+		totalAge += sg.GetStarredAt().Sub(user.GetCreatedAt().Time).Hours() / 24
+		totalFollowers += float64(user.GetFollowers())
+
+		if user.GetPublicRepos() == 0 && user.GetFollowers() == 0 {
+			sample.ZeroActivity++
+		}
+		if score >= genuineScoreThreshold {
+			sample.Genuine++
+		} else {
+			sample.Suspicious++
+		}
+	}
+
+	if sample.Sampled == 0 {
+		sample.GenuineRatio = 1.0
+		sample.AdjustedStars = stars
+		return sample, nil
+	}
+
+	n := float64(sample.Sampled)
+	sample.AvgScore = totalScore / n
+	sample.AvgAccountAgeDays = totalAge / n
+	sample.AvgFollowers = totalFollowers / n
+
+	// 使用先验平滑，样本较少时不至于把 star 数打得过低
+	const priorRatio, priorWeight = 0.9, 5.0
+	sample.GenuineRatio = (float64(sample.Genuine) + priorRatio*priorWeight) / (n + priorWeight)
+	sample.AdjustedStars = int(math.Round(float64(stars) * sample.GenuineRatio))
+
+	return sample, nil
+}
+
+// sampleStargazers 从列表的首、中、尾几页抽取 stargazer，并按仓库名确定性地打乱
+func (gc *GitHubCrawler) sampleStargazers(ctx context.Context, owner, name string, stars int) ([]*github.Stargazer, error) {
+	const perPage = 100
+	lastPage := (stars + perPage - 1) / perPage
+	if lastPage > maxStargazerPages {
+		lastPage = maxStargazerPages
+	}
+	if lastPage < 1 {
+		lastPage = 1
+	}
+
+	pages := []int{1}
+	if mid := (lastPage + 1) / 2; mid > 1 {
+		pages = append(pages, mid)
+	}
+	if lastPage > pages[len(pages)-1] {
+		pages = append(pages, lastPage)
+	}
+
+	var pool []*github.Stargazer
+	for _, page := range pages {
+		stargazers, _, err := gc.client.Activity.ListStargazers(ctx, owner, name, &github.ListOptions{
+			Page:    page,
+			PerPage: perPage,
+		})
+		if err != nil {
+			if len(pool) > 0 {
+				break
+			}
+			return nil, err
+		}
+		pool = append(pool, stargazers...)
+	}
+
+	// 固定随机种子，保证同一仓库的采样结果可复现
+	h := fnv.New64a()
+	h.Write([]byte(owner + "/" + name))
+	rng := rand.New(rand.NewSource(int64(h.Sum64())))
+	rng.Shuffle(len(pool), func(i, j int) { pool[i], pool[j] = pool[j], pool[i] })
+
+	if len(pool) > gc.starConfig.SampleSize {
+		pool = pool[:gc.starConfig.SampleSize]
+	}
+	return pool, nil
+}
+
+// scoreStargazer 根据账号年龄、活跃度和关注者数量给 stargazer 打分，范围 0-1
+func scoreStargazer(user *github.User, starredAt time.Time) float64 {
+	// 1. 点 star 时的账号年龄 (40%)
+	ageDays := starredAt.Sub(user.GetCreatedAt().Time).Hours() / 24
+	ageScore := math.Max(0, math.Min(ageDays/365.0, 1.0))
+
+	// 2. 账号活跃度 (35%)
+	activityScore := 0.0
+	if user.GetPublicRepos() > 0 {
+		activityScore += 0.4
+	}
+	if user.GetPublicGists() > 0 || user.GetFollowing() > 0 {
+		activityScore += 0.2
+	}
+	if user.GetBio() != "" || user.GetName() != "" || user.GetBlog() != "" {
+		activityScore += 0.2
+	}
+	// 资料在注册之后被更新过，说明账号有人在使用
+	if user.GetUpdatedAt().Sub(user.GetCreatedAt().Time) > 24*time.Hour {
+		activityScore += 0.2
+	}
+
+	// 3. 关注者数量 (25%)
+	followerScore := math.Min(math.Log1p(float64(user.GetFollowers()))/math.Log1p(50), 1.0)
+
+	return ageScore*0.4 + activityScore*0.35 + followerScore*0.25
+}
@@ -0,0 +1,198 @@
+package models
+
+import (
+	"context"
+	"errors"
+	"math"
+	"sort"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// PlatformAccount 开发者在某个代码托管平台上的账号
+type PlatformAccount struct {
+	Key         string         `bson:"key" json:"key"`                               // 平台|主机|用户ID，全局唯一
+	Platform    string         `bson:"platform" json:"platform"`                     // github / gitlab / gitee
+	BaseURL     string         `bson:"base_url,omitempty" json:"base_url,omitempty"` // 自建实例的 API 地址
+	AccountID   string         `bson:"account_id" json:"account_id"`
+	Login       string         `bson:"login" json:"login"`
+	ProfileURL  string         `bson:"profile_url,omitempty" json:"profile_url,omitempty"`
+	Metrics     AccountMetrics `bson:"metrics" json:"metrics"`
+	LastCrawled time.Time      `bson:"last_crawled" json:"last_crawled"`
+	Cursor      *CrawlCursor   `bson:"cursor,omitempty" json:"-"` // 增量爬取游标
+}
+
+// CrawlCursor 账号级别的增量爬取游标，刷新时只获取变化的仓库和新提交
+type CrawlCursor struct {
+	LastPushedAt  time.Time    `bson:"last_pushed_at"`        // 已处理仓库中最新的 pushed_at
+	EventsETag    string       `bson:"events_etag,omitempty"` // 用户公开事件列表的 ETag
+	LastFullCrawl time.Time    `bson:"last_full_crawl"`       // 上次完整爬取的时间
+	Repos         []RepoCursor `bson:"repos,omitempty"`
+}
+
+// RepoCursor 单个仓库的增量爬取游标，同时缓存上次统计的结果
+type RepoCursor struct {
+	RepoID        string    `bson:"repo_id"`
+	FullName      string    `bson:"full_name"`
+	PushedAt      time.Time `bson:"pushed_at"`
+	LastCommitSHA string    `bson:"last_commit_sha,omitempty"` // 用户在该仓库最新一次提交
+	UserCommits   int       `bson:"user_commits"`
+	Languages     []string  `bson:"languages,omitempty"`
+}
+
+// Repo 返回指定仓库的游标
+func (c *CrawlCursor) Repo(repoID string) *RepoCursor {
+	if c == nil {
+		return nil
+	}
+	for i := range c.Repos {
+		if c.Repos[i].RepoID == repoID {
+			return &c.Repos[i]
+		}
+	}
+	return nil
+}
+
+// AccountMetrics 单个平台账号的指标快照，用于跨平台合并 TalentRank
+type AccountMetrics struct {
+	Repos          int       `bson:"repos" json:"repos"`
+	Stars          int       `bson:"stars" json:"stars"`
+	AdjustedStars  int       `bson:"adjusted_stars" json:"adjusted_stars"`
+	Forks          int       `bson:"forks" json:"forks"`
+	Commits        int       `bson:"commits" json:"commits"`
+	Followers      int       `bson:"followers" json:"followers"`
+	ProjectQuality float64   `bson:"project_quality" json:"project_quality"`
+	Recognition    float64   `bson:"recognition" json:"recognition"`
+	Languages      []string  `bson:"languages,omitempty" json:"languages,omitempty"`
+	LastActive     time.Time `bson:"last_active" json:"last_active"`
+}
+
+// FindByAccountKey 通过平台账号标识查找开发者
+func FindByAccountKey(key string) (*Developer, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	var developer Developer
+	err := GetCollection().FindOne(ctx, bson.M{"accounts.key": key}).Decode(&developer)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	return &developer, nil
+}
+
+// Account 返回指定标识的平台账号
+func (d *Developer) Account(key string) *PlatformAccount {
+	for i := range d.Accounts {
+		if d.Accounts[i].Key == key {
+			return &d.Accounts[i]
+		}
+	}
+	return nil
+}
+
+// UpsertAccount 添加或更新平台账号
+func (d *Developer) UpsertAccount(account PlatformAccount) {
+	if existing := d.Account(account.Key); existing != nil {
+		*existing = account
+		return
+	}
+	d.Accounts = append(d.Accounts, account)
+}
+
+// RemoveAccount 移除平台账号，返回是否存在
+func (d *Developer) RemoveAccount(key string) bool {
+	for i := range d.Accounts {
+		if d.Accounts[i].Key == key {
+			d.Accounts = append(d.Accounts[:i], d.Accounts[i+1:]...)
+			return true
+		}
+	}
+	return false
+}
+
+// ApplyAccounts 根据所有平台账号重新计算合并后的统计数据和 TalentRank
+func (d *Developer) ApplyAccounts() {
+	if len(d.Accounts) == 0 {
+		return
+	}
+
+	var stars, forks, commits int
+	skillSet := make(map[string]struct{})
+	for _, account := range d.Accounts {
+		stars += account.Metrics.Stars
+		forks += account.Metrics.Forks
+		commits += account.Metrics.Commits
+		for _, lang := range account.Metrics.Languages {
+			skillSet[lang] = struct{}{}
+		}
+	}
+
+	skills := make([]string, 0, len(skillSet))
+	for skill := range skillSet {
+		skills = append(skills, skill)
+	}
+	sort.Strings(skills)
+
+	d.StarCount = stars
+	d.ForkCount = forks
+	d.CommitCount = commits
+	d.Skills = skills
+	d.TalentRank = CalculateTalentRank(BuildDeveloperMetrics(d.Accounts))
+}
+
+// BuildDeveloperMetrics 合并多个平台账号的指标
+// 数量类指标求和，质量类指标按仓库数量加权平均
+func BuildDeveloperMetrics(accounts []PlatformAccount) *DeveloperMetrics {
+	metrics := &DeveloperMetrics{}
+
+	var totalRepos int
+	var qualitySum, recognitionSum float64
+	languageSet := make(map[string]struct{})
+
+	for _, account := range accounts {
+		m := account.Metrics
+		metrics.Contributions.CommitCount += m.Commits
+		metrics.Projects.StarCount += m.AdjustedStars
+		metrics.Projects.ForkCount += m.Forks
+		metrics.Projects.TotalCount += m.Repos
+		metrics.Influence.Followers += m.Followers
+
+		// 没有仓库的账号也要参与平均，权重至少为 1
+		weight := math.Max(float64(m.Repos), 1)
+		qualitySum += m.ProjectQuality * weight
+		recognitionSum += m.Recognition * weight
+		totalRepos += int(weight)
+
+		if m.LastActive.After(metrics.Activity.LastActive) {
+			metrics.Activity.LastActive = m.LastActive
+		}
+		for _, lang := range m.Languages {
+			languageSet[lang] = struct{}{}
+		}
+	}
+
+	if totalRepos > 0 {
+		metrics.Projects.Quality = qualitySum / float64(totalRepos)
+		metrics.Influence.Recognition = recognitionSum / float64(totalRepos)
+	}
+
+	commits := metrics.Contributions.CommitCount
+	metrics.Contributions.Quality = 0.8 // 默认质量分数
+	metrics.Activity.Frequency = calculateActivityFrequency(commits)
+	metrics.Activity.Consistency = 0.8 // 默认持续性分数
+	metrics.Activity.Growth = calculateGrowthTrend(commits)
+
+	for lang := range languageSet {
+		metrics.Expertise.Languages = append(metrics.Expertise.Languages, lang)
+	}
+	sort.Strings(metrics.Expertise.Languages)
+	metrics.Expertise.Depth = 0.8 // 可以根据实际情况计算
+
+	return metrics
+}
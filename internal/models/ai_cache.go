@@ -0,0 +1,77 @@
+package models
+
+import (
+	"context"
+	"errors"
+	"qinniu/internal/pkg/database"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const aiResultCollectionName = "ai_results"
+
+// AIResult 缓存的模型输出，按输入哈希（提示词版本、模型和渲染后的提示词）索引
+type AIResult struct {
+	Key              string     `bson:"_id" json:"key"`
+	Operation        string     `bson:"operation" json:"operation"` // 提示词模板名，如 evaluation、nation
+	PromptVersion    string     `bson:"prompt_version" json:"prompt_version"`
+	Provider         string     `bson:"provider" json:"provider"`
+	Model            string     `bson:"model" json:"model"`
+	Content          string     `bson:"content" json:"content"` // 通过 Schema 校验的模型输出
+	PromptTokens     int        `bson:"prompt_tokens" json:"prompt_tokens"`
+	CompletionTokens int        `bson:"completion_tokens" json:"completion_tokens"`
+	Hits             int        `bson:"hits" json:"hits"`
+	CreatedAt        time.Time  `bson:"created_at" json:"created_at"`
+	LastHitAt        time.Time  `bson:"last_hit_at,omitempty" json:"last_hit_at,omitempty"`
+	ExpiresAt        *time.Time `bson:"expires_at,omitempty" json:"expires_at,omitempty"` // 为空时不过期
+}
+
+func GetAIResultCollection() *mongo.Collection {
+	return database.DB.Collection(aiResultCollectionName)
+}
+
+// ensureAIResultIndexes 缓存到 expires_at 后由 MongoDB 删除
+func ensureAIResultIndexes(ctx context.Context) error {
+	_, err := GetAIResultCollection().Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys:    bson.D{{Key: "expires_at", Value: 1}},
+		Options: options.Index().SetName("ttl_expires_at").SetExpireAfterSeconds(0),
+	})
+	return err
+}
+
+// FindAIResult 查找缓存的模型输出并记录命中，不存在时返回 nil
+func FindAIResult(key string) (*AIResult, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	var result AIResult
+	err := GetAIResultCollection().FindOneAndUpdate(ctx,
+		bson.M{"_id": key, "$or": bson.A{
+			bson.M{"expires_at": bson.M{"$exists": false}},
+			bson.M{"expires_at": bson.M{"$gt": time.Now()}},
+		}},
+		bson.M{"$inc": bson.M{"hits": 1}, "$set": bson.M{"last_hit_at": time.Now()}},
+	).Decode(&result)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &result, nil
+}
+
+// SaveAIResult 保存模型输出，同一输入并发调用时保留后写入的结果
+func SaveAIResult(result *AIResult) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	if result.CreatedAt.IsZero() {
+		result.CreatedAt = time.Now()
+	}
+	_, err := GetAIResultCollection().ReplaceOne(ctx, bson.M{"_id": result.Key}, result, options.Replace().SetUpsert(true))
+	return err
+}
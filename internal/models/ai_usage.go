@@ -0,0 +1,126 @@
+package models
+
+import (
+	"context"
+	"qinniu/internal/pkg/database"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const aiUsageCollectionName = "ai_usage"
+
+// AIUsage 一次模型调用的用量，命中缓存的调用也记录一条，token 数为 0
+type AIUsage struct {
+	ID               primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	Operation        string             `bson:"operation" json:"operation"`
+	PromptVersion    string             `bson:"prompt_version" json:"prompt_version"`
+	Provider         string             `bson:"provider" json:"provider"`
+	Model            string             `bson:"model" json:"model"`
+	Username         string             `bson:"username,omitempty" json:"username,omitempty"`
+	Cached           bool               `bson:"cached" json:"cached"`
+	Repair           bool               `bson:"repair,omitempty" json:"repair,omitempty"` // 校验失败后请求模型修正的调用
+	PromptTokens     int                `bson:"prompt_tokens" json:"prompt_tokens"`
+	CompletionTokens int                `bson:"completion_tokens" json:"completion_tokens"`
+	TotalTokens      int                `bson:"total_tokens" json:"total_tokens"`
+	Cost             float64            `bson:"cost" json:"cost"` // 按 AI_PRICES 配置的单价计算
+	CreatedAt        time.Time          `bson:"created_at" json:"created_at"`
+}
+
+// AIUsageSummary 按天和模型汇总的用量
+type AIUsageSummary struct {
+	Day              string  `bson:"day" json:"day,omitempty"`
+	Model            string  `bson:"model" json:"model,omitempty"`
+	Calls            int     `bson:"calls" json:"calls"`
+	CachedCalls      int     `bson:"cached_calls" json:"cached_calls"`
+	PromptTokens     int     `bson:"prompt_tokens" json:"prompt_tokens"`
+	CompletionTokens int     `bson:"completion_tokens" json:"completion_tokens"`
+	TotalTokens      int     `bson:"total_tokens" json:"total_tokens"`
+	Cost             float64 `bson:"cost" json:"cost"`
+}
+
+func GetAIUsageCollection() *mongo.Collection {
+	return database.DB.Collection(aiUsageCollectionName)
+}
+
+func ensureAIUsageIndexes(ctx context.Context) error {
+	_, err := GetAIUsageCollection().Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys:    bson.D{{Key: "created_at", Value: 1}, {Key: "model", Value: 1}},
+		Options: options.Index().SetName("idx_created_at_model"),
+	})
+	return err
+}
+
+// RecordAIUsage 保存一次调用的用量
+func RecordAIUsage(usage *AIUsage) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	if usage.CreatedAt.IsZero() {
+		usage.CreatedAt = time.Now()
+	}
+	usage.ID = primitive.NilObjectID
+	result, err := GetAIUsageCollection().InsertOne(ctx, usage)
+	if err != nil {
+		return err
+	}
+	usage.ID = result.InsertedID.(primitive.ObjectID)
+	return nil
+}
+
+// AIUsageReport 统计 [from, to) 内的调用，按天（location 时区）和模型汇总，operation 不为空时只统计该操作
+func AIUsageReport(from, to time.Time, location *time.Location, operation string) ([]AIUsageSummary, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	match := bson.M{"created_at": bson.M{"$gte": from, "$lt": to}}
+	if operation != "" {
+		match["operation"] = operation
+	}
+	pipeline := mongo.Pipeline{
+		{{Key: "$match", Value: match}},
+		{{Key: "$group", Value: bson.M{
+			"_id": bson.M{
+				"day": bson.M{"$dateToString": bson.M{
+					"format":   "%Y-%m-%d",
+					"date":     "$created_at",
+					"timezone": location.String(),
+				}},
+				"model": "$model",
+			},
+			"calls":             bson.M{"$sum": 1},
+			"cached_calls":      bson.M{"$sum": bson.M{"$cond": bson.A{"$cached", 1, 0}}},
+			"prompt_tokens":     bson.M{"$sum": "$prompt_tokens"},
+			"completion_tokens": bson.M{"$sum": "$completion_tokens"},
+			"total_tokens":      bson.M{"$sum": "$total_tokens"},
+			"cost":              bson.M{"$sum": "$cost"},
+		}}},
+		{{Key: "$project", Value: bson.M{
+			"_id":               0,
+			"day":               "$_id.day",
+			"model":             "$_id.model",
+			"calls":             1,
+			"cached_calls":      1,
+			"prompt_tokens":     1,
+			"completion_tokens": 1,
+			"total_tokens":      1,
+			"cost":              1,
+		}}},
+		{{Key: "$sort", Value: bson.D{{Key: "day", Value: 1}, {Key: "model", Value: 1}}}},
+	}
+
+	cursor, err := GetAIUsageCollection().Aggregate(ctx, pipeline)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	summaries := []AIUsageSummary{}
+	if err := cursor.All(ctx, &summaries); err != nil {
+		return nil, err
+	}
+	return summaries, nil
+}
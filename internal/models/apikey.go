@@ -0,0 +1,188 @@
+package models
+
+import (
+	"context"
+	"qinniu/internal/pkg/database"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const apiKeyCollectionName = "api_keys"
+
+// 角色，权限依次递增
+const (
+	RoleViewer    = "viewer"    // 查看和导出，看不到邮箱和 AI 评估
+	RoleRecruiter = "recruiter" // 可以查看敏感字段、关注开发者
+	RoleOperator  = "operator"  // 可以爬取、编辑开发者和管理 webhook
+	RoleAdmin     = "admin"     // 可以删除开发者、管理密钥
+)
+
+// Roles 全部角色
+var Roles = []string{RoleViewer, RoleRecruiter, RoleOperator, RoleAdmin}
+
+// IsValidRole 判断角色是否存在
+func IsValidRole(role string) bool {
+	for _, r := range Roles {
+		if r == role {
+			return true
+		}
+	}
+	return false
+}
+
+// APIKey 访问 API 的密钥，数据库中只保存哈希值
+type APIKey struct {
+	ID         primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	Name       string             `bson:"name" json:"name"`
+	Prefix     string             `bson:"prefix" json:"prefix"` // 密钥前几位，用于在列表中辨认
+	KeyHash    string             `bson:"key_hash" json:"-"`
+	Role       string             `bson:"role" json:"role"`
+	CreatedBy  string             `bson:"created_by,omitempty" json:"created_by,omitempty"`
+	CreatedAt  time.Time          `bson:"created_at" json:"created_at"`
+	ExpiresAt  *time.Time         `bson:"expires_at,omitempty" json:"expires_at,omitempty"`
+	LastUsedAt *time.Time         `bson:"last_used_at,omitempty" json:"last_used_at,omitempty"`
+	RevokedAt  *time.Time         `bson:"revoked_at,omitempty" json:"revoked_at,omitempty"`
+}
+
+// Active 密钥未吊销且未过期
+func (k *APIKey) Active(now time.Time) bool {
+	if k.RevokedAt != nil {
+		return false
+	}
+	return k.ExpiresAt == nil || now.Before(*k.ExpiresAt)
+}
+
+func GetAPIKeyCollection() *mongo.Collection {
+	return database.DB.Collection(apiKeyCollectionName)
+}
+
+// ensureAPIKeyIndexes 按哈希值查找密钥，并为旧密钥补充角色
+func ensureAPIKeyIndexes(ctx context.Context) error {
+	_, err := GetAPIKeyCollection().Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys:    bson.D{{Key: "key_hash", Value: 1}},
+		Options: options.Index().SetName("uniq_key_hash").SetUnique(true),
+	})
+	if err != nil {
+		return err
+	}
+	return migrateAPIKeyRoles(ctx)
+}
+
+// migrateAPIKeyRoles 为引入角色之前创建的密钥补充角色
+// 原管理员密钥成为 admin，其他密钥原本可以调用所有写接口，成为 operator
+func migrateAPIKeyRoles(ctx context.Context) error {
+	collection := GetAPIKeyCollection()
+	if _, err := collection.UpdateMany(ctx,
+		bson.M{"role": bson.M{"$exists": false}, "admin": true},
+		bson.M{"$set": bson.M{"role": RoleAdmin}, "$unset": bson.M{"admin": ""}}); err != nil {
+		return err
+	}
+	_, err := collection.UpdateMany(ctx,
+		bson.M{"role": bson.M{"$exists": false}},
+		bson.M{"$set": bson.M{"role": RoleOperator}, "$unset": bson.M{"admin": ""}})
+	return err
+}
+
+// Create 保存密钥
+func (k *APIKey) Create() error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	k.ID = primitive.NilObjectID
+	k.CreatedAt = time.Now()
+	result, err := GetAPIKeyCollection().InsertOne(ctx, k)
+	if err != nil {
+		return err
+	}
+	k.ID = result.InsertedID.(primitive.ObjectID)
+	return nil
+}
+
+// Revoke 吊销密钥，吊销后的密钥保留在列表中
+func (k *APIKey) Revoke() error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	now := time.Now()
+	_, err := GetAPIKeyCollection().UpdateOne(ctx,
+		bson.M{"_id": k.ID, "revoked_at": bson.M{"$exists": false}},
+		bson.M{"$set": bson.M{"revoked_at": now}})
+	if err != nil {
+		return err
+	}
+	if k.RevokedAt == nil {
+		k.RevokedAt = &now
+	}
+	return nil
+}
+
+// TouchAPIKey 记录密钥的最近使用时间
+func TouchAPIKey(id primitive.ObjectID, at time.Time) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	_, err := GetAPIKeyCollection().UpdateOne(ctx, bson.M{"_id": id}, bson.M{"$set": bson.M{"last_used_at": at}})
+	return err
+}
+
+// FindAPIKeyByHash 通过密钥哈希查找
+func FindAPIKeyByHash(hash string) (*APIKey, error) {
+	return findAPIKey(bson.M{"key_hash": hash})
+}
+
+// FindAPIKeyByID 通过ID查找密钥
+func FindAPIKeyByID(id string) (*APIKey, error) {
+	objectID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return nil, err
+	}
+	return findAPIKey(bson.M{"_id": objectID})
+}
+
+func findAPIKey(filter bson.M) (*APIKey, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	var key APIKey
+	if err := GetAPIKeyCollection().FindOne(ctx, filter).Decode(&key); err != nil {
+		return nil, err
+	}
+	return &key, nil
+}
+
+// ListAPIKeys 列出所有密钥，最新创建的在前
+func ListAPIKeys() ([]*APIKey, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	cursor, err := GetAPIKeyCollection().Find(ctx, bson.M{}, options.Find().SetSort(bson.M{"created_at": -1}))
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var keys []*APIKey
+	if err := cursor.All(ctx, &keys); err != nil {
+		return nil, err
+	}
+	return keys, nil
+}
+
+// CountActiveAdminKeys 统计未吊销的管理员密钥数量
+func CountActiveAdminKeys() (int64, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	return GetAPIKeyCollection().CountDocuments(ctx, bson.M{
+		"role":       RoleAdmin,
+		"revoked_at": bson.M{"$exists": false},
+		"$or": []bson.M{
+			{"expires_at": bson.M{"$exists": false}},
+			{"expires_at": bson.M{"$gt": time.Now()}},
+		},
+	})
+}
@@ -16,31 +16,41 @@ import (
 )
 
 type Developer struct {
-	ID               primitive.ObjectID `bson:"_id,omitempty" json:"id"`
-	Username         string             `bson:"username" json:"username"`
-	Name             string             `bson:"name" json:"name"`
-	Email            string             `bson:"email" json:"email"`
-	Location         string             `bson:"location" json:"location"`
-	Nation           string             `bson:"nation" json:"nation"`
-	NationConfidence float64            `bson:"nation_confidence" json:"nation_confidence"`
-	TalentRank       float64            `bson:"talent_rank" json:"talent_rank"`
-	Confidence       float64            `bson:"confidence" json:"confidence"`
-	Skills           []string           `bson:"skills" json:"skills"`
-	Repositories     []string           `bson:"repositories" json:"repositories"`
-	CreatedAt        time.Time          `bson:"created_at" json:"created_at"`
-	UpdatedAt        time.Time          `bson:"updated_at" json:"updated_at"`
-	LastActive       time.Time          `bson:"last_active" json:"last_active"`
-	CommitCount      int                `bson:"commit_count" json:"commit_count"`
-	StarCount        int                `bson:"star_count" json:"star_count"`
-	ForkCount        int                `bson:"fork_count" json:"fork_count"` // 新增
-	LastUpdated      time.Time          `bson:"last_updated" json:"last_updated"`
-	DataValidation   ValidationResult   `bson:"data_validation" json:"data_validation"`
-	UpdateFrequency  time.Duration      `bson:"update_frequency" json:"update_frequency"`
-	Avatar           string             `bson:"avatar,omitempty" json:"avatar,omitempty"`
-	ProfileURL       string             `bson:"profile_url,omitempty" json:"profile_url,omitempty"`
-	RepositoryURLs   map[string]string  `bson:"repository_urls,omitempty" json:"repository_urls,omitempty"`
-	RepoStars        map[string]int     `bson:"repo_stars,omitempty" json:"repo_stars,omitempty"`
-	TechEvaluation   TechEvaluation     `bson:"tech_evaluation,omitempty" json:"tech_evaluation,omitempty"`
+	ID                primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	GitHubID          int64              `bson:"github_id,omitempty" json:"github_id,omitempty"` // 不可变的 GitHub 用户 ID，作为身份标识
+	Username          string             `bson:"username" json:"username"`
+	PreviousUsernames []string           `bson:"previous_usernames,omitempty" json:"previous_usernames,omitempty"` // 改名前使用过的用户名
+	ManualFields      []string           `bson:"manual_fields,omitempty" json:"manual_fields,omitempty"`           // 手工维护、爬虫刷新时保留的字段
+	Name              string             `bson:"name" json:"name"`
+	Email             string             `bson:"email" json:"email"`
+	Location          string             `bson:"location" json:"location"`
+	Bio               string             `bson:"bio,omitempty" json:"bio,omitempty"`
+	Nation            string             `bson:"nation" json:"nation"`
+	NationConfidence  float64            `bson:"nation_confidence" json:"nation_confidence"`
+	TalentRank        float64            `bson:"talent_rank" json:"talent_rank"`
+	Confidence        float64            `bson:"confidence" json:"confidence"`
+	Skills            []string           `bson:"skills" json:"skills"`
+	Repositories      []string           `bson:"repositories" json:"repositories"`
+	CreatedAt         time.Time          `bson:"created_at" json:"created_at"`
+	UpdatedAt         time.Time          `bson:"updated_at" json:"updated_at"`
+	LastActive        time.Time          `bson:"last_active" json:"last_active"`
+	CommitCount       int                `bson:"commit_count" json:"commit_count"`
+	StarCount         int                `bson:"star_count" json:"star_count"`
+	ForkCount         int                `bson:"fork_count" json:"fork_count"` // 新增
+	LastUpdated       time.Time          `bson:"last_updated" json:"last_updated"`
+	DataValidation    ValidationResult   `bson:"data_validation" json:"data_validation"`
+	UpdateFrequency   time.Duration      `bson:"update_frequency" json:"update_frequency"`
+	Watched           bool               `bson:"watched" json:"watched"` // 关注的开发者优先刷新
+	Avatar            string             `bson:"avatar,omitempty" json:"avatar,omitempty"`
+	ProfileURL        string             `bson:"profile_url,omitempty" json:"profile_url,omitempty"`
+	RepositoryURLs    map[string]string  `bson:"repository_urls,omitempty" json:"repository_urls,omitempty"`
+	RepoStars         map[string]int     `bson:"repo_stars,omitempty" json:"repo_stars,omitempty"`
+	RepoSummaries     []RepoSummary      `bson:"repo_summaries,omitempty" json:"-"` // 仓库描述和主题，用于全文搜索
+	TechEvaluation    TechEvaluation     `bson:"tech_evaluation,omitempty" json:"tech_evaluation,omitempty"`
+	NationConsensus   *Consensus         `bson:"nation_consensus,omitempty" json:"nation_consensus,omitempty"` // 多个模型推断国家时的一致程度
+	StarAnalysis      *StarAnalysis      `bson:"star_analysis,omitempty" json:"star_analysis,omitempty"`
+	Accounts          []PlatformAccount  `bson:"accounts,omitempty" json:"accounts,omitempty"`               // 关联的各平台账号
+	RefreshFailure    *RefreshFailure    `bson:"refresh_failure,omitempty" json:"refresh_failure,omitempty"` // 后台刷新连续失败的记录，刷新成功后清除
 	// 添加其他必要的字段
 }
 
@@ -53,13 +63,55 @@ type ValidationResult struct {
 }
 
 type TechEvaluation struct {
-	BlogURL         string            `bson:"blog_url,omitempty" json:"blog_url,omitempty"`
-	PersonalSiteURL string            `bson:"personal_site_url,omitempty" json:"personal_site_url,omitempty"`
-	Biography       string            `bson:"biography,omitempty" json:"biography,omitempty"`
-	Specialties     []string          `bson:"specialties,omitempty" json:"specialties,omitempty"`
-	Experience      map[string]string `bson:"experience,omitempty" json:"experience,omitempty"`
-	AIEvaluation    string            `bson:"ai_evaluation,omitempty" json:"ai_evaluation,omitempty"`
-	LastEvaluated   time.Time         `bson:"last_evaluated,omitempty" json:"last_evaluated,omitempty"`
+	BlogURL           string             `bson:"blog_url,omitempty" json:"blog_url,omitempty"`
+	PersonalSiteURL   string             `bson:"personal_site_url,omitempty" json:"personal_site_url,omitempty"`
+	Biography         string             `bson:"biography,omitempty" json:"biography,omitempty"`
+	Specialties       []string           `bson:"specialties,omitempty" json:"specialties,omitempty"`
+	Experience        map[string]string  `bson:"experience,omitempty" json:"experience,omitempty"`
+	AIEvaluation      string             `bson:"ai_evaluation,omitempty" json:"ai_evaluation,omitempty"`
+	Reasons           []string           `bson:"reasons,omitempty" json:"reasons,omitempty"`                       // AI 给出的评价依据
+	Summary           string             `bson:"summary,omitempty" json:"summary,omitempty"`                       // 面向招聘人员的概括
+	PromptVersion     string             `bson:"prompt_version,omitempty" json:"prompt_version,omitempty"`         // 评估使用的提示词模板版本
+	Evidence          []EvidenceRef      `bson:"evidence,omitempty" json:"evidence,omitempty"`                     // 评估依据的工作证据
+	InputHash         string             `bson:"input_hash,omitempty" json:"-"`                                    // 评估输入（提示词和证据）的哈希，相同时复用缓存的结果
+	ProfileHash       string             `bson:"profile_hash,omitempty" json:"-"`                                  // 评估时资料的指纹，爬虫据此判断是否需要重新评估
+	Consensus         *Consensus         `bson:"consensus,omitempty" json:"consensus,omitempty"`                   // 多个评估配置的一致程度，只配置一个时为空
+	InjectionWarnings []InjectionWarning `bson:"injection_warnings,omitempty" json:"injection_warnings,omitempty"` // 资料中疑似提示词注入的内容
+	Status            string             `bson:"status,omitempty" json:"status,omitempty"`                         // 审核状态，见 EvaluationPending 等
+	Review            *EvaluationReview  `bson:"review,omitempty" json:"review,omitempty"`                         // 最近一次人工审核
+	LastEvaluated     time.Time          `bson:"last_evaluated,omitempty" json:"last_evaluated,omitempty"`
+}
+
+// Consensus 多个评估配置（不同模型、温度或提示词）的一致程度，一致性低的需要人工复核
+type Consensus struct {
+	Agreement   float64        `bson:"agreement" json:"agreement"` // 0-1
+	NeedsReview bool           `bson:"needs_review" json:"needs_review"`
+	Runs        []ConsensusRun `bson:"runs" json:"runs"`
+}
+
+// InjectionWarning 开发者资料中疑似试图操纵评估的内容
+type InjectionWarning struct {
+	Field   string `bson:"field" json:"field"`     // 如 bio、repositories[2].description、evidence[0].content
+	Pattern string `bson:"pattern" json:"pattern"` // 如 ignore_instructions、score_manipulation
+	Excerpt string `bson:"excerpt" json:"excerpt"` // 匹配处附近的原文
+}
+
+// ConsensusRun 单个评估配置的结论
+type ConsensusRun struct {
+	Evaluator   string   `bson:"evaluator" json:"evaluator"` // 如 openai:deepseek-chat@0.7#evaluation
+	Specialties []string `bson:"specialties,omitempty" json:"specialties,omitempty"`
+	Nation      string   `bson:"nation,omitempty" json:"nation,omitempty"`
+	Confidence  float64  `bson:"confidence,omitempty" json:"confidence,omitempty"`
+	Error       string   `bson:"error,omitempty" json:"error,omitempty"`
+}
+
+// EvidenceRef 评估使用的一条工作证据，只保存出处不保存内容
+type EvidenceRef struct {
+	Kind   string `bson:"kind" json:"kind"` // readme、code、commits 或 pull_request
+	Repo   string `bson:"repo" json:"repo"`
+	Title  string `bson:"title,omitempty" json:"title,omitempty"`
+	URL    string `bson:"url,omitempty" json:"url,omitempty"`
+	Tokens int    `bson:"tokens" json:"tokens"`
 }
 
 const collectionName = "developers"
@@ -119,10 +171,12 @@ func (d *Developer) Create() error {
 		log.Printf("Debug - Verified Avatar URL after insert: %s", inserted.Avatar)
 	}
 
+	notifyDeveloperCreated(d)
 	return nil
 }
 
-// Update 更新开发者信息
+// Update 更新开发者信息，不写入 tech_evaluation，评估结果只由评估 worker 和审核接口按字段更新，
+// 避免用爬取开始时读到的旧值覆盖期间的审核和新评估；更新后 d.TechEvaluation 为数据库中的当前值
 func (d *Developer) Update() error {
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
@@ -140,35 +194,59 @@ func (d *Developer) Update() error {
 	// 构建更新文档，排除 _id 字段
 	update := bson.M{
 		"$set": bson.M{
-			"username":          d.Username,
-			"name":              d.Name,
-			"email":             d.Email,
-			"location":          d.Location,
-			"nation":            d.Nation,
-			"nation_confidence": d.NationConfidence,
-			"talent_rank":       d.TalentRank,
-			"confidence":        d.Confidence,
-			"skills":            d.Skills,
-			"repositories":      d.Repositories,
-			"updated_at":        d.UpdatedAt,
-			"last_active":       d.LastActive,
-			"commit_count":      d.CommitCount,
-			"star_count":        d.StarCount,
-			"fork_count":        d.ForkCount, // 新增
-			"last_updated":      d.LastUpdated,
-			"data_validation":   d.DataValidation,
-			"update_frequency":  d.UpdateFrequency,
-			"avatar":            d.Avatar, // 确保包含 Avatar 字段
-			"profile_url":       d.ProfileURL,
-			"repository_urls":   d.RepositoryURLs,
-			"repo_stars":        d.RepoStars,
-			"tech_evaluation":   d.TechEvaluation,
+			"github_id":          d.GitHubID,
+			"username":           d.Username,
+			"previous_usernames": d.PreviousUsernames,
+			"manual_fields":      d.ManualFields,
+			"name":               d.Name,
+			"email":              d.Email,
+			"location":           d.Location,
+			"bio":                d.Bio,
+			"nation":             d.Nation,
+			"nation_confidence":  d.NationConfidence,
+			"talent_rank":        d.TalentRank,
+			"confidence":         d.Confidence,
+			"skills":             d.Skills,
+			"repositories":       d.Repositories,
+			"updated_at":         d.UpdatedAt,
+			"last_active":        d.LastActive,
+			"commit_count":       d.CommitCount,
+			"star_count":         d.StarCount,
+			"fork_count":         d.ForkCount, // 新增
+			"last_updated":       d.LastUpdated,
+			"data_validation":    d.DataValidation,
+			"update_frequency":   d.UpdateFrequency,
+			"watched":            d.Watched,
+			"avatar":             d.Avatar, // 确保包含 Avatar 字段
+			"profile_url":        d.ProfileURL,
+			"repository_urls":    d.RepositoryURLs,
+			"repo_stars":         d.RepoStars,
+			"repo_summaries":     d.RepoSummaries,
+			"nation_consensus":   d.NationConsensus,
+			"star_analysis":      d.StarAnalysis,
+			"accounts":           d.Accounts,
 			// 不要包含 "_id" 字段
 		},
 	}
 
-	_, err := GetCollection().UpdateOne(ctx, filter, update)
-	return err
+	// 取回更新前的 TalentRank，用于通知 TalentRank 变化，tech_evaluation 未被修改即为当前值
+	var previous struct {
+		TalentRank     float64        `bson:"talent_rank"`
+		TechEvaluation TechEvaluation `bson:"tech_evaluation"`
+	}
+	err := GetCollection().FindOneAndUpdate(ctx, filter, update,
+		options.FindOneAndUpdate().SetProjection(bson.M{"talent_rank": 1, "tech_evaluation": 1}),
+	).Decode(&previous)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil
+		}
+		return err
+	}
+	d.TechEvaluation = previous.TechEvaluation
+
+	notifyDeveloperUpdated(previous.TalentRank, d)
+	return nil
 }
 
 // Delete 删除开发者
@@ -432,7 +510,8 @@ func AggregateSearch(pipeline []bson.M) ([]*Developer, error) {
 	return developers, nil
 }
 
-// DeleteByUsername 删除指定用户名的所有记录
+// DeleteByUsername 删除指定用户名的记录
+// 有 github_id 唯一索引后同一身份只会有一条记录，旧的无 ID 记录可能仍有多条
 func DeleteByUsername(username string) error {
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
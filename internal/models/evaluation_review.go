@@ -0,0 +1,244 @@
+package models
+
+import (
+	"context"
+	"errors"
+	"log"
+	"qinniu/internal/pkg/database"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const evaluationReviewCollectionName = "evaluation_reviews"
+
+// AI 评估的审核状态
+const (
+	EvaluationPending      = "pending"       // 已提交评估任务，还没有结果
+	EvaluationAutoApproved = "auto_approved" // 各评估配置意见一致且没有注入警告，自动通过
+	EvaluationNeedsReview  = "needs_review"  // 等待人工审核
+	EvaluationApproved     = "approved"      // 人工审核通过（可能修改过内容）
+	EvaluationRejected     = "rejected"      // 人工审核驳回，不对外展示
+)
+
+// 审核操作
+const (
+	ReviewApprove = "approve"
+	ReviewEdit    = "edit"
+	ReviewReject  = "reject"
+)
+
+// EvaluationStatuses 全部审核状态
+var EvaluationStatuses = []string{EvaluationPending, EvaluationAutoApproved, EvaluationNeedsReview, EvaluationApproved, EvaluationRejected}
+
+// ErrEvaluationChanged 审核期间评估被重新生成或已被其他人审核
+var ErrEvaluationChanged = errors.New("评估已被重新生成或已被其他人审核，请刷新后重试")
+
+// EvaluationReview 一次人工审核；最近一次同时保存在 tech_evaluation.review 中
+type EvaluationReview struct {
+	ID            primitive.ObjectID     `bson:"_id,omitempty" json:"id,omitempty"`
+	DeveloperID   primitive.ObjectID     `bson:"developer_id,omitempty" json:"developer_id,omitempty"`
+	Username      string                 `bson:"username,omitempty" json:"username,omitempty"`
+	Action        string                 `bson:"action" json:"action"` // approve、edit 或 reject
+	FromStatus    string                 `bson:"from_status,omitempty" json:"from_status,omitempty"`
+	Status        string                 `bson:"status" json:"status"`
+	Reviewer      string                 `bson:"reviewer" json:"reviewer"` // 审核人使用的 API 密钥或令牌名称
+	Comment       string                 `bson:"comment,omitempty" json:"comment,omitempty"`
+	Previous      map[string]interface{} `bson:"previous,omitempty" json:"previous,omitempty"` // edit 时被修改字段的原值
+	PromptVersion string                 `bson:"prompt_version,omitempty" json:"prompt_version,omitempty"`
+	CreatedAt     time.Time              `bson:"created_at" json:"created_at"`
+}
+
+// IsApproved 评估是否已通过审核（人工或自动），只有通过的评估对 viewer 展示
+func (e *TechEvaluation) IsApproved() bool {
+	return e.Status == EvaluationApproved || e.Status == EvaluationAutoApproved
+}
+
+// IsValidEvaluationStatus 判断审核状态是否有效
+func IsValidEvaluationStatus(status string) bool {
+	for _, s := range EvaluationStatuses {
+		if s == status {
+			return true
+		}
+	}
+	return false
+}
+
+func GetEvaluationReviewCollection() *mongo.Collection {
+	return database.DB.Collection(evaluationReviewCollectionName)
+}
+
+func ensureEvaluationReviewIndexes(ctx context.Context) error {
+	_, err := GetCollection().Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys:    bson.D{{Key: "tech_evaluation.status", Value: 1}, {Key: "tech_evaluation.last_evaluated", Value: 1}},
+		Options: options.Index().SetName("idx_evaluation_status"),
+	})
+	if err != nil {
+		return err
+	}
+	_, err = GetEvaluationReviewCollection().Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys:    bson.D{{Key: "developer_id", Value: 1}, {Key: "created_at", Value: -1}},
+		Options: options.Index().SetName("idx_developer_created_at"),
+	})
+	return err
+}
+
+// FindEvaluationsByStatus 审核队列：按状态查询开发者，需要审核的评估中一致程度低的在前，其余按评估时间先后
+func FindEvaluationsByStatus(status string, limit int64) ([]*Developer, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	opts := options.Find().
+		SetLimit(limit).
+		SetSort(bson.D{
+			{Key: "tech_evaluation.consensus.agreement", Value: 1},
+			{Key: "tech_evaluation.last_evaluated", Value: 1},
+		})
+
+	cursor, err := GetCollection().Find(ctx, bson.M{"tech_evaluation.status": status}, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	developers := []*Developer{}
+	if err = cursor.All(ctx, &developers); err != nil {
+		return nil, err
+	}
+	return developers, nil
+}
+
+// ApplyEvaluationReview 保存审核结果：更新 tech_evaluation 的状态、最近一次审核和 set 中修改的字段，并记录审核历史
+// 只有评估在读取后没有被重新生成或审核过时才会更新，否则返回 ErrEvaluationChanged
+func ApplyEvaluationReview(developer *Developer, review *EvaluationReview, set bson.M) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	evaluation := &developer.TechEvaluation
+	review.ID = primitive.NilObjectID
+	review.DeveloperID = developer.ID
+	review.Username = developer.Username
+	review.FromStatus = evaluation.Status
+	review.PromptVersion = evaluation.PromptVersion
+	review.CreatedAt = time.Now()
+
+	latest := *review
+	latest.DeveloperID = primitive.NilObjectID
+	latest.Username = ""
+	latest.Previous = nil
+
+	update := bson.M{
+		"tech_evaluation.status": review.Status,
+		"tech_evaluation.review": latest,
+		"updated_at":             review.CreatedAt,
+	}
+	for field, value := range set {
+		update["tech_evaluation."+field] = value
+	}
+
+	filter := bson.M{
+		"_id":                        developer.ID,
+		"tech_evaluation.input_hash": valueOrMissing(evaluation.InputHash),
+		"tech_evaluation.status":     valueOrMissing(evaluation.Status),
+	}
+	result, err := GetCollection().UpdateOne(ctx, filter, bson.M{"$set": update})
+	if err != nil {
+		return err
+	}
+	if result.MatchedCount == 0 {
+		return ErrEvaluationChanged
+	}
+
+	evaluation.Status = review.Status
+	evaluation.Review = &latest
+	if err := syncSearchEvaluation(ctx, bson.M{"_id": developer.ID}); err != nil {
+		log.Printf("Warning: 更新 %s 的搜索内容失败: %v", developer.Username, err)
+	}
+
+	inserted, err := GetEvaluationReviewCollection().InsertOne(ctx, review)
+	if err != nil {
+		return err
+	}
+	review.ID = inserted.InsertedID.(primitive.ObjectID)
+	return nil
+}
+
+// valueOrMissing 字段为空字符串时同时匹配不存在的字段（旧数据）
+func valueOrMissing(v string) interface{} {
+	if v == "" {
+		return bson.M{"$in": bson.A{nil, ""}}
+	}
+	return v
+}
+
+// ListEvaluationReviews 开发者的审核历史，最新的在前
+func ListEvaluationReviews(developerID primitive.ObjectID, limit int64) ([]*EvaluationReview, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	opts := options.Find().
+		SetLimit(limit).
+		SetSort(bson.D{{Key: "created_at", Value: -1}})
+	cursor, err := GetEvaluationReviewCollection().Find(ctx, bson.M{"developer_id": developerID}, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	reviews := []*EvaluationReview{}
+	if err = cursor.All(ctx, &reviews); err != nil {
+		return nil, err
+	}
+	return reviews, nil
+}
+
+// MarkEvaluationPending 提交评估任务后，还没有评估结果的开发者标记为 pending
+func MarkEvaluationPending(username string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	_, err := GetCollection().UpdateOne(ctx, bson.M{
+		"username":                      username,
+		"tech_evaluation.ai_evaluation": bson.M{"$in": bson.A{nil, ""}},
+	}, bson.M{"$set": bson.M{"tech_evaluation.status": EvaluationPending}})
+	return err
+}
+
+// BackfillEvaluationStatus 为没有审核状态的旧评估补上状态：被标记需要复核或有注入警告的进入审核队列，其余视为自动通过
+func BackfillEvaluationStatus() (autoApproved, needsReview int64, err error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 60*time.Second)
+	defer cancel()
+
+	legacy := bson.M{
+		"tech_evaluation.ai_evaluation": bson.M{"$nin": bson.A{nil, ""}},
+		"tech_evaluation.status":        bson.M{"$in": bson.A{nil, ""}},
+	}
+	flagged := bson.A{
+		bson.M{"tech_evaluation.consensus.needs_review": true},
+		bson.M{"tech_evaluation.injection_warnings.0": bson.M{"$exists": true}},
+	}
+
+	result, err := GetCollection().UpdateMany(ctx,
+		bson.M{"$and": bson.A{legacy, bson.M{"$or": flagged}}},
+		bson.M{"$set": bson.M{"tech_evaluation.status": EvaluationNeedsReview}})
+	if err != nil {
+		return 0, 0, err
+	}
+	needsReview = result.ModifiedCount
+
+	result, err = GetCollection().UpdateMany(ctx, legacy,
+		bson.M{"$set": bson.M{"tech_evaluation.status": EvaluationAutoApproved}})
+	if err != nil {
+		return 0, needsReview, err
+	}
+	autoApproved = result.ModifiedCount
+
+	// 全文索引只包含通过审核的评估，为已有评估生成 search_evaluation
+	if err := syncSearchEvaluation(ctx, bson.M{"tech_evaluation.ai_evaluation": bson.M{"$nin": bson.A{nil, ""}}}); err != nil {
+		return autoApproved, needsReview, err
+	}
+	return autoApproved, needsReview, nil
+}
@@ -0,0 +1,113 @@
+package models
+
+import (
+	"context"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// FacetBucket 分面统计中的一项，如 {"value": "Go", "count": 1204}
+type FacetBucket struct {
+	Value string `bson:"_id" json:"value"`
+	Count int64  `bson:"count" json:"count"`
+}
+
+// SearchPage 一页搜索结果，以及整个结果集的总数和分面统计
+type SearchPage struct {
+	Developers []*Developer
+	Total      int64
+	Facets     map[string][]FacetBucket
+}
+
+// AggregateFacetedSearch 在同一个 $facet 聚合中查询一页结果、总数和分面统计
+// results 为排序、分页和投影阶段，facets 为各分面的子管道，输出格式为 {_id, count}
+func AggregateFacetedSearch(filter bson.M, results []bson.M, facets map[string][]bson.M) (*SearchPage, error) {
+	stages := bson.M{
+		"developers": results,
+		"total":      []bson.M{{"$count": "count"}},
+	}
+	for name, pipeline := range facets {
+		stages["facet_"+name] = pipeline
+	}
+
+	raw, err := aggregateFacet(filter, stages)
+	if err != nil {
+		return nil, err
+	}
+
+	page := &SearchPage{Developers: make([]*Developer, 0)}
+	if value, err := raw.LookupErr("developers"); err == nil {
+		if err := value.Unmarshal(&page.Developers); err != nil {
+			return nil, err
+		}
+	}
+	if value, err := raw.LookupErr("total"); err == nil {
+		var counts []struct {
+			Count int64 `bson:"count"`
+		}
+		if err := value.Unmarshal(&counts); err != nil {
+			return nil, err
+		}
+		if len(counts) > 0 {
+			page.Total = counts[0].Count
+		}
+	}
+	page.Facets, err = decodeFacets(raw, facets)
+	if err != nil {
+		return nil, err
+	}
+	return page, nil
+}
+
+// AggregateFacets 只计算分面统计，用于结果另行查询的场景（如全文搜索）
+func AggregateFacets(filter bson.M, facets map[string][]bson.M) (map[string][]FacetBucket, error) {
+	stages := bson.M{}
+	for name, pipeline := range facets {
+		stages["facet_"+name] = pipeline
+	}
+
+	raw, err := aggregateFacet(filter, stages)
+	if err != nil {
+		return nil, err
+	}
+	return decodeFacets(raw, facets)
+}
+
+func aggregateFacet(filter bson.M, stages bson.M) (bson.Raw, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	pipeline := []bson.M{
+		{"$match": filter},
+		{"$facet": stages},
+	}
+	cursor, err := GetCollection().Aggregate(ctx, pipeline)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	// $facet 总是输出一个文档
+	if !cursor.Next(ctx) {
+		if err := cursor.Err(); err != nil {
+			return nil, err
+		}
+		return bson.Raw{}, nil
+	}
+	return append(bson.Raw(nil), cursor.Current...), nil
+}
+
+func decodeFacets(raw bson.Raw, facets map[string][]bson.M) (map[string][]FacetBucket, error) {
+	result := make(map[string][]FacetBucket, len(facets))
+	for name := range facets {
+		buckets := make([]FacetBucket, 0)
+		if value, err := raw.LookupErr("facet_" + name); err == nil {
+			if err := value.Unmarshal(&buckets); err != nil {
+				return nil, err
+			}
+		}
+		result[name] = buckets
+	}
+	return result, nil
+}
@@ -0,0 +1,37 @@
+package models
+
+import "sync"
+
+// DeveloperObserver 接收开发者创建和更新的通知，用于 webhook 等外部通知
+type DeveloperObserver interface {
+	DeveloperCreated(developer *Developer)
+	DeveloperUpdated(previousTalentRank float64, developer *Developer)
+}
+
+var (
+	observersMu sync.RWMutex
+	observers   []DeveloperObserver
+)
+
+// RegisterDeveloperObserver 注册开发者变化的观察者
+func RegisterDeveloperObserver(observer DeveloperObserver) {
+	observersMu.Lock()
+	defer observersMu.Unlock()
+	observers = append(observers, observer)
+}
+
+func notifyDeveloperCreated(developer *Developer) {
+	observersMu.RLock()
+	defer observersMu.RUnlock()
+	for _, observer := range observers {
+		observer.DeveloperCreated(developer)
+	}
+}
+
+func notifyDeveloperUpdated(previousTalentRank float64, developer *Developer) {
+	observersMu.RLock()
+	defer observersMu.RUnlock()
+	for _, observer := range observers {
+		observer.DeveloperUpdated(previousTalentRank, developer)
+	}
+}
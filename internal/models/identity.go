@@ -0,0 +1,452 @@
+package models
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// MergeReport 重复记录合并的统计结果
+type MergeReport struct {
+	Groups  int      `json:"groups"`  // 存在重复的身份数量
+	Removed int      `json:"removed"` // 被合并删除的记录数量
+	Keys    []string `json:"keys"`    // 发生合并的身份标识
+}
+
+// 不属于手工字段的元数据字段，不能被标记或覆盖
+var nonManualFields = map[string]struct{}{
+	"_id":                {},
+	"id":                 {},
+	"manual_fields":      {},
+	"github_id":          {},
+	"username":           {},
+	"previous_usernames": {},
+	"created_at":         {},
+	"watched":            {},
+	"updated_at":         {},
+	"tech_evaluation":    {}, // 只能通过审核接口修改
+	"nation_consensus":   {},
+}
+
+// EnsureIndexes 创建开发者集合及其他集合所需的索引
+// github_id 唯一索引只约束已经回填了 GitHub ID 的记录，平台账号标识同样全局唯一
+// 各组索引分别创建，某组失败时其余索引照常创建，返回合并后的错误
+func EnsureIndexes() error {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	// 唯一索引在存在重复记录时会失败，需要先运行 cmd/migrate，不能因此跳过其他索引
+	uniqueIndexes := []mongo.IndexModel{
+		{
+			Keys: bson.D{{Key: "github_id", Value: 1}},
+			Options: options.Index().
+				SetName("uniq_github_id").
+				SetUnique(true).
+				SetPartialFilterExpression(bson.M{"github_id": bson.M{"$gt": 0}}),
+		},
+		{
+			Keys: bson.D{{Key: "accounts.key", Value: 1}},
+			Options: options.Index().
+				SetName("uniq_account_key").
+				SetUnique(true).
+				SetPartialFilterExpression(bson.M{"accounts.key": bson.M{"$exists": true}}),
+		},
+	}
+
+	var errs []error
+	if _, err := GetCollection().Indexes().CreateMany(ctx, uniqueIndexes); err != nil {
+		errs = append(errs, fmt.Errorf("创建唯一索引失败（如存在重复记录请先运行 cmd/migrate）: %v", err))
+	}
+	if _, err := GetCollection().Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys:    bson.D{{Key: "username", Value: 1}},
+		Options: options.Index().SetName("idx_username"),
+	}); err != nil {
+		errs = append(errs, fmt.Errorf("创建用户名索引失败: %v", err))
+	}
+	if err := ensureTextIndex(ctx); err != nil {
+		errs = append(errs, fmt.Errorf("创建全文索引失败: %v", err))
+	}
+	if err := ensureWebhookIndexes(ctx); err != nil {
+		errs = append(errs, err)
+	}
+	if err := ensureAIResultIndexes(ctx); err != nil {
+		errs = append(errs, err)
+	}
+	if err := ensureEvaluationReviewIndexes(ctx); err != nil {
+		errs = append(errs, fmt.Errorf("创建审核记录索引失败: %v", err))
+	}
+	if err := ensureAIUsageIndexes(ctx); err != nil {
+		errs = append(errs, err)
+	}
+	if err := ensureAPIKeyIndexes(ctx); err != nil {
+		errs = append(errs, err)
+	}
+	return errors.Join(errs...)
+}
+
+// FindByGitHubID 通过不可变的 GitHub 用户 ID 查找开发者
+func FindByGitHubID(githubID int64) (*Developer, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	var developer Developer
+	err := GetCollection().FindOne(ctx, bson.M{"github_id": githubID}).Decode(&developer)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	return &developer, nil
+}
+
+// githubCandidate 可能对应 GitHub 账号的记录：没有关联平台账号（旧数据、手工创建）或关联了 GitHub 账号
+// 以 GitLab、Gitee 账号为主的记录的用户名属于其他平台，不能按用户名对应到 GitHub
+var githubCandidate = bson.M{"$or": []bson.M{
+	{"accounts.0": bson.M{"$exists": false}},
+	{"accounts.platform": "github"},
+}}
+
+// FindUsernamesWithoutGitHubID 列出尚未回填 GitHub ID 的用户名
+func FindUsernamesWithoutGitHubID() ([]string, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	filter := bson.M{"$and": []bson.M{
+		{"$or": []bson.M{
+			{"github_id": bson.M{"$exists": false}},
+			{"github_id": bson.M{"$lte": 0}},
+		}},
+		githubCandidate,
+	}}
+	values, err := GetCollection().Distinct(ctx, "username", filter)
+	if err != nil {
+		return nil, err
+	}
+
+	usernames := make([]string, 0, len(values))
+	for _, v := range values {
+		if name, ok := v.(string); ok && name != "" {
+			usernames = append(usernames, name)
+		}
+	}
+	return usernames, nil
+}
+
+// SetGitHubIDForUsername 为指定用户名的所有旧记录回填 GitHub ID
+func SetGitHubIDForUsername(username string, githubID int64) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	filter := bson.M{
+		"username": username,
+		"$and": []bson.M{
+			{"$or": []bson.M{
+				{"github_id": bson.M{"$exists": false}},
+				{"github_id": bson.M{"$lte": 0}},
+			}},
+			githubCandidate,
+		},
+	}
+	_, err := GetCollection().UpdateMany(ctx, filter, bson.M{"$set": bson.M{"github_id": githubID}})
+	return err
+}
+
+// NamespacePlatformUsernames 以 GitLab、Gitee 账号为主的旧记录直接使用平台用户名，可能与 GitHub 开发者重名，
+// 改为"平台:用户名"，原用户名记入改名历史
+func NamespacePlatformUsernames() (int64, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 60*time.Second)
+	defer cancel()
+
+	filter := bson.M{
+		"$or": []bson.M{
+			{"github_id": bson.M{"$exists": false}},
+			{"github_id": bson.M{"$lte": 0}},
+		},
+		"accounts.0":        bson.M{"$exists": true},
+		"accounts.platform": bson.M{"$ne": "github"},
+		"username":          bson.M{"$not": primitive.Regex{Pattern: ":"}},
+	}
+	// $set 阶段中的表达式都基于更新前的文档
+	update := mongo.Pipeline{{{Key: "$set", Value: bson.D{
+		{Key: "previous_usernames", Value: bson.M{"$concatArrays": bson.A{
+			bson.M{"$ifNull": bson.A{"$previous_usernames", bson.A{}}},
+			bson.A{"$username"},
+		}}},
+		{Key: "username", Value: bson.M{"$concat": bson.A{
+			bson.M{"$arrayElemAt": bson.A{"$accounts.platform", 0}}, ":", "$username",
+		}}},
+	}}}}
+	result, err := GetCollection().UpdateMany(ctx, filter, update)
+	if err != nil {
+		return 0, err
+	}
+	return result.ModifiedCount, nil
+}
+
+// MergeDuplicateDevelopers 合并同一身份的重复记录
+// 身份优先使用 github_id，没有 ID 的记录按用户名（忽略大小写）归并；
+// 保留最新的爬取数据，同时保留旧记录中的手工字段
+func MergeDuplicateDevelopers(dryRun bool) (*MergeReport, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Minute)
+	defer cancel()
+
+	cursor, err := GetCollection().Find(ctx, bson.M{})
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	groups := make(map[string][]bson.M)
+	for cursor.Next(ctx) {
+		var doc bson.M
+		if err := cursor.Decode(&doc); err != nil {
+			return nil, err
+		}
+		key := identityKey(doc)
+		groups[key] = append(groups[key], doc)
+	}
+	if err := cursor.Err(); err != nil {
+		return nil, err
+	}
+
+	report := &MergeReport{Keys: make([]string, 0)}
+	for key, docs := range groups {
+		if len(docs) < 2 {
+			continue
+		}
+
+		merged, removed := mergeDeveloperDocs(docs)
+		report.Groups++
+		report.Removed += len(removed)
+		report.Keys = append(report.Keys, key)
+
+		log.Printf("合并重复记录 %s: 保留 %v, 删除 %d 条", key, merged["_id"], len(removed))
+		if dryRun {
+			continue
+		}
+
+		if _, err := GetCollection().ReplaceOne(ctx, bson.M{"_id": merged["_id"]}, merged); err != nil {
+			return report, fmt.Errorf("写入合并结果失败 %s: %v", key, err)
+		}
+		if _, err := GetCollection().DeleteMany(ctx, bson.M{"_id": bson.M{"$in": removed}}); err != nil {
+			return report, fmt.Errorf("删除重复记录失败 %s: %v", key, err)
+		}
+	}
+
+	sort.Strings(report.Keys)
+	return report, nil
+}
+
+// identityKey 计算记录的身份标识
+func identityKey(doc bson.M) string {
+	if id := toInt64(doc["github_id"]); id > 0 {
+		return fmt.Sprintf("github:%d", id)
+	}
+	username, _ := doc["username"].(string)
+	return "username:" + strings.ToLower(username)
+}
+
+// mergeDeveloperDocs 以最新的记录为基础合并同一身份的多条记录
+func mergeDeveloperDocs(docs []bson.M) (bson.M, []interface{}) {
+	sort.SliceStable(docs, func(i, j int) bool {
+		return docTime(docs[i], "last_updated").After(docTime(docs[j], "last_updated"))
+	})
+
+	base := docs[0]
+	manual := stringSet(base["manual_fields"])
+	previous := stringSet(base["previous_usernames"])
+	baseUsername, _ := base["username"].(string)
+	createdAt := docTime(base, "created_at")
+
+	removed := make([]interface{}, 0, len(docs)-1)
+	for _, doc := range docs[1:] {
+		removed = append(removed, doc["_id"])
+
+		// 旧记录上的手工字段，如果新记录没有手工修改过，则保留旧值
+		for field := range stringSet(doc["manual_fields"]) {
+			if _, ok := manual[field]; ok {
+				continue
+			}
+			if value, ok := doc[field]; ok {
+				base[field] = value
+				manual[field] = struct{}{}
+			}
+		}
+
+		// AI 评估代价较高，最新记录没有时沿用旧记录的结果
+		if !hasEvaluation(base) && hasEvaluation(doc) {
+			base["tech_evaluation"] = doc["tech_evaluation"]
+		}
+
+		if username, _ := doc["username"].(string); username != "" && username != baseUsername {
+			previous[username] = struct{}{}
+		}
+		for name := range stringSet(doc["previous_usernames"]) {
+			if name != baseUsername {
+				previous[name] = struct{}{}
+			}
+		}
+
+		if t := docTime(doc, "created_at"); !t.IsZero() && (createdAt.IsZero() || t.Before(createdAt)) {
+			createdAt = t
+		}
+		if toInt64(base["github_id"]) <= 0 && toInt64(doc["github_id"]) > 0 {
+			base["github_id"] = doc["github_id"]
+		}
+	}
+
+	if len(manual) > 0 {
+		base["manual_fields"] = setToSlice(manual)
+	}
+	if len(previous) > 0 {
+		base["previous_usernames"] = setToSlice(previous)
+	}
+	if !createdAt.IsZero() {
+		base["created_at"] = createdAt
+	}
+	base["updated_at"] = time.Now()
+
+	return base, removed
+}
+
+// PreserveManualFields 将已有记录中手工维护的字段覆盖到新爬取的数据上
+func (d *Developer) PreserveManualFields(existing *Developer) error {
+	if existing == nil || len(existing.ManualFields) == 0 {
+		return nil
+	}
+
+	src, err := toBsonM(existing)
+	if err != nil {
+		return err
+	}
+	dst, err := toBsonM(d)
+	if err != nil {
+		return err
+	}
+
+	for _, field := range existing.ManualFields {
+		if _, skip := nonManualFields[field]; skip {
+			continue
+		}
+		if value, ok := src[field]; ok {
+			dst[field] = value
+		}
+	}
+
+	data, err := bson.Marshal(dst)
+	if err != nil {
+		return err
+	}
+	if err := bson.Unmarshal(data, d); err != nil {
+		return err
+	}
+	d.ManualFields = existing.ManualFields
+	return nil
+}
+
+// MarkManualFields 记录通过 API 手工修改过的字段，爬虫刷新时不会覆盖这些字段
+func (d *Developer) MarkManualFields(fields []string) {
+	set := stringSet(d.ManualFields)
+	for _, field := range fields {
+		if _, skip := nonManualFields[field]; skip || field == "" {
+			continue
+		}
+		set[field] = struct{}{}
+	}
+	d.ManualFields = setToSlice(set)
+}
+
+// RecordRename 记录 GitHub 账号改名
+func (d *Developer) RecordRename(oldUsername string) {
+	if oldUsername == "" || oldUsername == d.Username {
+		return
+	}
+	for _, name := range d.PreviousUsernames {
+		if name == oldUsername {
+			return
+		}
+	}
+	d.PreviousUsernames = append(d.PreviousUsernames, oldUsername)
+}
+
+func toBsonM(v interface{}) (bson.M, error) {
+	data, err := bson.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	var m bson.M
+	if err := bson.Unmarshal(data, &m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func hasEvaluation(doc bson.M) bool {
+	eval, ok := doc["tech_evaluation"].(bson.M)
+	if !ok {
+		return false
+	}
+	text, _ := eval["ai_evaluation"].(string)
+	return text != ""
+}
+
+func docTime(doc bson.M, field string) time.Time {
+	switch v := doc[field].(type) {
+	case primitive.DateTime:
+		return v.Time()
+	case time.Time:
+		return v
+	}
+	return time.Time{}
+}
+
+func toInt64(v interface{}) int64 {
+	switch n := v.(type) {
+	case int32:
+		return int64(n)
+	case int64:
+		return n
+	case float64:
+		return int64(n)
+	case int:
+		return int64(n)
+	}
+	return 0
+}
+
+func stringSet(v interface{}) map[string]struct{} {
+	set := make(map[string]struct{})
+	switch items := v.(type) {
+	case []string:
+		for _, s := range items {
+			set[s] = struct{}{}
+		}
+	case primitive.A:
+		for _, item := range items {
+			if s, ok := item.(string); ok {
+				set[s] = struct{}{}
+			}
+		}
+	}
+	return set
+}
+
+func setToSlice(set map[string]struct{}) []string {
+	result := make([]string, 0, len(set))
+	for s := range set {
+		result = append(result, s)
+	}
+	sort.Strings(result)
+	return result
+}
@@ -0,0 +1,194 @@
+package models
+
+import (
+	"context"
+	"fmt"
+	"qinniu/internal/pkg/database"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const jobCollectionName = "crawl_jobs"
+
+// 爬取任务状态
+const (
+	JobStatusQueued    = "queued"
+	JobStatusRunning   = "running"
+	JobStatusCompleted = "completed"
+)
+
+// 任务中单个用户的状态
+const (
+	JobUserPending   = "pending"
+	JobUserRunning   = "running"
+	JobUserSucceeded = "succeeded"
+	JobUserFailed    = "failed"
+)
+
+// CrawlJob 异步爬取任务
+type CrawlJob struct {
+	ID          primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	Status      string             `bson:"status" json:"status"`
+	Full        bool               `bson:"full" json:"full"`
+	Concurrency int                `bson:"concurrency" json:"concurrency"`
+	Total       int                `bson:"total" json:"total"`
+	Succeeded   int                `bson:"succeeded" json:"succeeded"`
+	Failed      int                `bson:"failed" json:"failed"`
+	Users       []JobUser          `bson:"users" json:"users"`
+	CreatedAt   time.Time          `bson:"created_at" json:"created_at"`
+	StartedAt   *time.Time         `bson:"started_at,omitempty" json:"started_at,omitempty"`
+	FinishedAt  *time.Time         `bson:"finished_at,omitempty" json:"finished_at,omitempty"`
+}
+
+// JobUser 任务中单个用户的执行情况
+type JobUser struct {
+	Username   string         `bson:"username" json:"username"`
+	Status     string         `bson:"status" json:"status"`
+	Error      string         `bson:"error,omitempty" json:"error,omitempty"`
+	StartedAt  *time.Time     `bson:"started_at,omitempty" json:"started_at,omitempty"`
+	FinishedAt *time.Time     `bson:"finished_at,omitempty" json:"finished_at,omitempty"`
+	DurationMs int64          `bson:"duration_ms,omitempty" json:"duration_ms,omitempty"`
+	Result     *JobUserResult `bson:"result,omitempty" json:"result,omitempty"`
+}
+
+// JobUserResult 爬取成功后的结果摘要
+type JobUserResult struct {
+	DeveloperID      primitive.ObjectID `bson:"developer_id" json:"developer_id"`
+	TalentRank       float64            `bson:"talent_rank" json:"talent_rank"`
+	Nation           string             `bson:"nation,omitempty" json:"nation,omitempty"`
+	NationConfidence float64            `bson:"nation_confidence,omitempty" json:"nation_confidence,omitempty"`
+	Confidence       float64            `bson:"confidence" json:"confidence"`
+	Repositories     int                `bson:"repositories" json:"repositories"`
+}
+
+// GetJobCollection 获取爬取任务集合
+func GetJobCollection() *mongo.Collection {
+	return database.DB.Collection(jobCollectionName)
+}
+
+// NewCrawlJob 为一批用户名创建任务，所有用户初始为等待状态
+func NewCrawlJob(usernames []string, full bool, concurrency int) *CrawlJob {
+	job := &CrawlJob{
+		Status:      JobStatusQueued,
+		Full:        full,
+		Concurrency: concurrency,
+		Total:       len(usernames),
+		CreatedAt:   time.Now(),
+	}
+	for _, username := range usernames {
+		job.Users = append(job.Users, JobUser{Username: username, Status: JobUserPending})
+	}
+	return job
+}
+
+// Create 保存新任务
+func (j *CrawlJob) Create() error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	j.ID = primitive.NilObjectID
+	result, err := GetJobCollection().InsertOne(ctx, j)
+	if err != nil {
+		return err
+	}
+	j.ID = result.InsertedID.(primitive.ObjectID)
+	return nil
+}
+
+// FindJobByID 通过ID查找任务
+func FindJobByID(id string) (*CrawlJob, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	objectID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return nil, err
+	}
+
+	var job CrawlJob
+	if err := GetJobCollection().FindOne(ctx, bson.M{"_id": objectID}).Decode(&job); err != nil {
+		return nil, err
+	}
+	return &job, nil
+}
+
+// FindUnfinishedJobs 查找服务重启前没有完成的任务
+func FindUnfinishedJobs() ([]*CrawlJob, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	filter := bson.M{"status": bson.M{"$in": []string{JobStatusQueued, JobStatusRunning}}}
+	cursor, err := GetJobCollection().Find(ctx, filter, options.Find().SetSort(bson.M{"created_at": 1}))
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var jobs []*CrawlJob
+	if err := cursor.All(ctx, &jobs); err != nil {
+		return nil, err
+	}
+	return jobs, nil
+}
+
+// MarkJobStarted 将任务标记为执行中
+func MarkJobStarted(id primitive.ObjectID) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	now := time.Now()
+	_, err := GetJobCollection().UpdateOne(ctx,
+		bson.M{"_id": id, "status": JobStatusQueued},
+		bson.M{"$set": bson.M{"status": JobStatusRunning, "started_at": now}},
+	)
+	return err
+}
+
+// MarkJobCompleted 将任务标记为已完成
+func MarkJobCompleted(id primitive.ObjectID) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	now := time.Now()
+	_, err := GetJobCollection().UpdateOne(ctx,
+		bson.M{"_id": id},
+		bson.M{"$set": bson.M{"status": JobStatusCompleted, "finished_at": now}},
+	)
+	return err
+}
+
+// MarkJobUserStarted 记录任务中第 index 个用户开始爬取
+func MarkJobUserStarted(id primitive.ObjectID, index int, startedAt time.Time) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	prefix := fmt.Sprintf("users.%d.", index)
+	_, err := GetJobCollection().UpdateOne(ctx, bson.M{"_id": id}, bson.M{
+		"$set": bson.M{
+			prefix + "status":     JobUserRunning,
+			prefix + "started_at": startedAt,
+		},
+	})
+	return err
+}
+
+// MarkJobUserFinished 记录任务中第 index 个用户的爬取结果，并更新成功/失败计数
+func MarkJobUserFinished(id primitive.ObjectID, index int, user JobUser) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	counter := "succeeded"
+	if user.Status == JobUserFailed {
+		counter = "failed"
+	}
+
+	_, err := GetJobCollection().UpdateOne(ctx, bson.M{"_id": id}, bson.M{
+		"$set": bson.M{fmt.Sprintf("users.%d", index): user},
+		"$inc": bson.M{counter: 1},
+	})
+	return err
+}
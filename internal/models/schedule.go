@@ -0,0 +1,194 @@
+package models
+
+import (
+	"context"
+	"math"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const (
+	// 自适应更新频率的上下限
+	MinUpdateFrequency = 6 * time.Hour
+	MaxUpdateFrequency = 30 * 24 * time.Hour
+)
+
+// RefreshFailure 后台刷新连续失败的记录
+type RefreshFailure struct {
+	Failures     int       `bson:"failures" json:"failures"`   // 连续失败次数
+	NotFound     int       `bson:"not_found" json:"not_found"` // 连续返回 404 的次数
+	LastError    string    `bson:"last_error" json:"last_error"`
+	LastFailedAt time.Time `bson:"last_failed_at" json:"last_failed_at"`
+	Paused       bool      `bson:"paused" json:"paused"` // 账号多次不存在，不再自动刷新，手动爬取成功后恢复
+}
+
+// FindDueForRefresh 查找 LastUpdated + UpdateFrequency 已经过期的开发者，跳过暂停刷新的
+// 关注的开发者优先，其次按 TalentRank 从高到低
+func FindDueForRefresh(now time.Time, limit int64) ([]*Developer, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	pipeline := []bson.M{
+		{"$match": bson.M{
+			"refresh_failure.paused": bson.M{"$ne": true},
+			"$expr": bson.M{
+				"$lt": []interface{}{
+					// update_frequency 以纳秒存储，日期加法使用毫秒
+					bson.M{"$add": []interface{}{
+						"$last_updated",
+						bson.M{"$divide": []interface{}{bson.M{"$ifNull": []interface{}{"$update_frequency", 0}}, 1e6}},
+					}},
+					now,
+				},
+			},
+		}},
+		{"$sort": bson.D{
+			{Key: "watched", Value: -1},
+			{Key: "talent_rank", Value: -1},
+			{Key: "_id", Value: 1},
+		}},
+		{"$limit": limit},
+		{"$project": bson.M{
+			"_id":              1,
+			"github_id":        1,
+			"username":         1,
+			"talent_rank":      1,
+			"watched":          1,
+			"repositories":     1,
+			"accounts":         1,
+			"last_updated":     1,
+			"update_frequency": 1,
+			"star_analysis":    1,
+		}},
+	}
+
+	cursor, err := GetCollection().Aggregate(ctx, pipeline)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var developers []*Developer
+	if err = cursor.All(ctx, &developers); err != nil {
+		return nil, err
+	}
+
+	return developers, nil
+}
+
+// RecordRefreshFailure 记录一次刷新失败并推迟下次刷新：LastUpdated 设为当前时间，更新频率加倍
+// notFound 表示账号不存在，连续 pauseAfter 次后暂停自动刷新，pauseAfter <= 0 时不暂停
+func RecordRefreshFailure(id primitive.ObjectID, message string, notFound bool, pauseAfter int) (*RefreshFailure, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	now := time.Now()
+	// 其他错误打断连续的 404 计数
+	var notFoundCount interface{} = 0
+	if notFound {
+		notFoundCount = bson.M{"$add": []interface{}{bson.M{"$ifNull": []interface{}{"$refresh_failure.not_found", 0}}, 1}}
+	}
+	var paused interface{} = false
+	if pauseAfter > 0 {
+		paused = bson.M{"$gte": []interface{}{"$refresh_failure.not_found", pauseAfter}}
+	}
+
+	update := []bson.M{
+		{"$set": bson.M{
+			"refresh_failure.failures":       bson.M{"$add": []interface{}{bson.M{"$ifNull": []interface{}{"$refresh_failure.failures", 0}}, 1}},
+			"refresh_failure.not_found":      notFoundCount,
+			"refresh_failure.last_error":     message,
+			"refresh_failure.last_failed_at": now,
+			"last_updated":                   now,
+			"update_frequency": bson.M{"$min": []interface{}{
+				bson.M{"$multiply": []interface{}{
+					bson.M{"$max": []interface{}{bson.M{"$ifNull": []interface{}{"$update_frequency", 0}}, MinUpdateFrequency}},
+					2,
+				}},
+				MaxUpdateFrequency,
+			}},
+		}},
+		{"$set": bson.M{"refresh_failure.paused": paused}},
+	}
+
+	var result struct {
+		RefreshFailure *RefreshFailure `bson:"refresh_failure"`
+	}
+	err := GetCollection().FindOneAndUpdate(ctx, bson.M{"_id": id}, update,
+		options.FindOneAndUpdate().
+			SetProjection(bson.M{"refresh_failure": 1}).
+			SetReturnDocument(options.After),
+	).Decode(&result)
+	if err != nil {
+		return nil, err
+	}
+	return result.RefreshFailure, nil
+}
+
+// ClearRefreshFailure 爬取成功后清除刷新失败的记录，暂停的开发者恢复自动刷新
+func ClearRefreshFailure(id primitive.ObjectID) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	_, err := GetCollection().UpdateOne(ctx,
+		bson.M{"_id": id, "refresh_failure": bson.M{"$exists": true}},
+		bson.M{"$unset": bson.M{"refresh_failure": ""}},
+	)
+	return err
+}
+
+// SetWatched 设置是否关注开发者，关注的开发者会被优先刷新
+func SetWatched(id primitive.ObjectID, watched bool) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	_, err := GetCollection().UpdateOne(ctx, bson.M{"_id": id}, bson.M{
+		"$set": bson.M{"watched": watched, "updated_at": time.Now()},
+	})
+	return err
+}
+
+// AdaptUpdateFrequency 根据上次刷新的数据变化程度调整更新频率
+// 变化很小则降低刷新频率，变化明显则提高刷新频率
+func AdaptUpdateFrequency(previous, current *Developer) time.Duration {
+	frequency := previous.UpdateFrequency
+	if frequency <= 0 {
+		frequency = current.UpdateFrequency
+	}
+	if frequency <= 0 {
+		frequency = 7 * 24 * time.Hour
+	}
+
+	change := math.Max(
+		math.Max(relativeChange(previous.StarCount, current.StarCount),
+			relativeChange(previous.CommitCount, current.CommitCount)),
+		math.Max(relativeChange(len(previous.Repositories), len(current.Repositories)),
+			math.Abs(current.TalentRank-previous.TalentRank)/100),
+	)
+
+	switch {
+	case change < 0.01:
+		frequency *= 2
+	case change > 0.10:
+		frequency /= 2
+	}
+
+	if frequency < MinUpdateFrequency {
+		frequency = MinUpdateFrequency
+	}
+	if frequency > MaxUpdateFrequency {
+		frequency = MaxUpdateFrequency
+	}
+	return frequency
+}
+
+// relativeChange 计算两个计数之间的相对变化
+func relativeChange(before, after int) float64 {
+	if before == after {
+		return 0
+	}
+	return math.Abs(float64(after-before)) / math.Max(float64(before), 1)
+}
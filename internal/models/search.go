@@ -0,0 +1,134 @@
+package models
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// 每个开发者保存描述的仓库数量上限，按 star 数取前 N 个，避免文档过大
+const maxRepoSummaries = 30
+
+// RepoSummary 仓库的名称、描述和主题
+type RepoSummary struct {
+	Name        string   `bson:"name" json:"name"`
+	Description string   `bson:"description,omitempty" json:"description,omitempty"`
+	Topics      []string `bson:"topics,omitempty" json:"topics,omitempty"`
+	Stars       int      `bson:"stars" json:"stars"`
+}
+
+// Highlight 全文搜索命中的片段，匹配的词用 <em> 标记
+type Highlight struct {
+	Field   string `json:"field"`
+	Snippet string `json:"snippet"`
+}
+
+// ScoredDeveloper 全文搜索结果
+type ScoredDeveloper struct {
+	Developer   `bson:",inline"`
+	Relevance   float64     `bson:"relevance" json:"relevance"`       // 文本相关度
+	SearchScore float64     `bson:"search_score" json:"search_score"` // 相关度与 TalentRank 加权后的排序分数
+	Highlights  []Highlight `bson:"-" json:"highlights,omitempty"`
+}
+
+// textIndexName 全文索引名称
+const textIndexName = "text_search"
+
+// textIndexWeights 全文索引覆盖的字段及权重
+// AI 评估只索引通过审核的副本 search_evaluation，未通过审核的评估内容不能影响没有 view_sensitive 权限的调用方能搜到谁
+var textIndexWeights = bson.D{
+	{Key: "name", Value: 10},
+	{Key: "username", Value: 10},
+	{Key: "bio", Value: 5},
+	{Key: "repositories", Value: 5},
+	{Key: "repo_summaries.name", Value: 5},
+	{Key: "repo_summaries.topics", Value: 4},
+	{Key: "repo_summaries.description", Value: 2},
+	{Key: "search_evaluation.specialties", Value: 3},
+	{Key: "search_evaluation.ai_evaluation", Value: 1},
+}
+
+// SearchEvaluation 通过审核的评估中参与全文搜索的内容，由 SyncSearchEvaluation 维护
+type SearchEvaluation struct {
+	Specialties  []string `bson:"specialties,omitempty"`
+	AIEvaluation string   `bson:"ai_evaluation,omitempty"`
+}
+
+// ensureTextIndex 创建开发者集合的全文索引，一个集合只能有一个全文索引
+// 索引字段变化后同名索引无法直接创建，删除旧索引后重建
+func ensureTextIndex(ctx context.Context) error {
+	keys := bson.D{}
+	for _, field := range textIndexWeights {
+		keys = append(keys, bson.E{Key: field.Key, Value: "text"})
+	}
+	model := mongo.IndexModel{
+		Keys: keys,
+		Options: options.Index().
+			SetName(textIndexName).
+			SetWeights(textIndexWeights).
+			SetDefaultLanguage("none"), // 不做词干和停用词处理，同时适用于中英文混合的内容
+	}
+	_, err := GetCollection().Indexes().CreateOne(ctx, model)
+	var cmdErr mongo.CommandError
+	if errors.As(err, &cmdErr) && (cmdErr.Code == 85 || cmdErr.Code == 86) { // IndexOptionsConflict、IndexKeySpecsConflict
+		if _, err := GetCollection().Indexes().DropOne(ctx, textIndexName); err != nil {
+			return err
+		}
+		_, err = GetCollection().Indexes().CreateOne(ctx, model)
+		return err
+	}
+	return err
+}
+
+// SyncSearchEvaluation 评估或审核状态变化后更新开发者的 search_evaluation：
+// 通过审核（approved、auto_approved）时复制专长和评价，否则删除
+func SyncSearchEvaluation(id primitive.ObjectID) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	return syncSearchEvaluation(ctx, bson.M{"_id": id})
+}
+
+func syncSearchEvaluation(ctx context.Context, filter bson.M) error {
+	update := mongo.Pipeline{{{Key: "$set", Value: bson.D{{Key: "search_evaluation", Value: bson.M{"$cond": bson.A{
+		bson.M{"$in": bson.A{"$tech_evaluation.status", bson.A{EvaluationApproved, EvaluationAutoApproved}}},
+		bson.M{"specialties": "$tech_evaluation.specialties", "ai_evaluation": "$tech_evaluation.ai_evaluation"},
+		"$$REMOVE",
+	}}}}}}}
+	_, err := GetCollection().UpdateMany(ctx, filter, update)
+	return err
+}
+
+// SummarizeRepositories 按 star 数保留前 N 个仓库的描述
+func SummarizeRepositories(summaries []RepoSummary) []RepoSummary {
+	sort.SliceStable(summaries, func(i, j int) bool {
+		return summaries[i].Stars > summaries[j].Stars
+	})
+	if len(summaries) > maxRepoSummaries {
+		summaries = summaries[:maxRepoSummaries]
+	}
+	return summaries
+}
+
+// AggregateTextSearch 执行全文搜索的聚合查询，管道中需要自行投影 relevance 和 search_score
+func AggregateTextSearch(pipeline []bson.M) ([]*ScoredDeveloper, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	cursor, err := GetCollection().Aggregate(ctx, pipeline)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	developers := make([]*ScoredDeveloper, 0)
+	if err = cursor.All(ctx, &developers); err != nil {
+		return nil, err
+	}
+	return developers, nil
+}
@@ -0,0 +1,41 @@
+package models
+
+import "time"
+
+// StarAnalysis star 质量分析结果，保留采样统计便于审计
+type StarAnalysis struct {
+	AnalyzedAt    time.Time        `bson:"analyzed_at" json:"analyzed_at"`
+	RawStars      int              `bson:"raw_stars" json:"raw_stars"`           // 原始 star 总数
+	AdjustedStars int              `bson:"adjusted_stars" json:"adjusted_stars"` // 折算后的 star 总数
+	GenuineRatio  float64          `bson:"genuine_ratio" json:"genuine_ratio"`   // 采样仓库的加权真实比例
+	SampleSize    int              `bson:"sample_size" json:"sample_size"`       // 每个仓库的目标采样数
+	Repositories  []RepoStarSample `bson:"repositories" json:"repositories"`
+}
+
+// RepoStarSample 单个仓库的 stargazer 采样统计
+type RepoStarSample struct {
+	Repo              string  `bson:"repo" json:"repo"`
+	Stars             int     `bson:"stars" json:"stars"`
+	Sampled           int     `bson:"sampled" json:"sampled"`
+	Genuine           int     `bson:"genuine" json:"genuine"`
+	Suspicious        int     `bson:"suspicious" json:"suspicious"`
+	AvgScore          float64 `bson:"avg_score" json:"avg_score"`
+	AvgAccountAgeDays float64 `bson:"avg_account_age_days" json:"avg_account_age_days"`
+	AvgFollowers      float64 `bson:"avg_followers" json:"avg_followers"`
+	ZeroActivity      int     `bson:"zero_activity" json:"zero_activity"` // 没有仓库、没有关注者的账号数
+	GenuineRatio      float64 `bson:"genuine_ratio" json:"genuine_ratio"`
+	AdjustedStars     int     `bson:"adjusted_stars" json:"adjusted_stars"`
+}
+
+// AdjustedStarsFor 返回仓库折算后的 star 数，未采样的仓库返回 false
+func (a *StarAnalysis) AdjustedStarsFor(repo string) (int, bool) {
+	if a == nil {
+		return 0, false
+	}
+	for _, sample := range a.Repositories {
+		if sample.Repo == repo {
+			return sample.AdjustedStars, true
+		}
+	}
+	return 0, false
+}
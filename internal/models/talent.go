@@ -116,3 +116,15 @@ func calculateExpertiseScore(expertise ExpertiseMetrics) float64 {
 
 	return breadthScore*0.3 + domainScore*0.3 + depthScore*0.4
 }
+
+// 计算活动频率
+func calculateActivityFrequency(contributions int) float64 {
+	// 简单的活动频率计算
+	return math.Min(float64(contributions)/1000.0, 1.0)
+}
+
+// 计算增长趋势
+func calculateGrowthTrend(contributions int) float64 {
+	// 简单的增长趋势计算
+	return math.Min(float64(contributions)/500.0, 1.0)
+}
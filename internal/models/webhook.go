@@ -0,0 +1,273 @@
+package models
+
+import (
+	"context"
+	"errors"
+	"qinniu/internal/pkg/database"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const (
+	webhookCollectionName  = "webhooks"
+	deliveryCollectionName = "webhook_deliveries"
+)
+
+// 投递状态
+const (
+	DeliveryPending   = "pending"
+	DeliverySucceeded = "succeeded"
+	DeliveryFailed    = "failed"
+)
+
+// WebhookSubscription 外部系统订阅的 webhook
+type WebhookSubscription struct {
+	ID          primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	URL         string             `bson:"url" json:"url"`
+	Events      []string           `bson:"events" json:"events"`
+	Filters     WebhookFilters     `bson:"filters" json:"filters"`
+	Secret      string             `bson:"secret" json:"-"` // 用于 HMAC 签名，只在创建时返回
+	Description string             `bson:"description,omitempty" json:"description,omitempty"`
+	Active      bool               `bson:"active" json:"active"`
+	CreatedAt   time.Time          `bson:"created_at" json:"created_at"`
+	UpdatedAt   time.Time          `bson:"updated_at" json:"updated_at"`
+}
+
+// WebhookFilters 订阅的过滤条件，为空表示不限制
+type WebhookFilters struct {
+	Nations             []string `bson:"nations,omitempty" json:"nations,omitempty"`
+	Skills              []string `bson:"skills,omitempty" json:"skills,omitempty"`
+	MinTalentRank       float64  `bson:"min_talent_rank,omitempty" json:"min_talent_rank,omitempty"`
+	RankChangeThreshold float64  `bson:"rank_change_threshold,omitempty" json:"rank_change_threshold,omitempty"` // TalentRank 变化超过该值才通知
+}
+
+// WebhookDelivery 单次事件投递及其重试记录
+type WebhookDelivery struct {
+	ID             primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	SubscriptionID primitive.ObjectID `bson:"subscription_id" json:"subscription_id"`
+	Event          string             `bson:"event" json:"event"`
+	Payload        string             `bson:"payload" json:"payload"` // 原样保存，重试时签名内容不变
+	Status         string             `bson:"status" json:"status"`
+	AttemptCount   int                `bson:"attempt_count" json:"attempt_count"`
+	Attempts       []WebhookAttempt   `bson:"attempts,omitempty" json:"attempts,omitempty"`
+	NextAttemptAt  time.Time          `bson:"next_attempt_at" json:"next_attempt_at"`
+	CreatedAt      time.Time          `bson:"created_at" json:"created_at"`
+	UpdatedAt      time.Time          `bson:"updated_at" json:"updated_at"`
+}
+
+// WebhookAttempt 一次投递尝试
+type WebhookAttempt struct {
+	At         time.Time `bson:"at" json:"at"`
+	StatusCode int       `bson:"status_code,omitempty" json:"status_code,omitempty"`
+	Error      string    `bson:"error,omitempty" json:"error,omitempty"`
+	DurationMs int64     `bson:"duration_ms" json:"duration_ms"`
+}
+
+func GetWebhookCollection() *mongo.Collection {
+	return database.DB.Collection(webhookCollectionName)
+}
+
+func GetDeliveryCollection() *mongo.Collection {
+	return database.DB.Collection(deliveryCollectionName)
+}
+
+// ensureWebhookIndexes 创建投递记录的索引，用于领取到期投递和查询投递日志
+func ensureWebhookIndexes(ctx context.Context) error {
+	_, err := GetDeliveryCollection().Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{
+			Keys:    bson.D{{Key: "status", Value: 1}, {Key: "next_attempt_at", Value: 1}},
+			Options: options.Index().SetName("idx_status_next_attempt"),
+		},
+		{
+			Keys:    bson.D{{Key: "subscription_id", Value: 1}, {Key: "created_at", Value: -1}},
+			Options: options.Index().SetName("idx_subscription_created"),
+		},
+	})
+	return err
+}
+
+// Create 创建订阅
+func (w *WebhookSubscription) Create() error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	w.ID = primitive.NilObjectID
+	w.CreatedAt = time.Now()
+	w.UpdatedAt = w.CreatedAt
+	result, err := GetWebhookCollection().InsertOne(ctx, w)
+	if err != nil {
+		return err
+	}
+	w.ID = result.InsertedID.(primitive.ObjectID)
+	return nil
+}
+
+// Update 更新订阅的地址、事件、过滤条件和状态，密钥不变
+func (w *WebhookSubscription) Update() error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	w.UpdatedAt = time.Now()
+	_, err := GetWebhookCollection().UpdateOne(ctx, bson.M{"_id": w.ID}, bson.M{
+		"$set": bson.M{
+			"url":         w.URL,
+			"events":      w.Events,
+			"filters":     w.Filters,
+			"description": w.Description,
+			"active":      w.Active,
+			"updated_at":  w.UpdatedAt,
+		},
+	})
+	return err
+}
+
+// Delete 删除订阅
+func (w *WebhookSubscription) Delete() error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	_, err := GetWebhookCollection().DeleteOne(ctx, bson.M{"_id": w.ID})
+	return err
+}
+
+// FindWebhookByID 通过ID查找订阅
+func FindWebhookByID(id string) (*WebhookSubscription, error) {
+	objectID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return nil, err
+	}
+	return findWebhook(objectID)
+}
+
+func findWebhook(id primitive.ObjectID) (*WebhookSubscription, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	var subscription WebhookSubscription
+	if err := GetWebhookCollection().FindOne(ctx, bson.M{"_id": id}).Decode(&subscription); err != nil {
+		return nil, err
+	}
+	return &subscription, nil
+}
+
+// ListWebhooks 列出所有订阅
+func ListWebhooks() ([]*WebhookSubscription, error) {
+	return findWebhooks(bson.M{})
+}
+
+// FindActiveWebhooks 查找订阅了指定事件的有效订阅
+func FindActiveWebhooks(event string) ([]*WebhookSubscription, error) {
+	return findWebhooks(bson.M{"active": true, "events": event})
+}
+
+func findWebhooks(filter bson.M) ([]*WebhookSubscription, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	cursor, err := GetWebhookCollection().Find(ctx, filter, options.Find().SetSort(bson.M{"created_at": 1}))
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var subscriptions []*WebhookSubscription
+	if err := cursor.All(ctx, &subscriptions); err != nil {
+		return nil, err
+	}
+	return subscriptions, nil
+}
+
+// Create 保存待投递的事件
+func (d *WebhookDelivery) Create() error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	d.ID = primitive.NilObjectID
+	d.CreatedAt = time.Now()
+	d.UpdatedAt = d.CreatedAt
+	if d.Status == "" {
+		d.Status = DeliveryPending
+	}
+	if d.NextAttemptAt.IsZero() {
+		d.NextAttemptAt = d.CreatedAt
+	}
+	result, err := GetDeliveryCollection().InsertOne(ctx, d)
+	if err != nil {
+		return err
+	}
+	d.ID = result.InsertedID.(primitive.ObjectID)
+	return nil
+}
+
+// ClaimDueDelivery 领取一个到期的投递，并把下次尝试时间推迟 lease
+// 多个服务实例同时投递时，同一事件在 lease 内只会被一个实例领取
+func ClaimDueDelivery(now time.Time, lease time.Duration) (*WebhookDelivery, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	var delivery WebhookDelivery
+	err := GetDeliveryCollection().FindOneAndUpdate(ctx,
+		bson.M{"status": DeliveryPending, "next_attempt_at": bson.M{"$lte": now}},
+		bson.M{"$set": bson.M{"next_attempt_at": now.Add(lease)}},
+		options.FindOneAndUpdate().
+			SetSort(bson.M{"next_attempt_at": 1}).
+			SetReturnDocument(options.After),
+	).Decode(&delivery)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &delivery, nil
+}
+
+// RecordAttempt 记录一次投递结果
+func (d *WebhookDelivery) RecordAttempt(attempt WebhookAttempt, status string, nextAttemptAt time.Time) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	d.Attempts = append(d.Attempts, attempt)
+	d.AttemptCount++
+	d.Status = status
+	d.NextAttemptAt = nextAttemptAt
+	d.UpdatedAt = time.Now()
+
+	_, err := GetDeliveryCollection().UpdateOne(ctx, bson.M{"_id": d.ID}, bson.M{
+		"$push": bson.M{"attempts": attempt},
+		"$inc":  bson.M{"attempt_count": 1},
+		"$set": bson.M{
+			"status":          status,
+			"next_attempt_at": nextAttemptAt,
+			"updated_at":      d.UpdatedAt,
+		},
+	})
+	return err
+}
+
+// ListDeliveries 查询订阅的投递记录，最新的在前
+func ListDeliveries(subscriptionID primitive.ObjectID, status string, limit int64) ([]*WebhookDelivery, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	filter := bson.M{"subscription_id": subscriptionID}
+	if status != "" {
+		filter["status"] = status
+	}
+	cursor, err := GetDeliveryCollection().Find(ctx, filter,
+		options.Find().SetSort(bson.M{"created_at": -1}).SetLimit(limit))
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var deliveries []*WebhookDelivery
+	if err := cursor.All(ctx, &deliveries); err != nil {
+		return nil, err
+	}
+	return deliveries, nil
+}
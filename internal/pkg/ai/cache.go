@@ -0,0 +1,176 @@
+package ai
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"os"
+	"qinniu/internal/models"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ResultStore 保存模型输出和调用用量
+type ResultStore interface {
+	FindResult(key string) (*models.AIResult, error)
+	SaveResult(result *models.AIResult) error
+	RecordUsage(usage *models.AIUsage) error
+}
+
+type mongoStore struct{}
+
+func (mongoStore) FindResult(key string) (*models.AIResult, error) { return models.FindAIResult(key) }
+func (mongoStore) SaveResult(result *models.AIResult) error        { return models.SaveAIResult(result) }
+func (mongoStore) RecordUsage(usage *models.AIUsage) error         { return models.RecordAIUsage(usage) }
+
+// MongoStore 把模型输出缓存在 ai_results 集合，用量记录在 ai_usage 集合
+var MongoStore ResultStore = mongoStore{}
+
+// CacheConfig 模型输出缓存配置
+type CacheConfig struct {
+	Enabled bool
+	TTL     time.Duration // 0 表示不过期
+}
+
+// LoadCacheConfig 从环境变量读取缓存配置，默认启用、保存 30 天
+func LoadCacheConfig() CacheConfig {
+	cfg := CacheConfig{Enabled: os.Getenv("AI_CACHE_ENABLED") != "false", TTL: 30 * 24 * time.Hour}
+	if v := os.Getenv("AI_CACHE_TTL"); v != "" {
+		if ttl, err := time.ParseDuration(v); err == nil && ttl >= 0 {
+			cfg.TTL = ttl
+		}
+	}
+	return cfg
+}
+
+// LoadEvaluationMaxAge 评估结果的最长有效期，超过后即使资料没有变化也重新评估（工作证据可能变化）
+// 读取 AI_EVALUATION_MAX_AGE，默认 30 天，0 表示资料不变就不重新评估
+func LoadEvaluationMaxAge() time.Duration {
+	if v := os.Getenv("AI_EVALUATION_MAX_AGE"); v != "" {
+		if age, err := time.ParseDuration(v); err == nil && age >= 0 {
+			return age
+		}
+	}
+	return 30 * 24 * time.Hour
+}
+
+// Price 每百万 token 的单价
+type Price struct {
+	Input  float64 `json:"input"`
+	Output float64 `json:"output"`
+}
+
+// Cost 按单价计算一次调用的费用
+func (p Price) Cost(usage Usage) float64 {
+	return (float64(usage.PromptTokens)*p.Input + float64(usage.CompletionTokens)*p.Output) / 1e6
+}
+
+// LoadPrices 读取 AI_PRICES，格式为 模型=输入单价/输出单价，多个模型用逗号分隔，
+// 如 deepseek-chat=0.27/1.10,gpt-4o-mini=0.15/0.60；没有配置的模型费用为 0
+func LoadPrices() map[string]Price {
+	prices := make(map[string]Price)
+	for _, item := range strings.Split(os.Getenv("AI_PRICES"), ",") {
+		model, rates, ok := strings.Cut(strings.TrimSpace(item), "=")
+		if !ok {
+			continue
+		}
+		in, out, _ := strings.Cut(rates, "/")
+		input, err := strconv.ParseFloat(strings.TrimSpace(in), 64)
+		if err != nil {
+			continue
+		}
+		output, err := strconv.ParseFloat(strings.TrimSpace(out), 64)
+		if err != nil {
+			output = input
+		}
+		prices[strings.TrimSpace(model)] = Price{Input: input, Output: output}
+	}
+	return prices
+}
+
+// cacheKey 输入哈希：后端、模型、提示词版本、输出格式和完整的对话消息
+// 工作证据和资料都渲染在消息中，任何变化都会得到不同的键
+func cacheKey(provider Provider, promptVersion string, req *ChatRequest) string {
+	payload := struct {
+		Provider    string    `json:"provider"`
+		Model       string    `json:"model"`
+		Prompt      string    `json:"prompt"`
+		Format      string    `json:"format,omitempty"`
+		Temperature *float64  `json:"temperature,omitempty"`
+		Messages    []Message `json:"messages"`
+	}{
+		Provider:    provider.Name(),
+		Model:       provider.Model(),
+		Prompt:      promptVersion,
+		Temperature: req.Temperature,
+		Messages:    req.Messages,
+	}
+	if req.Format != nil {
+		payload.Format = req.Format.Name
+	}
+	data, _ := json.Marshal(payload)
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// Fingerprint 资料的指纹，不含提交数、star 数、活跃时间等每次爬取都会变化的统计数据
+// 爬虫据此判断是否需要重新评估
+func (p *DeveloperProfile) Fingerprint() string {
+	repos := make([]RepoInfo, 0, len(p.Repositories))
+	for _, repo := range p.Repositories {
+		repo.Stars = 0
+		repos = append(repos, repo)
+	}
+	sort.Slice(repos, func(i, j int) bool { return repos[i].Owner+"/"+repos[i].Name < repos[j].Owner+"/"+repos[j].Name })
+
+	data, _ := json.Marshal(struct {
+		Username     string     `json:"username"`
+		Name         string     `json:"name"`
+		Bio          string     `json:"bio"`
+		Blog         string     `json:"blog"`
+		Skills       []string   `json:"skills"`
+		Repositories []RepoInfo `json:"repositories"`
+	}{p.Username, p.Name, p.Bio, p.Blog, p.Skills, repos})
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:16])
+}
+
+// inflightGroup 合并同一输入的并发调用，只有一个请求真正调用模型
+type inflightGroup struct {
+	mu    sync.Mutex
+	calls map[string]*inflightCall
+}
+
+type inflightCall struct {
+	wg      sync.WaitGroup
+	content string
+	err     error
+}
+
+// do 执行 fn，同一 key 已有调用在进行时等待其结果，shared 表示结果来自其他调用
+func (g *inflightGroup) do(key string, fn func() (string, error)) (content string, shared bool, err error) {
+	g.mu.Lock()
+	if g.calls == nil {
+		g.calls = make(map[string]*inflightCall)
+	}
+	if call, ok := g.calls[key]; ok {
+		g.mu.Unlock()
+		call.wg.Wait()
+		return call.content, true, call.err
+	}
+	call := &inflightCall{}
+	call.wg.Add(1)
+	g.calls[key] = call
+	g.mu.Unlock()
+
+	call.content, call.err = fn()
+	call.wg.Done()
+
+	g.mu.Lock()
+	delete(g.calls, key)
+	g.mu.Unlock()
+	return call.content, false, call.err
+}
@@ -1,198 +1,347 @@
 package ai
 
 import (
-	"bytes"
 	"context"
-	"encoding/json"
 	"fmt"
-	"io"
 	"log"
-	"net/http"
+	"qinniu/internal/models"
 	"strings"
 	"time"
 )
 
+// Client 开发者评估客户端，具体调用哪个大模型由 Provider 决定，提示词来自模板
+// 设置了 ResultStore 时，相同输入复用缓存的输出，并记录每次调用的用量
 type Client struct {
-	apiKey     string
-	apiBaseURL string
-	model      string
+	provider  Provider
+	prompts   *PromptRegistry
+	store     ResultStore
+	cache     CacheConfig
+	prices    map[string]Price
+	inflight  inflightGroup
+	consensus ConsensusConfig // 配置了多个评估配置时，评估和国家推断取多数意见
 }
 
-func NewClient(apiKey string) *Client {
-	log.Printf("Initializing AI client with key: %s...", apiKey[:10])
+func NewClient(provider Provider) *Client {
+	return &Client{provider: provider, prompts: DefaultPrompts(), prices: LoadPrices()}
+}
 
-	return &Client{
-		apiKey:     apiKey,
-		apiBaseURL: "https://api.deepseek.com/chat/completions",
-		model:      "deepseek-chat",
+// NewClientFromEnv 按 AI_PROVIDER 等环境变量创建客户端，输出缓存和用量保存在 MongoDB
+func NewClientFromEnv() (*Client, error) {
+	provider, err := NewProvider(LoadProviderConfig())
+	if err != nil {
+		return nil, err
+	}
+	client := NewClient(provider)
+	client.SetStore(MongoStore, LoadCacheConfig())
+
+	consensus, err := LoadConsensusConfig(LoadProviderConfig())
+	if err != nil {
+		return nil, err
 	}
+	client.SetConsensus(consensus)
+	return client, nil
 }
 
-type Message struct {
-	Role    string `json:"role"`
-	Content string `json:"content"`
+// SetStore 设置输出缓存和用量记录的存储，store 为 nil 时两者都关闭
+func (c *Client) SetStore(store ResultStore, cache CacheConfig) {
+	c.store = store
+	c.cache = cache
 }
 
-type ChatRequest struct {
-	Model    string    `json:"model"`
-	Messages []Message `json:"messages"`
-	Stream   bool      `json:"stream"`
+// Provider 客户端使用的后端
+func (c *Client) Provider() Provider {
+	return c.provider
 }
 
-type ChatResponse struct {
-	Choices []struct {
-		Message struct {
-			Content string `json:"content"`
-		} `json:"message"`
-	} `json:"choices"`
+// Prompts 客户端使用的提示词模板
+func (c *Client) Prompts() *PromptRegistry {
+	return c.prompts
 }
 
-func (c *Client) EvaluateDeveloper(ctx context.Context, info map[string]interface{}) (*EvaluationResult, error) {
-	prompt := buildEvaluationPrompt(info)
+// CallInfo 一次结构化调用的输入哈希和用量，命中缓存或合并到其他调用时 Usage 为 0
+type CallInfo struct {
+	InputHash string `json:"input_hash"`
+	Cached    bool   `json:"cached"`
+	Usage     Usage  `json:"usage"`
+}
+
+// EvaluationResult 技术能力评估结果
+type EvaluationResult struct {
+	CallInfo
+	Specialties   []string          `json:"specialties"`
+	Experience    map[string]string `json:"experience"`
+	AIEvaluation  string            `json:"evaluation"`
+	Reasons       []string          `json:"reasons"`        // 评价依据
+	PromptVersion string            `json:"prompt_version"` // 使用的提示词模板版本
+	LastEvaluated time.Time         `json:"last_evaluated"`
+	Consensus     *models.Consensus `json:"consensus,omitempty"` // 多个评估配置时各自的结论和一致程度
+	// 资料中疑似提示词注入的内容，只作记录，评估照常进行
+	InjectionWarnings []models.InjectionWarning `json:"injection_warnings,omitempty"`
+}
+
+// NationResult 国家推断结果
+type NationResult struct {
+	CallInfo
+	Nation        string            `json:"nation"`
+	Confidence    float64           `json:"confidence"`
+	Reasons       []string          `json:"reasons"`
+	PromptVersion string            `json:"prompt_version"`
+	Consensus     *models.Consensus `json:"consensus,omitempty"`
+	// 资料中疑似提示词注入的内容
+	InjectionWarnings []models.InjectionWarning `json:"injection_warnings,omitempty"`
+}
+
+// SummaryResult 开发者概括
+type SummaryResult struct {
+	CallInfo
+	Summary       string `json:"summary"`
+	PromptVersion string `json:"prompt_version"`
+}
 
-	request := ChatRequest{
-		Model: c.model,
-		Messages: []Message{
-			{
-				Role:    "system",
-				Content: "你是一个专业的技术人才评估专家。请根据提供的信息评估开发者的技术能力，并以 JSON 格式返回评估结果。",
-			},
-			{
-				Role:    "user",
-				Content: prompt,
-			},
-		},
-		Stream: false,
+// EvaluateDeveloper 评估开发者的技术能力，配置了多个评估配置时汇总各自的结论
+func (c *Client) EvaluateDeveloper(ctx context.Context, profile *DeveloperProfile) (*EvaluationResult, error) {
+	if len(c.consensus.Evaluators) > 1 {
+		return c.evaluateConsensus(ctx, profile)
 	}
+	return c.evaluateWith(ctx, c.primary(), profile)
+}
 
-	reqBody, err := json.Marshal(request)
+func (c *Client) evaluateWith(ctx context.Context, evaluator *Evaluator, profile *DeveloperProfile) (*EvaluationResult, error) {
+	prompt, err := c.render(evaluator.prompt(PromptEvaluation), &PromptData{DeveloperProfile: profile})
 	if err != nil {
-		return nil, fmt.Errorf("failed to marshal request: %v", err)
+		return nil, err
 	}
 
-	req, err := http.NewRequestWithContext(ctx, "POST", c.apiBaseURL, bytes.NewBuffer(reqBody))
+	var result EvaluationResult
+	format := &ResponseFormat{Name: "developer_evaluation", Description: "开发者技术能力的评估结果", Schema: EvaluationSchema}
+	call, err := c.complete(ctx, evaluator, prompt, format, profile.Username, nil, &result)
 	if err != nil {
-		return nil, fmt.Errorf("failed to create request: %v", err)
+		return nil, err
+	}
+	result.CallInfo = *call
+	if result.Experience == nil {
+		result.Experience = make(map[string]string)
 	}
+	result.PromptVersion = prompt.Version
+	result.InjectionWarnings = prompt.Warnings
+	result.LastEvaluated = time.Now()
+	return &result, nil
+}
 
-	req.Header.Set("Content-Type", "application/json")
-	req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", c.apiKey))
+// PredictNation 推断开发者所在的国家，无法判断时 Nation 为空；配置了多个评估配置时按置信度加权投票
+func (c *Client) PredictNation(ctx context.Context, profile *DeveloperProfile) (*NationResult, error) {
+	if len(c.consensus.Evaluators) > 1 {
+		return c.predictNationConsensus(ctx, profile)
+	}
+	return c.predictNationWith(ctx, c.primary(), profile)
+}
 
-	log.Printf("Sending request to DeepSeek API with URL: %s", c.apiBaseURL)
-	log.Printf("Request headers: %+v", req.Header)
+func (c *Client) predictNationWith(ctx context.Context, evaluator *Evaluator, profile *DeveloperProfile) (*NationResult, error) {
+	prompt, err := c.render(PromptNation, &PromptData{DeveloperProfile: profile})
+	if err != nil {
+		return nil, err
+	}
+
+	var result NationResult
+	format := &ResponseFormat{Name: "nation_prediction", Description: "开发者所在国家的推断结果", Schema: NationSchema}
+	call, err := c.complete(ctx, evaluator, prompt, format, profile.Username, normalizeNation, &result)
+	if err != nil {
+		return nil, err
+	}
+	result.CallInfo = *call
+	result.PromptVersion = prompt.Version
+	result.InjectionWarnings = prompt.Warnings
+	return &result, nil
+}
 
-	resp, err := http.DefaultClient.Do(req)
+// Summarize 生成面向招聘人员的概括，evaluation 可以为 nil
+func (c *Client) Summarize(ctx context.Context, profile *DeveloperProfile, evaluation *EvaluationResult) (*SummaryResult, error) {
+	prompt, err := c.render(PromptSummary, &PromptData{DeveloperProfile: profile, Evaluation: evaluation})
 	if err != nil {
-		return nil, fmt.Errorf("failed to send request: %v", err)
+		return nil, err
 	}
-	defer resp.Body.Close()
 
-	log.Printf("DeepSeek API response status: %d", resp.StatusCode)
+	var result SummaryResult
+	format := &ResponseFormat{Name: "developer_summary", Description: "开发者概括", Schema: SummarySchema}
+	call, err := c.complete(ctx, c.primary(), prompt, format, profile.Username, nil, &result)
+	if err != nil {
+		return nil, err
+	}
+	result.CallInfo = *call
+	result.PromptVersion = prompt.Version
+	return &result, nil
+}
 
-	if resp.StatusCode != http.StatusOK {
-		body, _ := io.ReadAll(resp.Body)
-		return nil, fmt.Errorf("DeepSeek API returned status code %d: %s", resp.StatusCode, string(body))
+func (c *Client) render(name string, data *PromptData) (*RenderedPrompt, error) {
+	t, err := c.prompts.Get(name)
+	if err != nil {
+		return nil, err
 	}
+	return t.Render(data)
+}
+
+// primary 客户端默认的评估配置：默认后端、后端默认温度
+func (c *Client) primary() *Evaluator {
+	return &Evaluator{Name: c.provider.Name() + ":" + c.provider.Model(), provider: c.provider}
+}
 
-	var chatResp ChatResponse
-	if err := json.NewDecoder(resp.Body).Decode(&chatResp); err != nil {
-		return nil, fmt.Errorf("failed to decode response: %v", err)
+// complete 执行一次结构化调用：先查缓存，同一输入正在调用时等待其结果，否则调用模型并缓存通过校验的输出
+func (c *Client) complete(ctx context.Context, evaluator *Evaluator, prompt *RenderedPrompt, format *ResponseFormat, username string, normalize func(map[string]interface{}), out interface{}) (*CallInfo, error) {
+	provider := evaluator.provider
+	request := &ChatRequest{Messages: prompt.Messages(), Format: format, Temperature: evaluator.Temperature}
+	info := &CallInfo{InputHash: cacheKey(provider, prompt.Version, request)}
+	usage := models.AIUsage{
+		Operation:     prompt.Name,
+		PromptVersion: prompt.Version,
+		Provider:      provider.Name(),
+		Model:         provider.Model(),
+		Username:      username,
 	}
 
-	if len(chatResp.Choices) == 0 {
-		return nil, fmt.Errorf("no response from DeepSeek API")
+	if cached := c.cachedResult(info.InputHash); cached != nil {
+		if err := decodeStructured(cached.Content, format.Schema, normalize, out); err == nil {
+			log.Printf("AI cache hit for %s %s (%s)", prompt.Name, username, info.InputHash[:12])
+			info.Cached = true
+			usage.Cached = true
+			c.recordUsage(usage)
+			return info, nil
+		}
 	}
 
-	log.Printf("DeepSeek API Response: %s", chatResp.Choices[0].Message.Content)
+	content, shared, err := c.inflight.do(info.InputHash, func() (string, error) {
+		record := func(resp *ChatResponse, repair bool) {
+			info.Usage.PromptTokens += resp.Usage.PromptTokens
+			info.Usage.CompletionTokens += resp.Usage.CompletionTokens
+			info.Usage.TotalTokens += resp.Usage.TotalTokens
 
-	result, err := parseAIResponse(chatResp.Choices[0].Message.Content)
+			u := usage
+			if resp.Model != "" {
+				u.Model = resp.Model
+			}
+			u.Repair = repair
+			u.PromptTokens = resp.Usage.PromptTokens
+			u.CompletionTokens = resp.Usage.CompletionTokens
+			u.TotalTokens = resp.Usage.TotalTokens
+			u.Cost = c.cost(provider, u.Model, resp.Usage)
+			c.recordUsage(u)
+		}
+		content, err := c.chatStructured(ctx, provider, request, normalize, out, record)
+		if err == nil {
+			c.saveResult(info.InputHash, provider, prompt, content)
+		}
+		return content, err
+	})
 	if err != nil {
-		return nil, fmt.Errorf("failed to parse AI response: %v", err)
-	}
-
-	return result, nil
-}
-
-func buildEvaluationPrompt(info map[string]interface{}) string {
-	return fmt.Sprintf(`请根据以下信息评估该开发者的技术能力，重点分析其可能所在的国家/地区。请特别关注：
-1. 用户名特征
-2. 代码注释语言
-3. 项目描述语言
-4. 技术栈特点
-5. 活跃时间规律
-
-开发者信息：
-- 用户名：%v
-- 名称：%v
-- 邮箱：%v
-- 位置：%v
-- GitHub主页：%v
-- 技术栈：%v
-- 编程语言：%v
-- 主要仓库：%v
-- 提交数：%v
-- Stars数：%v
-- Forks数：%v
-- 最近活跃：%v
-
-请以JSON格式返回以下信息：
-{
-    "nation": "CN",  // 两位国家代码：CN中国,US美国,JP日本,KR韩国,SG新加坡等
-    "confidence": 85, // 置信度0-100
-    "reasons": ["原因1", "原因2"], // 判断依据
-    "specialties": ["专长1", "专长2"],
-    "evaluation": "整体评价"
-}`,
-		info["username"], info["name"], info["email"], info["location"],
-		info["profile_url"], info["skills"], info["languages"],
-		info["repos"], info["commits"], info["stars"],
-		info["forks"], info["last_active"])
+		return nil, err
+	}
+	if shared {
+		if err := decodeStructured(content, format.Schema, normalize, out); err != nil {
+			return nil, fmt.Errorf("failed to parse AI response: %w", err)
+		}
+		info.Cached = true
+		usage.Cached = true
+		c.recordUsage(usage)
+	}
+	return info, nil
 }
 
-type EvaluationResult struct {
-	Specialties   []string          `json:"specialties"`
-	Experience    map[string]string `json:"experience"`
-	AIEvaluation  string            `json:"evaluation"`
-	Nation        string            `json:"nation"`
-	Confidence    float64           `json:"confidence"`
-	LastEvaluated time.Time         `json:"last_evaluated"`
-}
+// chatStructured 请求结构化输出并按 Schema 校验，不符合时把错误发回给模型修正一次
+// 返回通过校验的输出，record 在每次模型返回后调用
+func (c *Client) chatStructured(ctx context.Context, provider Provider, request *ChatRequest, normalize func(map[string]interface{}), out interface{}, record func(resp *ChatResponse, repair bool)) (string, error) {
+	resp, err := provider.Chat(ctx, request)
+	if err != nil {
+		return "", fmt.Errorf("%s 调用失败: %v", provider.Name(), err)
+	}
+	record(resp, false)
+	log.Printf("AI response from %s (%s): %s", provider.Name(), resp.Model, resp.Content)
 
-func parseAIResponse(response string) (*EvaluationResult, error) {
-	log.Printf("Raw AI response: %s", response)
+	err = decodeStructured(resp.Content, request.Format.Schema, normalize, out)
+	if err == nil {
+		return resp.Content, nil
+	}
+	log.Printf("AI 输出校验失败，请求模型修正: %v", err)
 
-	cleanedResponse := response
-	if strings.Contains(response, "```json") {
-		start := strings.Index(response, "```json\n") + 8
-		end := strings.Index(response[start:], "```")
-		if end != -1 {
-			cleanedResponse = response[start : start+end]
-		}
+	repair := *request
+	repair.Messages = append(append([]Message{}, request.Messages...),
+		Message{Role: "assistant", Content: resp.Content},
+		Message{Role: "user", Content: repairPrompt(err)},
+	)
+	resp, err = provider.Chat(ctx, &repair)
+	if err != nil {
+		return "", fmt.Errorf("%s 调用失败: %v", provider.Name(), err)
 	}
+	record(resp, true)
+	log.Printf("AI repaired response from %s (%s): %s", provider.Name(), resp.Model, resp.Content)
 
-	// 定义一个临时结构体来解析完整的响应
-	var temp struct {
-		Nation      string   `json:"nation"`
-		Confidence  float64  `json:"confidence"`
-		Reasons     []string `json:"reasons"`
-		Specialties []string `json:"specialties"`
-		Evaluation  string   `json:"evaluation"`
+	if err := decodeStructured(resp.Content, request.Format.Schema, normalize, out); err != nil {
+		return "", fmt.Errorf("failed to parse AI response: %w", err)
 	}
+	return resp.Content, nil
+}
+
+func (c *Client) cachedResult(key string) *models.AIResult {
+	if c.store == nil || !c.cache.Enabled {
+		return nil
+	}
+	result, err := c.store.FindResult(key)
+	if err != nil {
+		log.Printf("Warning: 读取 AI 输出缓存失败: %v", err)
+		return nil
+	}
+	return result
+}
+
+func (c *Client) saveResult(key string, provider Provider, prompt *RenderedPrompt, content string) {
+	if c.store == nil || !c.cache.Enabled {
+		return
+	}
+	result := &models.AIResult{
+		Key:           key,
+		Operation:     prompt.Name,
+		PromptVersion: prompt.Version,
+		Provider:      provider.Name(),
+		Model:         provider.Model(),
+		Content:       content,
+	}
+	if c.cache.TTL > 0 {
+		expiresAt := time.Now().Add(c.cache.TTL)
+		result.ExpiresAt = &expiresAt
+	}
+	if err := c.store.SaveResult(result); err != nil {
+		log.Printf("Warning: 保存 AI 输出缓存失败: %v", err)
+	}
+}
 
-	if err := json.Unmarshal([]byte(cleanedResponse), &temp); err != nil {
-		log.Printf("Error parsing AI response: %v", err)
-		return nil, fmt.Errorf("解析 AI 响应失败: %v", err)
+func (c *Client) recordUsage(usage models.AIUsage) {
+	if c.store == nil {
+		return
 	}
+	if err := c.store.RecordUsage(&usage); err != nil {
+		log.Printf("Warning: 记录 AI 调用用量失败: %v", err)
+	}
+}
 
-	// 转换为 EvaluationResult
-	result := &EvaluationResult{
-		Specialties:   temp.Specialties,
-		Experience:    make(map[string]string),
-		AIEvaluation:  temp.Evaluation,
-		Nation:        temp.Nation,
-		Confidence:    temp.Confidence,
-		LastEvaluated: time.Now(),
+// cost 按模型单价计算费用，返回的模型名带版本后缀时使用配置的模型名
+func (c *Client) cost(provider Provider, model string, usage Usage) float64 {
+	if price, ok := c.prices[model]; ok {
+		return price.Cost(usage)
 	}
+	if price, ok := c.prices[provider.Model()]; ok {
+		return price.Cost(usage)
+	}
+	return 0
+}
 
-	return result, nil
+// normalizeNation 国家代码统一为大写，常见的未知写法视为空
+func normalizeNation(obj map[string]interface{}) {
+	nation, ok := obj["nation"].(string)
+	if !ok {
+		return
+	}
+	nation = strings.ToUpper(strings.TrimSpace(nation))
+	switch nation {
+	case "UNKNOWN", "N/A", "NONE", "NULL", "未知":
+		nation = ""
+	}
+	obj["nation"] = nation
 }
@@ -0,0 +1,384 @@
+package ai
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"math"
+	"os"
+	"qinniu/internal/models"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// 一致程度低于该值时标记为需要人工复核
+const defaultMinAgreement = 0.5
+
+// Evaluator 一个评估配置：后端和模型、温度、评估模板
+type Evaluator struct {
+	Name        string // 如 openai:deepseek-chat@0.7#evaluation
+	provider    Provider
+	Temperature *float64 // 为 nil 时使用后端默认值
+	Prompt      string   // 评估使用的模板，为空时使用 evaluation；国家推断总是使用 nation
+}
+
+func (e *Evaluator) prompt(defaultName string) string {
+	if e.Prompt != "" {
+		return e.Prompt
+	}
+	return defaultName
+}
+
+// ConsensusConfig 多个评估配置取多数意见
+type ConsensusConfig struct {
+	Evaluators   []*Evaluator
+	MinAgreement float64
+}
+
+// SetConsensus 设置参与共识的评估配置，少于两个时只使用默认后端
+func (c *Client) SetConsensus(cfg ConsensusConfig) {
+	c.consensus = cfg
+}
+
+// LoadConsensusConfig 读取 AI_EVALUATORS 和 AI_CONSENSUS_MIN_AGREEMENT
+// AI_EVALUATORS 为逗号分隔的评估配置，每项格式为 [后端:][模型][@温度][#模板]，如
+// openai:deepseek-chat@0.2,openai:deepseek-chat@0.8#evaluation_strict,ollama:qwen2.5
+// 后端省略时使用 AI_PROVIDER，模型省略时使用该后端的默认模型
+func LoadConsensusConfig(base ProviderConfig) (ConsensusConfig, error) {
+	cfg := ConsensusConfig{MinAgreement: defaultMinAgreement}
+	if v, err := strconv.ParseFloat(os.Getenv("AI_CONSENSUS_MIN_AGREEMENT"), 64); err == nil && v >= 0 && v <= 1 {
+		cfg.MinAgreement = v
+	}
+
+	for _, spec := range strings.Split(os.Getenv("AI_EVALUATORS"), ",") {
+		if spec = strings.TrimSpace(spec); spec == "" {
+			continue
+		}
+		evaluator, err := ParseEvaluator(spec, base)
+		if err != nil {
+			return cfg, err
+		}
+		if _, err := DefaultPrompts().Get(evaluator.prompt(PromptEvaluation)); err != nil {
+			return cfg, fmt.Errorf("评估配置 %s: %v", spec, err)
+		}
+		cfg.Evaluators = append(cfg.Evaluators, evaluator)
+	}
+	return cfg, nil
+}
+
+// ParseEvaluator 解析一个评估配置，格式见 LoadConsensusConfig
+func ParseEvaluator(spec string, base ProviderConfig) (*Evaluator, error) {
+	evaluator := &Evaluator{}
+	rest := spec
+	if i := strings.LastIndex(rest, "#"); i >= 0 {
+		evaluator.Prompt = strings.TrimSpace(rest[i+1:])
+		rest = rest[:i]
+	}
+	if i := strings.LastIndex(rest, "@"); i >= 0 {
+		t, err := strconv.ParseFloat(strings.TrimSpace(rest[i+1:]), 64)
+		if err != nil || t < 0 || t > 2 {
+			return nil, fmt.Errorf("评估配置 %s 的温度无效", spec)
+		}
+		evaluator.Temperature = &t
+		rest = rest[:i]
+	}
+
+	// Ollama 的模型名可能带冒号（如 qwen2.5:7b），只有已知的后端名才作为前缀
+	cfg := base
+	if name, model, ok := strings.Cut(rest, ":"); ok {
+		switch strings.ToLower(name) {
+		case ProviderOpenAI, ProviderOllama, ProviderMock:
+			if strings.ToLower(name) != base.Provider {
+				cfg.BaseURL = ""
+				cfg.Model = ""
+			}
+			cfg.Provider = strings.ToLower(name)
+			rest = model
+		}
+	}
+	if rest = strings.TrimSpace(rest); rest != "" {
+		cfg.Model = rest
+	}
+
+	provider, err := NewProvider(cfg)
+	if err != nil {
+		return nil, fmt.Errorf("评估配置 %s: %v", spec, err)
+	}
+	evaluator.provider = provider
+	evaluator.Name = provider.Name() + ":" + provider.Model()
+	if evaluator.Temperature != nil {
+		evaluator.Name += "@" + strconv.FormatFloat(*evaluator.Temperature, 'f', -1, 64)
+	}
+	if evaluator.Prompt != "" {
+		evaluator.Name += "#" + evaluator.Prompt
+	}
+	return evaluator, nil
+}
+
+// evaluateConsensus 并发执行所有评估配置，专长按多数票汇总
+func (c *Client) evaluateConsensus(ctx context.Context, profile *DeveloperProfile) (*EvaluationResult, error) {
+	evaluators := c.consensus.Evaluators
+	results := make([]*EvaluationResult, len(evaluators))
+	errs := make([]error, len(evaluators))
+
+	var wg sync.WaitGroup
+	for i, evaluator := range evaluators {
+		wg.Add(1)
+		go func(i int, evaluator *Evaluator) {
+			defer wg.Done()
+			results[i], errs[i] = c.evaluateWith(ctx, evaluator, profile)
+		}(i, evaluator)
+	}
+	wg.Wait()
+
+	return aggregateEvaluations(evaluators, results, errs, c.consensus.MinAgreement)
+}
+
+// predictNationConsensus 并发执行所有评估配置，国家按置信度加权投票
+func (c *Client) predictNationConsensus(ctx context.Context, profile *DeveloperProfile) (*NationResult, error) {
+	evaluators := c.consensus.Evaluators
+	results := make([]*NationResult, len(evaluators))
+	errs := make([]error, len(evaluators))
+
+	var wg sync.WaitGroup
+	for i, evaluator := range evaluators {
+		wg.Add(1)
+		go func(i int, evaluator *Evaluator) {
+			defer wg.Done()
+			results[i], errs[i] = c.predictNationWith(ctx, evaluator, profile)
+		}(i, evaluator)
+	}
+	wg.Wait()
+
+	return aggregateNations(evaluators, results, errs, c.consensus.MinAgreement)
+}
+
+// aggregateEvaluations 汇总多次评估：
+//   - 专长保留过半数评估都给出的，没有过半数的专长时保留得票最多的
+//   - 评价、经验和依据取专长与汇总结果最接近的一次评估
+//   - 一致程度为各次评估专长集合两两之间 Jaccard 相似度的平均值
+func aggregateEvaluations(evaluators []*Evaluator, results []*EvaluationResult, errs []error, minAgreement float64) (*EvaluationResult, error) {
+	consensus := &models.Consensus{}
+	var (
+		succeeded []*EvaluationResult
+		hashes    []string
+		lastErr   error
+	)
+	for i, evaluator := range evaluators {
+		run := models.ConsensusRun{Evaluator: evaluator.Name}
+		if errs[i] != nil {
+			run.Error = errs[i].Error()
+			lastErr = errs[i]
+		} else {
+			run.Specialties = results[i].Specialties
+			succeeded = append(succeeded, results[i])
+			hashes = append(hashes, evaluator.Name+"="+results[i].InputHash)
+		}
+		consensus.Runs = append(consensus.Runs, run)
+	}
+	if len(succeeded) == 0 {
+		return nil, fmt.Errorf("所有评估配置都失败: %v", lastErr)
+	}
+
+	sets := make([]map[string]bool, len(succeeded))
+	votes := make(map[string]int)
+	display := make(map[string]string)
+	var order []string
+	for i, result := range succeeded {
+		sets[i] = specialtySet(result.Specialties)
+		for _, specialty := range result.Specialties {
+			key := normalizeSpecialty(specialty)
+			if _, ok := display[key]; !ok {
+				display[key] = specialty
+				order = append(order, key)
+			}
+		}
+		for key := range sets[i] {
+			votes[key]++
+		}
+	}
+	sort.SliceStable(order, func(i, j int) bool { return votes[order[i]] > votes[order[j]] })
+
+	var specialties []string
+	for _, key := range order {
+		if votes[key]*2 > len(succeeded) {
+			specialties = append(specialties, display[key])
+		}
+	}
+	if len(specialties) == 0 && len(order) > 0 {
+		for _, key := range order {
+			if votes[key] == votes[order[0]] {
+				specialties = append(specialties, display[key])
+			}
+		}
+	}
+
+	merged := specialtySet(specialties)
+	best := succeeded[0]
+	bestScore := -1.0
+	for i, result := range succeeded {
+		if score := jaccard(sets[i], merged); score > bestScore {
+			best, bestScore = result, score
+		}
+	}
+
+	result := *best
+	result.Specialties = specialties
+	result.CallInfo = combineCalls(succeeded, hashes)
+	result.PromptVersion = joinVersions(succeeded)
+
+	consensus.Agreement = roundAgreement(meanPairwiseJaccard(sets))
+	consensus.NeedsReview = len(succeeded) < 2 || consensus.Agreement < minAgreement
+	result.Consensus = consensus
+	return &result, nil
+}
+
+// aggregateNations 按置信度加权投票，一致程度为投给胜出国家的比例
+// 置信度为胜出国家的置信度之和除以有效结果数，意见分歧越大置信度越低
+func aggregateNations(evaluators []*Evaluator, results []*NationResult, errs []error, minAgreement float64) (*NationResult, error) {
+	consensus := &models.Consensus{}
+	var (
+		succeeded []*NationResult
+		hashes    []string
+		lastErr   error
+	)
+	for i, evaluator := range evaluators {
+		run := models.ConsensusRun{Evaluator: evaluator.Name}
+		if errs[i] != nil {
+			run.Error = errs[i].Error()
+			lastErr = errs[i]
+		} else {
+			run.Nation = results[i].Nation
+			run.Confidence = results[i].Confidence
+			succeeded = append(succeeded, results[i])
+			hashes = append(hashes, evaluator.Name+"="+results[i].InputHash)
+		}
+		consensus.Runs = append(consensus.Runs, run)
+	}
+	if len(succeeded) == 0 {
+		return nil, fmt.Errorf("所有评估配置都失败: %v", lastErr)
+	}
+
+	scores := make(map[string]float64)
+	counts := make(map[string]int)
+	for _, result := range succeeded {
+		scores[result.Nation] += math.Max(result.Confidence, 1)
+		counts[result.Nation]++
+	}
+	nations := make([]string, 0, len(scores))
+	for nation := range scores {
+		nations = append(nations, nation)
+	}
+	// 得分相同时优先给出了国家的结果
+	sort.Slice(nations, func(i, j int) bool {
+		if scores[nations[i]] != scores[nations[j]] {
+			return scores[nations[i]] > scores[nations[j]]
+		}
+		return nations[i] > nations[j]
+	})
+	winner := nations[0]
+
+	var best *NationResult
+	for _, result := range succeeded {
+		if result.Nation == winner && (best == nil || result.Confidence > best.Confidence) {
+			best = result
+		}
+	}
+
+	result := *best
+	result.Confidence = math.Round(scores[winner] / float64(len(succeeded)))
+	if winner == "" {
+		result.Confidence = 0
+	}
+	result.CallInfo = combineCalls(nationCalls(succeeded), hashes)
+
+	consensus.Agreement = roundAgreement(float64(counts[winner]) / float64(len(succeeded)))
+	consensus.NeedsReview = len(succeeded) < 2 || consensus.Agreement < minAgreement
+	result.Consensus = consensus
+	return &result, nil
+}
+
+func nationCalls(results []*NationResult) []*EvaluationResult {
+	calls := make([]*EvaluationResult, 0, len(results))
+	for _, r := range results {
+		calls = append(calls, &EvaluationResult{CallInfo: r.CallInfo, PromptVersion: r.PromptVersion})
+	}
+	return calls
+}
+
+// combineCalls 汇总多次调用：输入哈希由各次的哈希组合而成，全部命中缓存时才算命中
+func combineCalls(results []*EvaluationResult, hashes []string) CallInfo {
+	sort.Strings(hashes)
+	sum := sha256.Sum256([]byte(strings.Join(hashes, "\n")))
+	info := CallInfo{InputHash: hex.EncodeToString(sum[:]), Cached: true}
+	for _, r := range results {
+		info.Cached = info.Cached && r.Cached
+		info.Usage.PromptTokens += r.Usage.PromptTokens
+		info.Usage.CompletionTokens += r.Usage.CompletionTokens
+		info.Usage.TotalTokens += r.Usage.TotalTokens
+	}
+	return info
+}
+
+// joinVersions 各次评估使用的模板版本，去重后用逗号连接
+func joinVersions(results []*EvaluationResult) string {
+	seen := make(map[string]bool)
+	var versions []string
+	for _, r := range results {
+		if !seen[r.PromptVersion] {
+			seen[r.PromptVersion] = true
+			versions = append(versions, r.PromptVersion)
+		}
+	}
+	sort.Strings(versions)
+	return strings.Join(versions, ",")
+}
+
+// normalizeSpecialty 专长比较时忽略大小写和空白，如 "Go"、"go " 视为相同
+func normalizeSpecialty(s string) string {
+	return strings.ToLower(strings.Join(strings.Fields(s), " "))
+}
+
+func specialtySet(specialties []string) map[string]bool {
+	set := make(map[string]bool, len(specialties))
+	for _, s := range specialties {
+		if key := normalizeSpecialty(s); key != "" {
+			set[key] = true
+		}
+	}
+	return set
+}
+
+func jaccard(a, b map[string]bool) float64 {
+	if len(a) == 0 && len(b) == 0 {
+		return 1
+	}
+	common := 0
+	for key := range a {
+		if b[key] {
+			common++
+		}
+	}
+	return float64(common) / float64(len(a)+len(b)-common)
+}
+
+func meanPairwiseJaccard(sets []map[string]bool) float64 {
+	if len(sets) < 2 {
+		return 0
+	}
+	total, pairs := 0.0, 0
+	for i := 0; i < len(sets); i++ {
+		for j := i + 1; j < len(sets); j++ {
+			total += jaccard(sets[i], sets[j])
+			pairs++
+		}
+	}
+	return total / float64(pairs)
+}
+
+func roundAgreement(v float64) float64 {
+	return math.Round(v*1000) / 1000
+}
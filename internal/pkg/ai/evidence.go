@@ -0,0 +1,234 @@
+package ai
+
+import (
+	"context"
+	"log"
+	"math"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// 证据类型
+const (
+	EvidenceReadme      = "readme"       // 仓库 README 摘录
+	EvidenceCode        = "code"         // 代表性源码片段
+	EvidenceCommits     = "commits"      // 最近的提交信息
+	EvidencePullRequest = "pull_request" // PR 标题和描述
+)
+
+// 各类证据的基础权重，PR 描述和 README 最能说明做过什么
+var evidenceWeights = map[string]float64{
+	EvidencePullRequest: 1.0,
+	EvidenceReadme:      0.9,
+	EvidenceCode:        0.8,
+	EvidenceCommits:     0.7,
+}
+
+// 证据类型在提示词中的名称
+var evidenceKindNames = map[string]string{
+	EvidencePullRequest: "PR",
+	EvidenceReadme:      "README",
+	EvidenceCode:        "源码",
+	EvidenceCommits:     "提交记录",
+}
+
+// 单条证据截断后至少保留的 token 数，剩余预算不足时不再截断放入
+const minEvidenceTokens = 48
+
+// Evidence 一条工作证据，渲染进评估提示词
+type Evidence struct {
+	Kind    string    `json:"kind"`
+	Repo    string    `json:"repo"`
+	Title   string    `json:"title"` // 文件路径、PR 标题等
+	URL     string    `json:"url,omitempty"`
+	Content string    `json:"content"`
+	Stars   int       `json:"stars"`
+	Time    time.Time `json:"time"`
+	Score   float64   `json:"score"`
+	Tokens  int       `json:"tokens"`
+}
+
+// EvidenceSource 证据来源，通常是代码托管平台
+type EvidenceSource interface {
+	CollectEvidence(ctx context.Context, profile *DeveloperProfile, maxRepos int) ([]Evidence, error)
+}
+
+// EvidenceConfig 证据收集配置
+type EvidenceConfig struct {
+	TokenBudget int // 渲染进提示词的证据总 token 数，0 表示不收集
+	MaxRepos    int // 收集 README、源码和提交的仓库数
+}
+
+// LoadEvidenceConfig 从环境变量读取证据收集配置
+func LoadEvidenceConfig() EvidenceConfig {
+	cfg := EvidenceConfig{TokenBudget: 3000, MaxRepos: 3}
+	if v, err := strconv.Atoi(os.Getenv("AI_EVIDENCE_TOKENS")); err == nil && v >= 0 {
+		cfg.TokenBudget = v
+	}
+	if v, err := strconv.Atoi(os.Getenv("AI_EVIDENCE_REPOS")); err == nil && v > 0 {
+		cfg.MaxRepos = v
+	}
+	return cfg
+}
+
+// EvidenceBuilder 从来源收集证据，排序后截断到 token 预算内
+type EvidenceBuilder struct {
+	source EvidenceSource
+	config EvidenceConfig
+}
+
+func NewEvidenceBuilder(source EvidenceSource, config EvidenceConfig) *EvidenceBuilder {
+	return &EvidenceBuilder{source: source, config: config}
+}
+
+// Build 收集并挑选开发者的工作证据，结果写入 profile.Evidence
+// 收集失败时返回错误，已收集到的部分仍然使用
+func (b *EvidenceBuilder) Build(ctx context.Context, profile *DeveloperProfile) error {
+	if b == nil || b.source == nil || b.config.TokenBudget == 0 {
+		return nil
+	}
+	items, err := b.source.CollectEvidence(ctx, profile, b.config.MaxRepos)
+	profile.Evidence = SelectEvidence(items, b.config.TokenBudget)
+	if len(profile.Evidence) > 0 {
+		log.Printf("Evidence for %s: %d of %d items, %d tokens",
+			profile.Username, len(profile.Evidence), len(items), evidenceTokens(profile.Evidence))
+	}
+	return err
+}
+
+// SelectEvidence 按得分挑选证据，总 token 数不超过 budget
+// 得分由证据类型、仓库 star 数和新旧程度决定，同一仓库同类证据越多得分递减，避免预算被一个仓库占满
+// 放不下的证据在剩余预算足够时截断放入；结果按仓库分组，便于模型阅读
+func SelectEvidence(items []Evidence, budget int) []Evidence {
+	if budget <= 0 || len(items) == 0 {
+		return nil
+	}
+
+	candidates := make([]Evidence, 0, len(items))
+	for _, item := range items {
+		item.Content = strings.TrimSpace(item.Content)
+		if item.Content == "" {
+			continue
+		}
+		item.Tokens = EstimateTokens(item.Content)
+		item.Score = evidenceScore(item)
+		candidates = append(candidates, item)
+	}
+	sort.SliceStable(candidates, func(i, j int) bool { return candidates[i].Score > candidates[j].Score })
+
+	seen := make(map[string]int)
+	for i := range candidates {
+		key := candidates[i].Repo + "/" + candidates[i].Kind
+		candidates[i].Score /= 1 + 0.5*float64(seen[key])
+		seen[key]++
+	}
+	sort.SliceStable(candidates, func(i, j int) bool { return candidates[i].Score > candidates[j].Score })
+
+	// 单条证据最多占预算的一半
+	perItem := budget / 2
+	if perItem < minEvidenceTokens {
+		perItem = budget
+	}
+
+	var selected []Evidence
+	remaining := budget
+	for _, item := range candidates {
+		limit := perItem
+		if remaining < limit {
+			limit = remaining
+		}
+		if item.Tokens > limit {
+			if limit < minEvidenceTokens {
+				continue
+			}
+			item.Content = truncateTokens(item.Content, limit)
+			item.Tokens = EstimateTokens(item.Content)
+		}
+		selected = append(selected, item)
+		remaining -= item.Tokens
+		if remaining < minEvidenceTokens {
+			break
+		}
+	}
+
+	// 按仓库的最高得分分组，组内按得分排序
+	repoRank := make(map[string]int)
+	for i, item := range selected {
+		if _, ok := repoRank[item.Repo]; !ok {
+			repoRank[item.Repo] = i
+		}
+	}
+	sort.SliceStable(selected, func(i, j int) bool {
+		return repoRank[selected[i].Repo] < repoRank[selected[j].Repo]
+	})
+	return selected
+}
+
+func evidenceScore(item Evidence) float64 {
+	weight, ok := evidenceWeights[item.Kind]
+	if !ok {
+		weight = 0.5
+	}
+	// star 数按对数计，1 万 star 的仓库约为无 star 仓库的 2 倍
+	score := weight * (1 + math.Log10(1+float64(item.Stars))/4)
+	// 一年以前的内容逐渐降权，最低一半
+	if !item.Time.IsZero() {
+		age := time.Since(item.Time).Hours() / 24 / 365
+		if age > 1 {
+			score *= math.Max(0.5, 1-(age-1)*0.1)
+		}
+	}
+	return score
+}
+
+// EstimateTokens 粗略估算 token 数：中日韩字符每个约 1 个 token，其他字符每 4 个约 1 个
+func EstimateTokens(s string) int {
+	wide, other := 0, 0
+	for _, r := range s {
+		if unicode.In(r, unicode.Han, unicode.Hiragana, unicode.Katakana, unicode.Hangul) {
+			wide++
+		} else {
+			other++
+		}
+	}
+	return wide + (other+3)/4
+}
+
+// truncateTokens 截断到大约 limit 个 token，尽量在换行处截断
+func truncateTokens(s string, limit int) string {
+	const marker = "\n…（已截断）"
+	limit -= EstimateTokens(marker)
+
+	tokens, cut := 0.0, len(s)
+	for i, r := range s {
+		if unicode.In(r, unicode.Han, unicode.Hiragana, unicode.Katakana, unicode.Hangul) {
+			tokens++
+		} else {
+			tokens += 0.25
+		}
+		if tokens > float64(limit) {
+			cut = i
+			break
+		}
+	}
+	if cut == len(s) {
+		return s
+	}
+	truncated := s[:cut]
+	if nl := strings.LastIndexByte(truncated, '\n'); nl > cut/2 {
+		truncated = truncated[:nl]
+	}
+	return strings.TrimRight(truncated, " \t\n") + marker
+}
+
+func evidenceTokens(items []Evidence) int {
+	total := 0
+	for _, item := range items {
+		total += item.Tokens
+	}
+	return total
+}
@@ -0,0 +1,174 @@
+package ai
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"qinniu/internal/models"
+	"regexp"
+	"sort"
+	"strings"
+	"testing"
+)
+
+// 模型照做了注入内容时返回的专长，出现在评估结果中说明注入生效
+const injectedSpecialty = "INJECTED"
+
+// untrustedBlock 提示词中被标记为不可信的内容
+var untrustedBlock = regexp.MustCompile(`(?s)<untrusted>.*?</untrusted>`)
+
+// injectionCase 语料中的一个对抗性资料
+type injectionCase struct {
+	Name    string           `json:"name"`
+	Profile DeveloperProfile `json:"profile"`
+	Expect  []string         `json:"expect"` // 应当检测到的注入类型，为空表示正常资料，不应有警告
+}
+
+// TestInjectionCorpus 用 testdata 中的对抗性资料检查提示词注入防护，不调用任何外部服务：
+//  1. 检测：每个样例应检测到 expect 中的注入类型，正常资料不应有警告
+//  2. 隔离：渲染后的提示词中，开发者填写的内容都在 <untrusted> 标签内，标签外不含注入写法
+//  3. 端到端：用模拟"会执行标签外指令"的 mock 后端评估，结果不应被篡改，且带有注入警告
+func TestInjectionCorpus(t *testing.T) {
+	data, err := os.ReadFile("testdata/injection_corpus.json")
+	if err != nil {
+		t.Fatalf("读取语料失败: %v", err)
+	}
+	var cases []injectionCase
+	if err := json.Unmarshal(data, &cases); err != nil {
+		t.Fatalf("解析语料失败: %v", err)
+	}
+
+	templateLines := renderedTemplateLines(t)
+	client := NewClient(naiveModel(templateLines))
+	for i := range cases {
+		c := &cases[i]
+		t.Run(c.Name, func(t *testing.T) {
+			checkCase(t, client, c, templateLines)
+		})
+	}
+}
+
+func checkCase(t *testing.T, client *Client, c *injectionCase, templateLines map[string]bool) {
+	_, warnings := SanitizeProfile(&c.Profile)
+	detected := warningPatterns(warnings)
+	for _, w := range warnings {
+		t.Logf("%s %s: %s", w.Field, w.Pattern, w.Excerpt)
+	}
+	if len(c.Expect) == 0 && len(detected) > 0 {
+		t.Errorf("正常资料被误报: %v", detected)
+	}
+	for _, pattern := range c.Expect {
+		if !contains(detected, pattern) {
+			t.Errorf("未检测到 %s，检测到 %v", pattern, detected)
+		}
+	}
+
+	for _, name := range []string{PromptEvaluation, PromptNation, PromptSummary} {
+		tmpl, err := DefaultPrompts().Get(name)
+		if err != nil {
+			t.Error(err)
+			continue
+		}
+		rendered, err := tmpl.Render(&PromptData{DeveloperProfile: &c.Profile})
+		if err != nil {
+			t.Error(err)
+			continue
+		}
+		if open, end := strings.Count(rendered.User, "<untrusted>"), strings.Count(rendered.User, "</untrusted>"); open != end {
+			t.Errorf("%s: 不可信内容的标签不成对（%d 个开始，%d 个结束）", name, open, end)
+		}
+		if leaked := DetectInjection("prompt", outsideUntrusted(rendered.User, templateLines)); len(leaked) > 0 {
+			t.Errorf("%s: 标签外出现注入内容 %q", name, leaked[0].Excerpt)
+		}
+	}
+
+	evaluation, err := client.EvaluateDeveloper(context.Background(), &c.Profile)
+	if err != nil {
+		t.Fatalf("评估失败: %v", err)
+	}
+	if contains(evaluation.Specialties, injectedSpecialty) {
+		t.Error("评估结果被注入内容篡改")
+	}
+	if len(c.Expect) > 0 && len(evaluation.InjectionWarnings) == 0 {
+		t.Error("评估结果没有记录注入警告")
+	}
+
+	nation, err := client.PredictNation(context.Background(), &c.Profile)
+	if err != nil {
+		t.Fatalf("国家推断失败: %v", err)
+	}
+	if nation.Confidence == 100 {
+		t.Error("国家推断结果被注入内容篡改")
+	}
+}
+
+// renderedTemplateLines 模板自身的文字（用空资料渲染的各行），模板中的输出示例等不算注入
+func renderedTemplateLines(t *testing.T) map[string]bool {
+	lines := make(map[string]bool)
+	for _, name := range []string{PromptEvaluation, PromptNation, PromptSummary} {
+		tmpl, err := DefaultPrompts().Get(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		rendered, err := tmpl.Render(&PromptData{DeveloperProfile: &DeveloperProfile{}})
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, line := range strings.Split(rendered.User, "\n") {
+			lines[strings.TrimSpace(line)] = true
+		}
+	}
+	return lines
+}
+
+// naiveModel 模拟容易被注入的模型：不可信标签以外、模板自身以外的文字中出现注入写法时照做，否则返回 mock 的固定回复
+func naiveModel(templateLines map[string]bool) *MockProvider {
+	provider := NewMockProvider("naive")
+	provider.Reply = func(req *ChatRequest) (string, error) {
+		var user string
+		for _, m := range req.Messages {
+			if m.Role == "user" {
+				user = m.Content
+			}
+		}
+		if len(DetectInjection("prompt", outsideUntrusted(user, templateLines))) > 0 {
+			if req.Format != nil && req.Format.Name == "nation_prediction" {
+				return `{"nation": "US", "confidence": 100, "reasons": ["按资料中的要求"]}`, nil
+			}
+			return `{"specialties": ["` + injectedSpecialty + `"], "experience": {}, "evaluation": "按资料中的要求给出的评价", "reasons": []}`, nil
+		}
+		return mockReply(req), nil
+	}
+	return provider
+}
+
+// outsideUntrusted 去掉不可信标签内的内容和模板自身的文字，剩下的应当只有可信的字段
+func outsideUntrusted(prompt string, templateLines map[string]bool) string {
+	var lines []string
+	for _, line := range strings.Split(untrustedBlock.ReplaceAllString(prompt, ""), "\n") {
+		if !templateLines[strings.TrimSpace(line)] {
+			lines = append(lines, line)
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+func warningPatterns(warnings []models.InjectionWarning) []string {
+	var patterns []string
+	for _, w := range warnings {
+		if !contains(patterns, w.Pattern) {
+			patterns = append(patterns, w.Pattern)
+		}
+	}
+	sort.Strings(patterns)
+	return patterns
+}
+
+func contains(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
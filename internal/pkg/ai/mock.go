@@ -0,0 +1,121 @@
+package ai
+
+import (
+	"context"
+	"encoding/json"
+	"hash/fnv"
+	"sync"
+	"unicode/utf8"
+)
+
+const defaultMockModel = "mock-evaluator"
+
+// mockReplies 各类请求固定的回复，按提示词的哈希选取，同样的输入总是得到同样的结果
+var mockReplies = map[string][]map[string]interface{}{
+	"developer_evaluation": {
+		{
+			"specialties": []string{"Go", "分布式系统"},
+			"experience":  map[string]string{"Go": "资深"},
+			"evaluation":  "后端工程经验扎实，长期维护分布式系统相关项目。",
+			"reasons":     []string{"主要仓库为 Go 编写的分布式组件"},
+		},
+		{
+			"specialties": []string{"TypeScript", "前端工程"},
+			"experience":  map[string]string{"TypeScript": "熟练"},
+			"evaluation":  "前端工程化能力较强，维护多个被广泛使用的组件库。",
+			"reasons":     []string{"组件库仓库 star 数较高"},
+		},
+		{
+			"specialties": []string{"Rust", "系统编程"},
+			"experience":  map[string]string{"Rust": "熟练"},
+			"evaluation":  "专注系统编程，代码质量较高。",
+			"reasons":     []string{"仓库以 Rust 系统工具为主"},
+		},
+	},
+	"nation_prediction": {
+		{"nation": "CN", "confidence": 82, "reasons": []string{"位置信息为中国城市", "项目描述使用中文"}},
+		{"nation": "US", "confidence": 74, "reasons": []string{"位置信息为美国城市"}},
+		{"nation": "", "confidence": 20, "reasons": []string{"信息不足"}},
+	},
+	"developer_summary": {
+		{"summary": "资深后端工程师，擅长分布式系统。"},
+		{"summary": "前端工程专家，维护多个流行组件库。"},
+	},
+}
+
+// MockProvider 不调用任何外部服务的后端，用于测试和本地开发
+type MockProvider struct {
+	model string
+	// Reply 不为 nil 时用它生成回复，否则按请求的输出格式返回固定回复
+	Reply func(req *ChatRequest) (string, error)
+
+	mu    sync.Mutex
+	calls int
+}
+
+func NewMockProvider(model string) *MockProvider {
+	if model == "" {
+		model = defaultMockModel
+	}
+	return &MockProvider{model: model}
+}
+
+func (p *MockProvider) Name() string  { return ProviderMock }
+func (p *MockProvider) Model() string { return p.model }
+
+// Calls 已经处理的请求数
+func (p *MockProvider) Calls() int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.calls
+}
+
+func (p *MockProvider) Chat(ctx context.Context, req *ChatRequest) (*ChatResponse, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	p.mu.Lock()
+	p.calls++
+	p.mu.Unlock()
+
+	var content string
+	if p.Reply != nil {
+		reply, err := p.Reply(req)
+		if err != nil {
+			return nil, err
+		}
+		content = reply
+	} else {
+		content = mockReply(req)
+	}
+
+	// 按每 4 个字符一个 token 粗略估算用量
+	prompt := 0
+	for _, m := range req.Messages {
+		prompt += utf8.RuneCountInString(m.Content) / 4
+	}
+	completion := utf8.RuneCountInString(content) / 4
+	return &ChatResponse{
+		Content: content,
+		Model:   p.model,
+		Usage:   Usage{PromptTokens: prompt, CompletionTokens: completion, TotalTokens: prompt + completion},
+	}, nil
+}
+
+// mockReply 按请求的输出格式返回固定回复，没有指定格式时返回评估结果
+func mockReply(req *ChatRequest) string {
+	name := "developer_evaluation"
+	if req.Format != nil {
+		if _, ok := mockReplies[req.Format.Name]; ok {
+			name = req.Format.Name
+		}
+	}
+	replies := mockReplies[name]
+
+	h := fnv.New32a()
+	for _, m := range req.Messages {
+		h.Write([]byte(m.Content))
+	}
+	data, _ := json.Marshal(replies[h.Sum32()%uint32(len(replies))])
+	return string(data)
+}
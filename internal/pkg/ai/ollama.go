@@ -0,0 +1,95 @@
+package ai
+
+import (
+	"context"
+	"log"
+	"net/http"
+)
+
+const (
+	defaultOllamaBaseURL = "http://localhost:11434"
+	defaultOllamaModel   = "qwen2.5"
+)
+
+// OllamaProvider 本地 Ollama 的 /api/chat 接口
+type OllamaProvider struct {
+	baseURL    string
+	model      string
+	httpClient *http.Client
+}
+
+func NewOllamaProvider(baseURL, model string, httpClient *http.Client) *OllamaProvider {
+	if baseURL == "" {
+		baseURL = defaultOllamaBaseURL
+	}
+	if model == "" {
+		model = defaultOllamaModel
+	}
+	if httpClient == nil {
+		httpClient = &http.Client{Timeout: defaultTimeout}
+	}
+	log.Printf("AI provider: ollama, base URL: %s, model: %s", baseURL, model)
+
+	return &OllamaProvider{
+		baseURL:    baseURL,
+		model:      model,
+		httpClient: httpClient,
+	}
+}
+
+func (p *OllamaProvider) Name() string  { return ProviderOllama }
+func (p *OllamaProvider) Model() string { return p.model }
+
+type ollamaOptions struct {
+	Temperature *float64 `json:"temperature,omitempty"`
+	NumPredict  int      `json:"num_predict,omitempty"`
+}
+
+type ollamaRequest struct {
+	Model    string        `json:"model"`
+	Messages []Message     `json:"messages"`
+	Stream   bool          `json:"stream"`
+	Format   interface{}   `json:"format,omitempty"` // "json" 或 JSON Schema
+	Options  ollamaOptions `json:"options"`
+}
+
+type ollamaResponse struct {
+	Model   string  `json:"model"`
+	Message Message `json:"message"`
+	// Ollama 用 prompt_eval_count 和 eval_count 表示输入、输出的 token 数
+	PromptEvalCount int `json:"prompt_eval_count"`
+	EvalCount       int `json:"eval_count"`
+}
+
+func (p *OllamaProvider) Chat(ctx context.Context, req *ChatRequest) (*ChatResponse, error) {
+	body := ollamaRequest{
+		Model:    p.model,
+		Messages: req.Messages,
+		Options: ollamaOptions{
+			Temperature: req.Temperature,
+			NumPredict:  req.MaxTokens,
+		},
+	}
+	if req.Format != nil {
+		body.Format = req.Format.Schema
+	}
+
+	var resp ollamaResponse
+	if err := postJSON(ctx, p.httpClient, p.baseURL+"/api/chat", "", body, &resp); err != nil {
+		return nil, err
+	}
+
+	model := resp.Model
+	if model == "" {
+		model = p.model
+	}
+	return &ChatResponse{
+		Content: resp.Message.Content,
+		Model:   model,
+		Usage: Usage{
+			PromptTokens:     resp.PromptEvalCount,
+			CompletionTokens: resp.EvalCount,
+			TotalTokens:      resp.PromptEvalCount + resp.EvalCount,
+		},
+	}, nil
+}
@@ -0,0 +1,190 @@
+package ai
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+)
+
+const (
+	defaultOpenAIBaseURL = "https://api.deepseek.com"
+	defaultOpenAIModel   = "deepseek-chat"
+)
+
+// OpenAIProvider OpenAI 兼容的 /chat/completions 接口
+type OpenAIProvider struct {
+	baseURL    string
+	apiKey     string
+	model      string
+	format     string
+	httpClient *http.Client
+}
+
+// NewOpenAIProvider 未指定地址和模型时使用 DeepSeek
+func NewOpenAIProvider(baseURL, apiKey, model string, httpClient *http.Client) *OpenAIProvider {
+	if baseURL == "" {
+		baseURL = defaultOpenAIBaseURL
+	}
+	if model == "" {
+		model = defaultOpenAIModel
+	}
+	if httpClient == nil {
+		httpClient = &http.Client{Timeout: defaultTimeout}
+	}
+	log.Printf("AI provider: openai, base URL: %s, model: %s, key: %s", baseURL, model, MaskKey(apiKey))
+
+	return &OpenAIProvider{
+		baseURL:    baseURL,
+		apiKey:     apiKey,
+		model:      model,
+		format:     FormatJSONObject,
+		httpClient: httpClient,
+	}
+}
+
+// SetResponseFormat 设置请求结构化输出时使用的方式，取决于接口支持哪种
+func (p *OpenAIProvider) SetResponseFormat(format string) {
+	p.format = format
+}
+
+func (p *OpenAIProvider) Name() string  { return ProviderOpenAI }
+func (p *OpenAIProvider) Model() string { return p.model }
+
+type openAIRequest struct {
+	Model          string                 `json:"model"`
+	Messages       []Message              `json:"messages"`
+	Stream         bool                   `json:"stream"`
+	Temperature    *float64               `json:"temperature,omitempty"`
+	MaxTokens      int                    `json:"max_tokens,omitempty"`
+	ResponseFormat map[string]interface{} `json:"response_format,omitempty"`
+	Tools          []openAITool           `json:"tools,omitempty"`
+	ToolChoice     interface{}            `json:"tool_choice,omitempty"`
+}
+
+type openAIFunction struct {
+	Name        string `json:"name"`
+	Description string `json:"description,omitempty"`
+	Parameters  Schema `json:"parameters,omitempty"`
+	Arguments   string `json:"arguments,omitempty"`
+}
+
+type openAITool struct {
+	Type     string         `json:"type"`
+	Function openAIFunction `json:"function"`
+}
+
+type openAIResponse struct {
+	Model   string `json:"model"`
+	Choices []struct {
+		Message struct {
+			Content   string       `json:"content"`
+			ToolCalls []openAITool `json:"tool_calls"`
+		} `json:"message"`
+	} `json:"choices"`
+	Usage Usage `json:"usage"`
+}
+
+func (p *OpenAIProvider) Chat(ctx context.Context, req *ChatRequest) (*ChatResponse, error) {
+	body := openAIRequest{
+		Model:       p.model,
+		Messages:    req.Messages,
+		Temperature: req.Temperature,
+		MaxTokens:   req.MaxTokens,
+	}
+	if req.Format != nil {
+		p.applyFormat(&body, req.Format)
+	}
+
+	var resp openAIResponse
+	if err := postJSON(ctx, p.httpClient, p.baseURL+"/chat/completions", p.apiKey, body, &resp); err != nil {
+		return nil, err
+	}
+	if len(resp.Choices) == 0 {
+		return nil, fmt.Errorf("AI 接口没有返回结果")
+	}
+
+	content := resp.Choices[0].Message.Content
+	// 函数调用时结果在参数中
+	for _, call := range resp.Choices[0].Message.ToolCalls {
+		if req.Format != nil && call.Function.Name == req.Format.Name {
+			content = call.Function.Arguments
+			break
+		}
+	}
+
+	model := resp.Model
+	if model == "" {
+		model = p.model
+	}
+	return &ChatResponse{
+		Content: content,
+		Model:   model,
+		Usage:   resp.Usage,
+	}, nil
+}
+
+// applyFormat 按配置的方式要求模型输出结构化结果
+func (p *OpenAIProvider) applyFormat(body *openAIRequest, format *ResponseFormat) {
+	switch p.format {
+	case FormatJSONSchema:
+		body.ResponseFormat = map[string]interface{}{
+			"type": "json_schema",
+			"json_schema": map[string]interface{}{
+				"name":   format.Name,
+				"schema": format.Schema,
+			},
+		}
+	case FormatTools:
+		body.Tools = []openAITool{{
+			Type: "function",
+			Function: openAIFunction{
+				Name:        format.Name,
+				Description: format.Description,
+				Parameters:  format.Schema,
+			},
+		}}
+		body.ToolChoice = map[string]interface{}{
+			"type":     "function",
+			"function": map[string]string{"name": format.Name},
+		}
+	case FormatJSONObject:
+		body.ResponseFormat = map[string]interface{}{"type": "json_object"}
+	}
+}
+
+// postJSON 发送 JSON 请求并解析 JSON 响应，apiKey 为空时不带认证头
+func postJSON(ctx context.Context, client *http.Client, url, apiKey string, body, out interface{}) error {
+	reqBody, err := json.Marshal(body)
+	if err != nil {
+		return fmt.Errorf("failed to marshal request: %v", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(reqBody))
+	if err != nil {
+		return fmt.Errorf("failed to create request: %v", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if apiKey != "" {
+		req.Header.Set("Authorization", "Bearer "+apiKey)
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to send request: %v", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		data, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+		return fmt.Errorf("AI API returned status code %d: %s", resp.StatusCode, string(data))
+	}
+
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return fmt.Errorf("failed to decode response: %v", err)
+	}
+	return nil
+}
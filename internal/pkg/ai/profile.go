@@ -0,0 +1,110 @@
+package ai
+
+import (
+	"net/url"
+	"qinniu/internal/models"
+	"sort"
+	"strings"
+	"time"
+)
+
+// 提示词中最多列出的仓库数
+const maxPromptRepos = 15
+
+// RepoInfo 提示词中的仓库信息
+type RepoInfo struct {
+	Name        string   `json:"name"`
+	Owner       string   `json:"owner,omitempty"` // 为空时是开发者本人的仓库
+	Description string   `json:"description,omitempty"`
+	Topics      []string `json:"topics,omitempty"`
+	Stars       int      `json:"stars"`
+}
+
+// DeveloperProfile 渲染提示词使用的开发者信息
+type DeveloperProfile struct {
+	Username     string     `json:"username"`
+	Name         string     `json:"name,omitempty"`
+	Email        string     `json:"email,omitempty"`
+	Location     string     `json:"location,omitempty"`
+	Bio          string     `json:"bio,omitempty"`
+	Blog         string     `json:"blog,omitempty"`
+	ProfileURL   string     `json:"profile_url,omitempty"`
+	Skills       []string   `json:"skills,omitempty"`
+	Languages    []string   `json:"languages,omitempty"`
+	Repositories []RepoInfo `json:"repositories,omitempty"` // 按 star 数降序
+	Commits      int        `json:"commits"`
+	Stars        int        `json:"stars"`
+	Forks        int        `json:"forks"`
+	LastActive   time.Time  `json:"last_active"`
+	CreatedAt    time.Time  `json:"created_at"`
+	Evidence     []Evidence `json:"evidence,omitempty"` // 工作证据，只用于评估
+}
+
+// ProfileFromDeveloper 从数据库中的开发者构建提示词数据
+func ProfileFromDeveloper(d *models.Developer) *DeveloperProfile {
+	profile := &DeveloperProfile{
+		Username:   d.Username,
+		Name:       d.Name,
+		Email:      d.Email,
+		Location:   d.Location,
+		Bio:        d.Bio,
+		Blog:       d.TechEvaluation.BlogURL,
+		ProfileURL: d.ProfileURL,
+		Skills:     d.Skills,
+		Languages:  d.Skills,
+		Commits:    d.CommitCount,
+		Stars:      d.StarCount,
+		Forks:      d.ForkCount,
+		LastActive: d.LastActive,
+		CreatedAt:  d.CreatedAt,
+	}
+	if profile.Bio == "" {
+		profile.Bio = d.TechEvaluation.Biography
+	}
+
+	// 优先使用带描述的仓库摘要，旧数据只有仓库名和 star 数
+	if len(d.RepoSummaries) > 0 {
+		for _, repo := range d.RepoSummaries {
+			profile.Repositories = append(profile.Repositories, RepoInfo{
+				Name:        repo.Name,
+				Owner:       repoOwner(d.RepositoryURLs[repo.Name]),
+				Description: repo.Description,
+				Topics:      repo.Topics,
+				Stars:       repo.Stars,
+			})
+		}
+	} else {
+		for _, name := range d.Repositories {
+			profile.Repositories = append(profile.Repositories, RepoInfo{
+				Name:  name,
+				Owner: repoOwner(d.RepositoryURLs[name]),
+				Stars: d.RepoStars[name],
+			})
+		}
+	}
+	profile.SortRepositories()
+	return profile
+}
+
+// SortRepositories 仓库按 star 数降序排列，只保留前几个
+func (p *DeveloperProfile) SortRepositories() {
+	sort.SliceStable(p.Repositories, func(i, j int) bool {
+		return p.Repositories[i].Stars > p.Repositories[j].Stars
+	})
+	if len(p.Repositories) > maxPromptRepos {
+		p.Repositories = p.Repositories[:maxPromptRepos]
+	}
+}
+
+// repoOwner 从仓库地址 https://github.com/<owner>/<repo> 中取出所有者
+func repoOwner(repoURL string) string {
+	u, err := url.Parse(repoURL)
+	if err != nil {
+		return ""
+	}
+	parts := strings.Split(strings.Trim(u.Path, "/"), "/")
+	if len(parts) < 2 {
+		return ""
+	}
+	return parts[0]
+}
@@ -0,0 +1,200 @@
+package ai
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"embed"
+	"encoding/hex"
+	"fmt"
+	"io/fs"
+	"log"
+	"os"
+	"path"
+	"qinniu/internal/models"
+	"sort"
+	"strings"
+	"sync"
+	"text/template"
+	"time"
+)
+
+// 提示词模板名，对应 prompts 目录下的 <名称>.tmpl
+const (
+	PromptNation     = "nation"     // 推断所在国家
+	PromptEvaluation = "evaluation" // 技术能力评估
+	PromptSummary    = "summary"    // 面向招聘人员的概括
+)
+
+//go:embed prompts/*.tmpl
+var embeddedPrompts embed.FS
+
+// PromptData 渲染模板的数据，Evaluation 只在生成概括时提供
+type PromptData struct {
+	*DeveloperProfile
+	Evaluation *EvaluationResult
+}
+
+// PromptTemplate 一个提示词模板，文件中用 {{define "system"}} 和 {{define "user"}} 定义两条消息
+type PromptTemplate struct {
+	Name    string `json:"name"`
+	Version string `json:"version"` // 名称加内容哈希，模板内容变化时版本随之变化
+	tmpl    *template.Template
+}
+
+// RenderedPrompt 渲染后的提示词
+type RenderedPrompt struct {
+	Name     string                    `json:"name"`
+	Version  string                    `json:"version"`
+	System   string                    `json:"system"`
+	User     string                    `json:"user"`
+	Warnings []models.InjectionWarning `json:"injection_warnings,omitempty"` // 资料中疑似提示词注入的内容
+}
+
+// Messages 转换为对话消息
+func (r *RenderedPrompt) Messages() []Message {
+	return []Message{
+		{Role: "system", Content: r.System},
+		{Role: "user", Content: r.User},
+	}
+}
+
+// PromptRegistry 提示词模板集合
+type PromptRegistry struct {
+	templates map[string]*PromptTemplate
+}
+
+var promptFuncs = template.FuncMap{
+	"join":      func(list []string, sep string) string { return strings.Join(list, sep) },
+	"untrusted": wrapUntrusted,
+	"inc":       func(i int) int { return i + 1 },
+	"evidenceKind": func(kind string) string {
+		if name, ok := evidenceKindNames[kind]; ok {
+			return name
+		}
+		return kind
+	},
+	"date": func(t time.Time) string {
+		if t.IsZero() {
+			return "未知"
+		}
+		return t.Format("2006-01-02")
+	},
+}
+
+var (
+	defaultPrompts     *PromptRegistry
+	defaultPromptsOnce sync.Once
+)
+
+// DefaultPrompts 默认的模板集合：设置了 AI_PROMPT_DIR 时从该目录加载，否则使用内置模板
+// 目录中缺少的模板使用内置版本
+func DefaultPrompts() *PromptRegistry {
+	defaultPromptsOnce.Do(func() {
+		embedded, err := fs.Sub(embeddedPrompts, "prompts")
+		if err == nil {
+			defaultPrompts, err = LoadPrompts(embedded)
+		}
+		if err != nil {
+			log.Fatalf("加载内置提示词模板失败: %v", err)
+		}
+
+		if dir := os.Getenv("AI_PROMPT_DIR"); dir != "" {
+			custom, err := LoadPrompts(os.DirFS(dir))
+			if err != nil {
+				log.Printf("Warning: 加载 %s 中的提示词模板失败，使用内置模板: %v", dir, err)
+				return
+			}
+			for name, t := range custom.templates {
+				defaultPrompts.templates[name] = t
+			}
+		}
+		for _, t := range defaultPrompts.List() {
+			log.Printf("Prompt template %s version %s", t.Name, t.Version)
+		}
+	})
+	return defaultPrompts
+}
+
+// LoadPrompts 加载目录下所有 .tmpl 文件
+func LoadPrompts(fsys fs.FS) (*PromptRegistry, error) {
+	files, err := fs.Glob(fsys, "*.tmpl")
+	if err != nil {
+		return nil, err
+	}
+
+	registry := &PromptRegistry{templates: make(map[string]*PromptTemplate)}
+	for _, file := range files {
+		content, err := fs.ReadFile(fsys, file)
+		if err != nil {
+			return nil, err
+		}
+		name := strings.TrimSuffix(path.Base(file), ".tmpl")
+		t, err := parsePrompt(name, content)
+		if err != nil {
+			return nil, err
+		}
+		registry.templates[name] = t
+	}
+	return registry, nil
+}
+
+func parsePrompt(name string, content []byte) (*PromptTemplate, error) {
+	tmpl, err := template.New(name).Funcs(promptFuncs).Option("missingkey=error").Parse(string(content))
+	if err != nil {
+		return nil, fmt.Errorf("解析模板 %s 失败: %v", name, err)
+	}
+	for _, part := range []string{"system", "user"} {
+		if tmpl.Lookup(part) == nil {
+			return nil, fmt.Errorf("模板 %s 缺少 {{define \"%s\"}}", name, part)
+		}
+	}
+
+	sum := sha256.Sum256(content)
+	return &PromptTemplate{
+		Name:    name,
+		Version: name + "-" + hex.EncodeToString(sum[:])[:12],
+		tmpl:    tmpl,
+	}, nil
+}
+
+// Get 按名称取模板
+func (r *PromptRegistry) Get(name string) (*PromptTemplate, error) {
+	t, ok := r.templates[name]
+	if !ok {
+		return nil, fmt.Errorf("提示词模板 %s 不存在", name)
+	}
+	return t, nil
+}
+
+// List 所有模板，按名称排序
+func (r *PromptRegistry) List() []*PromptTemplate {
+	list := make([]*PromptTemplate, 0, len(r.templates))
+	for _, t := range r.templates {
+		list = append(list, t)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].Name < list[j].Name })
+	return list
+}
+
+// Render 渲染模板，开发者资料先经过 SanitizeProfile 转义
+func (t *PromptTemplate) Render(data *PromptData) (*RenderedPrompt, error) {
+	safe := *data
+	var warnings []models.InjectionWarning
+	safe.DeveloperProfile, warnings = SanitizeProfile(data.DeveloperProfile)
+	data = &safe
+
+	var system, user bytes.Buffer
+	if err := t.tmpl.ExecuteTemplate(&system, "system", data); err != nil {
+		return nil, fmt.Errorf("渲染模板 %s 失败: %v", t.Name, err)
+	}
+	if err := t.tmpl.ExecuteTemplate(&user, "user", data); err != nil {
+		return nil, fmt.Errorf("渲染模板 %s 失败: %v", t.Name, err)
+	}
+	return &RenderedPrompt{
+		Name:     t.Name,
+		Version:  t.Version,
+		System:   strings.TrimSpace(system.String()),
+		User:     strings.TrimSpace(user.String()),
+		Warnings: warnings,
+	}, nil
+}
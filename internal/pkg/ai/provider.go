@@ -0,0 +1,115 @@
+package ai
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+)
+
+// 支持的后端，通过 AI_PROVIDER 选择
+const (
+	ProviderOpenAI = "openai" // OpenAI 兼容接口，如 OpenAI、DeepSeek、vLLM
+	ProviderOllama = "ollama" // 本地 Ollama
+	ProviderMock   = "mock"   // 固定返回评估结果，用于测试和本地开发
+)
+
+const defaultTimeout = 60 * time.Second
+
+// Message 对话中的一条消息
+type Message struct {
+	Role    string `json:"role"`
+	Content string `json:"content"`
+}
+
+// ChatRequest 与后端无关的对话请求
+type ChatRequest struct {
+	Messages    []Message
+	Temperature *float64        // 为 nil 时使用后端默认值
+	MaxTokens   int             // 为 0 时不限制
+	Format      *ResponseFormat // 不为 nil 时要求模型按 Schema 输出 JSON
+}
+
+// Usage 一次调用消耗的 token 数，后端没有返回时为 0
+type Usage struct {
+	PromptTokens     int `json:"prompt_tokens" bson:"prompt_tokens"`
+	CompletionTokens int `json:"completion_tokens" bson:"completion_tokens"`
+	TotalTokens      int `json:"total_tokens" bson:"total_tokens"`
+}
+
+// ChatResponse 与后端无关的对话结果
+type ChatResponse struct {
+	Content string
+	Model   string // 实际使用的模型，后端没有返回时为请求的模型
+	Usage   Usage
+}
+
+// Provider 大模型后端
+type Provider interface {
+	// Name 后端名称，如 openai、ollama、mock
+	Name() string
+	// Model 默认使用的模型
+	Model() string
+	Chat(ctx context.Context, req *ChatRequest) (*ChatResponse, error)
+}
+
+// ProviderConfig 后端配置
+type ProviderConfig struct {
+	Provider       string
+	BaseURL        string
+	APIKey         string
+	Model          string
+	Timeout        time.Duration
+	ResponseFormat string // 结构化输出方式，见 FormatJSONObject 等
+}
+
+// LoadProviderConfig 从环境变量读取后端配置
+func LoadProviderConfig() ProviderConfig {
+	cfg := ProviderConfig{
+		Provider:       strings.ToLower(strings.TrimSpace(os.Getenv("AI_PROVIDER"))),
+		BaseURL:        strings.TrimRight(os.Getenv("AI_BASE_URL"), "/"),
+		APIKey:         os.Getenv("AI_API_KEY"),
+		Model:          os.Getenv("AI_MODEL"),
+		Timeout:        defaultTimeout,
+		ResponseFormat: LoadResponseFormatMode(),
+	}
+	if cfg.Provider == "" {
+		cfg.Provider = ProviderOpenAI
+	}
+	if d, err := time.ParseDuration(os.Getenv("AI_TIMEOUT")); err == nil && d > 0 {
+		cfg.Timeout = d
+	}
+	return cfg
+}
+
+// NewProvider 根据配置创建后端
+func NewProvider(cfg ProviderConfig) (Provider, error) {
+	httpClient := &http.Client{Timeout: cfg.Timeout}
+
+	switch cfg.Provider {
+	case ProviderOpenAI:
+		if cfg.APIKey == "" {
+			return nil, fmt.Errorf("AI_API_KEY 未设置")
+		}
+		provider := NewOpenAIProvider(cfg.BaseURL, cfg.APIKey, cfg.Model, httpClient)
+		provider.SetResponseFormat(cfg.ResponseFormat)
+		return provider, nil
+	case ProviderOllama:
+		return NewOllamaProvider(cfg.BaseURL, cfg.Model, httpClient), nil
+	case ProviderMock:
+		return NewMockProvider(cfg.Model), nil
+	default:
+		return nil, fmt.Errorf("不支持的 AI_PROVIDER: %s（可选 %s、%s、%s）", cfg.Provider, ProviderOpenAI, ProviderOllama, ProviderMock)
+	}
+}
+
+// MaskKey 日志中只显示密钥的前几位
+func MaskKey(key string) string {
+	const visible = 6
+	if len(key) <= visible {
+		return strings.Repeat("*", len(key))
+	}
+	return key[:visible] + "..."
+}
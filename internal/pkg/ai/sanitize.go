@@ -0,0 +1,171 @@
+package ai
+
+import (
+	"qinniu/internal/models"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// 单行字段（简介、仓库描述等）在提示词中的最大长度
+const maxUntrustedFieldRunes = 500
+
+// 警告中保留的匹配上下文长度
+const injectionExcerptRunes = 80
+
+// injectionPattern 一类常见的提示词注入写法
+type injectionPattern struct {
+	name string
+	re   *regexp.Regexp
+}
+
+// injectionPatterns 检测开发者填写的内容中试图改变评估方式的文字，只记录警告，不影响评估
+var injectionPatterns = []injectionPattern{
+	{"ignore_instructions", regexp.MustCompile(`(?i)\b(ignore|disregard|forget|override|bypass)\b[\s\w,]{0,30}?\b(instructions?|prompts?|rules|directions|guidelines)\b`)},
+	{"ignore_instructions", regexp.MustCompile(`(忽略|无视|忘记|忘掉|不要理会|跳过)[^。！？\n]{0,12}?(指令|指示|提示词?|规则|要求|设定)`)},
+	{"role_override", regexp.MustCompile(`(?i)\b(you are now|from now on,? you|act as (an?|the) |pretend (to be|you are)|new instructions?)\b`)},
+	{"role_override", regexp.MustCompile(`(你现在是|从现在开始你|现在你是|你的新(任务|指令|身份)|扮演一个)`)},
+	{"system_prompt", regexp.MustCompile(`(?i)(system prompt|系统提示词?|系统指令|developer mode|开发者模式|jailbreak|越狱)`)},
+	{"role_marker", regexp.MustCompile(`(?i)(<\|\s*(im_start|im_end|system|assistant|user|endoftext)\s*\|>|\[/?INST\]|<</?SYS>>|</?\s*(system|assistant)\s*>)`)},
+	{"delimiter_escape", regexp.MustCompile(`(?i)<\s*/?\s*untrusted\b`)},
+	{"score_manipulation", regexp.MustCompile(`(?i)\b(rate|score|rank|evaluate|grade|classify)\s+(me|him|her|them|this (developer|user|profile))\b[^.\n]{0,30}?(\b\d{2,3}\b|\bhighly\b|\btop\b|\bexpert\b|\bsenior\b|\bbest\b)`)},
+	{"score_manipulation", regexp.MustCompile(`(给(我|他|她|该开发者)?|请)?(打|评|给)(出)?(满分|高分|最高分|100\s*分|十分)|(评为|评价为|认定为)(资深|专家|顶级|最优秀)`)},
+	{"output_injection", regexp.MustCompile(`(?i)"\s*(specialties|experience|evaluation|reasons|nation|confidence|summary)\s*"\s*:`)},
+}
+
+var (
+	// 零宽字符和双向控制字符，可用于隐藏文字或打乱显示顺序
+	invisibleRunes = regexp.MustCompile(`[\x{200B}-\x{200F}\x{202A}-\x{202E}\x{2060}-\x{2064}\x{2066}-\x{2069}\x{FEFF}]`)
+	// 伪造的分隔标签和对话角色标记
+	untrustedTag = regexp.MustCompile(`(?i)<\s*(/?)\s*untrusted[^>]*>`)
+	specialToken = regexp.MustCompile(`<\|([^|<>]{0,20})\|>`)
+)
+
+// DetectInjection 检测文本中的注入写法，field 为字段名，如 bio、repositories[2].description
+// 同一字段的同一类写法只记录一次
+func DetectInjection(field, text string) []models.InjectionWarning {
+	var warnings []models.InjectionWarning
+	seen := make(map[string]bool)
+	text = invisibleRunes.ReplaceAllString(text, "")
+	for _, p := range injectionPatterns {
+		if seen[p.name] {
+			continue
+		}
+		loc := p.re.FindStringIndex(text)
+		if loc == nil {
+			continue
+		}
+		seen[p.name] = true
+		warnings = append(warnings, models.InjectionWarning{
+			Field:   field,
+			Pattern: p.name,
+			Excerpt: injectionExcerpt(text, loc[0], loc[1]),
+		})
+	}
+	return warnings
+}
+
+// SanitizeProfile 返回转义后的副本和检测到的注入警告，原资料不变
+// 开发者能控制的字段（名称、位置、简介、博客、仓库描述、工作证据）去掉不可见字符、
+// 伪造的分隔标签和对话角色标记，单行字段合并换行并限制长度；模板再用 untrusted 标记其边界
+func SanitizeProfile(profile *DeveloperProfile) (*DeveloperProfile, []models.InjectionWarning) {
+	if profile == nil {
+		return nil, nil
+	}
+	safe := *profile
+	var warnings []models.InjectionWarning
+	line := func(field, value string) string {
+		warnings = append(warnings, DetectInjection(field, value)...)
+		return escapeUntrusted(value, true)
+	}
+
+	safe.Name = line("name", profile.Name)
+	safe.Email = line("email", profile.Email)
+	safe.Location = line("location", profile.Location)
+	safe.Bio = line("bio", profile.Bio)
+	safe.Blog = line("blog", profile.Blog)
+
+	safe.Repositories = make([]RepoInfo, len(profile.Repositories))
+	for i, repo := range profile.Repositories {
+		repo.Name = line(repoField(i, "name"), repo.Name)
+		repo.Description = line(repoField(i, "description"), repo.Description)
+		topics := make([]string, len(repo.Topics))
+		for j, topic := range repo.Topics {
+			topics[j] = escapeUntrusted(topic, true)
+		}
+		repo.Topics = topics
+		safe.Repositories[i] = repo
+	}
+
+	safe.Evidence = make([]Evidence, len(profile.Evidence))
+	for i, e := range profile.Evidence {
+		field := "evidence[" + strconv.Itoa(i) + "]"
+		e.Title = line(field+".title", e.Title)
+		warnings = append(warnings, DetectInjection(field+".content", e.Content)...)
+		e.Content = escapeUntrusted(e.Content, false)
+		safe.Evidence[i] = e
+	}
+	if len(profile.Repositories) == 0 {
+		safe.Repositories = nil
+	}
+	if len(profile.Evidence) == 0 {
+		safe.Evidence = nil
+	}
+	return &safe, warnings
+}
+
+// escapeUntrusted 转义不可信文本，singleLine 为 true 时合并空白并限制长度
+func escapeUntrusted(s string, singleLine bool) string {
+	s = strings.ToValidUTF8(s, "")
+	s = invisibleRunes.ReplaceAllString(s, "")
+	s = untrustedTag.ReplaceAllString(s, "[${1}untrusted]")
+	s = specialToken.ReplaceAllString(s, "[$1]")
+	s = strings.Map(func(r rune) rune {
+		if r == '\n' || r == '\t' {
+			return r
+		}
+		if unicode.IsControl(r) {
+			return -1
+		}
+		return r
+	}, s)
+	if singleLine {
+		s = strings.Join(strings.Fields(s), " ")
+		if utf8.RuneCountInString(s) > maxUntrustedFieldRunes {
+			s = string([]rune(s)[:maxUntrustedFieldRunes]) + "…"
+		}
+	}
+	return s
+}
+
+// wrapUntrusted 模板函数 untrusted：用标签标出不可信内容的边界，空值返回空字符串以便配合 or 使用
+func wrapUntrusted(s string) string {
+	if s == "" {
+		return ""
+	}
+	if strings.Contains(s, "\n") {
+		return "<untrusted>\n" + s + "\n</untrusted>"
+	}
+	return "<untrusted>" + s + "</untrusted>"
+}
+
+func injectionExcerpt(text string, start, end int) string {
+	runes := []rune(text)
+	from := utf8.RuneCountInString(text[:start])
+	to := from + utf8.RuneCountInString(text[start:end])
+	pad := (injectionExcerptRunes - (to - from)) / 2
+	if pad < 0 {
+		pad = 0
+	}
+	from, to = max(from-pad, 0), min(to+pad, len(runes))
+	if to-from > injectionExcerptRunes {
+		to = from + injectionExcerptRunes
+	}
+	return strings.Join(strings.Fields(string(runes[from:to])), " ")
+}
+
+func repoField(i int, name string) string {
+	return "repositories[" + strconv.Itoa(i) + "]." + name
+}
@@ -0,0 +1,329 @@
+package ai
+
+import (
+	"fmt"
+	"math"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Schema JSON Schema，只支持评估结果用到的关键字：
+// type、properties、required、additionalProperties、items、enum、pattern、
+// minimum、maximum、minItems、maxItems、minLength、maxLength
+type Schema map[string]interface{}
+
+// EvaluationSchema 技术能力评估结果的 JSON Schema，同时发送给支持结构化输出的模型
+var EvaluationSchema = Schema{
+	"type": "object",
+	"properties": map[string]interface{}{
+		"specialties": map[string]interface{}{
+			"type":        "array",
+			"description": "技术专长",
+			"items":       map[string]interface{}{"type": "string", "minLength": 1},
+			"minItems":    1,
+			"maxItems":    10,
+		},
+		"experience": map[string]interface{}{
+			"type":                 "object",
+			"description":          "各技术方向的经验水平，如 {\"Go\": \"资深\"}",
+			"additionalProperties": map[string]interface{}{"type": "string"},
+		},
+		"evaluation": map[string]interface{}{
+			"type":        "string",
+			"description": "整体评价",
+			"minLength":   1,
+		},
+		"reasons": map[string]interface{}{
+			"type":        "array",
+			"description": "评价依据",
+			"items":       map[string]interface{}{"type": "string"},
+		},
+	},
+	"required":             []interface{}{"specialties", "evaluation", "reasons"},
+	"additionalProperties": false,
+}
+
+// NationSchema 国家推断结果的 JSON Schema
+var NationSchema = Schema{
+	"type": "object",
+	"properties": map[string]interface{}{
+		"nation": map[string]interface{}{
+			"type":        "string",
+			"description": "两位大写国家代码（ISO 3166-1），无法判断时为空字符串",
+			"pattern":     "^([A-Z]{2})?$",
+		},
+		"confidence": map[string]interface{}{
+			"type":        "number",
+			"description": "置信度，0-100",
+			"minimum":     0,
+			"maximum":     100,
+		},
+		"reasons": map[string]interface{}{
+			"type":        "array",
+			"description": "判断依据",
+			"items":       map[string]interface{}{"type": "string"},
+		},
+	},
+	"required":             []interface{}{"nation", "confidence", "reasons"},
+	"additionalProperties": false,
+}
+
+// SummarySchema 开发者概括的 JSON Schema
+var SummarySchema = Schema{
+	"type": "object",
+	"properties": map[string]interface{}{
+		"summary": map[string]interface{}{
+			"type":        "string",
+			"description": "面向招聘人员的概括",
+			"minLength":   1,
+			"maxLength":   500,
+		},
+	},
+	"required":             []interface{}{"summary"},
+	"additionalProperties": false,
+}
+
+// FieldError 某个字段不符合 Schema
+type FieldError struct {
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
+
+func (e FieldError) String() string {
+	if e.Field == "" {
+		return e.Message
+	}
+	return e.Field + ": " + e.Message
+}
+
+// ValidationError 模型输出不符合 Schema，Fields 为各字段的错误
+type ValidationError struct {
+	Fields []FieldError
+}
+
+func (e *ValidationError) Error() string {
+	parts := make([]string, 0, len(e.Fields))
+	for _, f := range e.Fields {
+		parts = append(parts, f.String())
+	}
+	return "AI 输出不符合格式要求: " + strings.Join(parts, "; ")
+}
+
+// Validate 按 Schema 校验 value，value 为 JSON 解码后的值
+func (s Schema) Validate(value interface{}) []FieldError {
+	var errs []FieldError
+	validateValue(s, value, "", &errs)
+	return errs
+}
+
+// Coerce 按 Schema 修正常见的类型偏差，如数字写成 "85" 或 "85%"、数组写成逗号分隔的字符串
+func (s Schema) Coerce(value interface{}) interface{} {
+	return coerceValue(s, value)
+}
+
+func validateValue(schema map[string]interface{}, value interface{}, path string, errs *[]FieldError) {
+	fail := func(format string, args ...interface{}) {
+		*errs = append(*errs, FieldError{Field: path, Message: fmt.Sprintf(format, args...)})
+	}
+
+	if typ, ok := schema["type"].(string); ok && !matchesType(typ, value) {
+		fail("expected %s, got %s", typ, jsonType(value))
+		return
+	}
+
+	if enum, ok := schema["enum"].([]interface{}); ok {
+		found := false
+		for _, allowed := range enum {
+			if allowed == value {
+				found = true
+				break
+			}
+		}
+		if !found {
+			fail("must be one of %v", enum)
+		}
+	}
+
+	switch v := value.(type) {
+	case string:
+		length := len([]rune(v))
+		if min, ok := number(schema["minLength"]); ok && float64(length) < min {
+			fail("must not be shorter than %v characters", min)
+		}
+		if max, ok := number(schema["maxLength"]); ok && float64(length) > max {
+			fail("must not be longer than %v characters", max)
+		}
+		if pattern, ok := schema["pattern"].(string); ok {
+			if re, err := regexp.Compile(pattern); err == nil && !re.MatchString(v) {
+				fail("does not match pattern %s", pattern)
+			}
+		}
+	case float64:
+		if min, ok := number(schema["minimum"]); ok && v < min {
+			fail("must be >= %v", min)
+		}
+		if max, ok := number(schema["maximum"]); ok && v > max {
+			fail("must be <= %v", max)
+		}
+	case []interface{}:
+		if min, ok := number(schema["minItems"]); ok && float64(len(v)) < min {
+			fail("must have at least %v items", min)
+		}
+		if max, ok := number(schema["maxItems"]); ok && float64(len(v)) > max {
+			fail("must have at most %v items", max)
+		}
+		if items, ok := schema["items"].(map[string]interface{}); ok {
+			for i, item := range v {
+				validateValue(items, item, fmt.Sprintf("%s[%d]", path, i), errs)
+			}
+		}
+	case map[string]interface{}:
+		properties, _ := schema["properties"].(map[string]interface{})
+		if required, ok := schema["required"].([]interface{}); ok {
+			for _, name := range required {
+				if _, exists := v[name.(string)]; !exists {
+					*errs = append(*errs, FieldError{Field: joinPath(path, name.(string)), Message: "is required"})
+				}
+			}
+		}
+
+		keys := make([]string, 0, len(v))
+		for key := range v {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			if prop, ok := properties[key].(map[string]interface{}); ok {
+				validateValue(prop, v[key], joinPath(path, key), errs)
+				continue
+			}
+			switch extra := schema["additionalProperties"].(type) {
+			case bool:
+				if !extra {
+					*errs = append(*errs, FieldError{Field: joinPath(path, key), Message: "is not allowed"})
+				}
+			case map[string]interface{}:
+				validateValue(extra, v[key], joinPath(path, key), errs)
+			}
+		}
+	}
+}
+
+func coerceValue(schema map[string]interface{}, value interface{}) interface{} {
+	switch schema["type"] {
+	case "number", "integer":
+		if s, ok := value.(string); ok {
+			s = strings.TrimSpace(strings.TrimSuffix(strings.TrimSpace(s), "%"))
+			if n, err := strconv.ParseFloat(s, 64); err == nil {
+				return n
+			}
+		}
+	case "string":
+		switch v := value.(type) {
+		case float64:
+			return strconv.FormatFloat(v, 'f', -1, 64)
+		case nil:
+			return ""
+		}
+	case "array":
+		items, _ := schema["items"].(map[string]interface{})
+		switch v := value.(type) {
+		case string:
+			var list []interface{}
+			for _, part := range strings.FieldsFunc(v, func(r rune) bool {
+				return r == ',' || r == '，' || r == '、' || r == ';' || r == '；' || r == '\n'
+			}) {
+				if part = strings.TrimSpace(part); part != "" {
+					list = append(list, coerceValue(items, part))
+				}
+			}
+			if list == nil {
+				list = []interface{}{}
+			}
+			return list
+		case []interface{}:
+			for i := range v {
+				v[i] = coerceValue(items, v[i])
+			}
+			return v
+		case nil:
+			return []interface{}{}
+		}
+	case "object":
+		if v, ok := value.(map[string]interface{}); ok {
+			properties, _ := schema["properties"].(map[string]interface{})
+			for key, item := range v {
+				if prop, ok := properties[key].(map[string]interface{}); ok {
+					v[key] = coerceValue(prop, item)
+				} else if extra, ok := schema["additionalProperties"].(map[string]interface{}); ok {
+					v[key] = coerceValue(extra, item)
+				}
+			}
+			return v
+		}
+	}
+	return value
+}
+
+func matchesType(typ string, value interface{}) bool {
+	switch typ {
+	case "object":
+		_, ok := value.(map[string]interface{})
+		return ok
+	case "array":
+		_, ok := value.([]interface{})
+		return ok
+	case "string":
+		_, ok := value.(string)
+		return ok
+	case "number":
+		_, ok := value.(float64)
+		return ok
+	case "integer":
+		n, ok := value.(float64)
+		return ok && n == math.Trunc(n)
+	case "boolean":
+		_, ok := value.(bool)
+		return ok
+	case "null":
+		return value == nil
+	}
+	return true
+}
+
+func jsonType(value interface{}) string {
+	switch value.(type) {
+	case nil:
+		return "null"
+	case map[string]interface{}:
+		return "object"
+	case []interface{}:
+		return "array"
+	case string:
+		return "string"
+	case float64:
+		return "number"
+	case bool:
+		return "boolean"
+	}
+	return fmt.Sprintf("%T", value)
+}
+
+func number(v interface{}) (float64, bool) {
+	switch n := v.(type) {
+	case int:
+		return float64(n), true
+	case float64:
+		return n, true
+	}
+	return 0, false
+}
+
+func joinPath(path, key string) string {
+	if path == "" {
+		return key
+	}
+	return path + "." + key
+}
@@ -0,0 +1,140 @@
+package ai
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// 结构化输出方式，通过 AI_RESPONSE_FORMAT 配置，只对 openai 后端生效
+const (
+	FormatJSONObject = "json_object" // JSON 模式，只保证输出是 JSON（默认，DeepSeek 支持）
+	FormatJSONSchema = "json_schema" // 按 Schema 约束输出（OpenAI 支持）
+	FormatTools      = "tools"       // 通过函数调用返回参数
+	FormatNone       = "none"        // 只在提示词中要求 JSON
+)
+
+// ResponseFormat 请求结构化输出，后端按各自支持的方式约束模型输出
+type ResponseFormat struct {
+	Name        string
+	Description string
+	Schema      Schema
+}
+
+// LoadResponseFormatMode 读取 AI_RESPONSE_FORMAT
+func LoadResponseFormatMode() string {
+	switch mode := strings.ToLower(strings.TrimSpace(os.Getenv("AI_RESPONSE_FORMAT"))); mode {
+	case FormatJSONSchema, FormatTools, FormatNone:
+		return mode
+	default:
+		return FormatJSONObject
+	}
+}
+
+var errNoJSON = errors.New("AI 输出中没有 JSON 对象")
+
+// extractJSON 从模型输出中找出 JSON 对象，容忍前后的说明文字和各种代码块标记
+func extractJSON(content string) (string, error) {
+	content = strings.TrimSpace(content)
+	// 优先取代码块中的内容，如 ```json、```JSON 或不带语言的 ```
+	if start := strings.Index(content, "```"); start >= 0 {
+		rest := content[start+3:]
+		if newline := strings.IndexByte(rest, '\n'); newline >= 0 {
+			if end := strings.Index(rest[newline+1:], "```"); end >= 0 {
+				if obj, err := firstObject(rest[newline+1 : newline+1+end]); err == nil {
+					return obj, nil
+				}
+			}
+		}
+	}
+	return firstObject(content)
+}
+
+// firstObject 返回第一个括号配对完整的 JSON 对象，字符串中的括号不计入
+func firstObject(s string) (string, error) {
+	for start := strings.IndexByte(s, '{'); start >= 0; {
+		depth := 0
+		inString, escaped := false, false
+		for i := start; i < len(s); i++ {
+			c := s[i]
+			if inString {
+				switch {
+				case escaped:
+					escaped = false
+				case c == '\\':
+					escaped = true
+				case c == '"':
+					inString = false
+				}
+				continue
+			}
+			switch c {
+			case '"':
+				inString = true
+			case '{':
+				depth++
+			case '}':
+				depth--
+				if depth == 0 {
+					candidate := s[start : i+1]
+					if json.Valid([]byte(candidate)) {
+						return candidate, nil
+					}
+					i = len(s) // 不是合法 JSON，从下一个 { 重新开始
+				}
+			}
+		}
+
+		next := strings.IndexByte(s[start+1:], '{')
+		if next < 0 {
+			break
+		}
+		start += next + 1
+	}
+	return "", errNoJSON
+}
+
+// decodeStructured 提取 JSON、修正类型偏差并按 Schema 校验，通过后解码到 out
+// normalize 不为 nil 时在校验前对对象做额外的规范化
+func decodeStructured(content string, schema Schema, normalize func(map[string]interface{}), out interface{}) error {
+	raw, err := extractJSON(content)
+	if err != nil {
+		return &ValidationError{Fields: []FieldError{{Message: err.Error()}}}
+	}
+
+	var value interface{}
+	if err := json.Unmarshal([]byte(raw), &value); err != nil {
+		return &ValidationError{Fields: []FieldError{{Message: fmt.Sprintf("invalid JSON: %v", err)}}}
+	}
+	value = schema.Coerce(value)
+	if obj, ok := value.(map[string]interface{}); ok && normalize != nil {
+		normalize(obj)
+	}
+
+	if errs := schema.Validate(value); len(errs) > 0 {
+		return &ValidationError{Fields: errs}
+	}
+
+	data, err := json.Marshal(value)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, out)
+}
+
+// repairPrompt 把校验错误发回给模型，要求重新输出
+func repairPrompt(err error) string {
+	var details []string
+	var validationErr *ValidationError
+	if errors.As(err, &validationErr) {
+		for _, field := range validationErr.Fields {
+			details = append(details, "- "+field.String())
+		}
+	} else {
+		details = append(details, "- "+err.Error())
+	}
+	return "你上一次的输出不符合要求的 JSON 格式：\n" + strings.Join(details, "\n") +
+		"\n请修正以上问题，只输出一个符合 Schema 的 JSON 对象，不要输出其他文字。"
+}
@@ -0,0 +1,190 @@
+package auth
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"log"
+	"qinniu/internal/models"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// API 密钥以固定前缀开头，便于和 JWT 区分，也便于在代码仓库中扫描泄露
+const (
+	KeyPrefix       = "tr_"
+	displayPrefix   = 8
+	touchInterval   = time.Minute // 最近使用时间的最小更新间隔，避免每个请求都写库
+	principalCtxKey = "auth.principal"
+)
+
+// 认证方式
+const (
+	MethodAPIKey = "api_key"
+	MethodJWT    = "jwt"
+)
+
+var (
+	ErrMissingCredentials = errors.New("missing credentials")
+	ErrInvalidAPIKey      = errors.New("invalid api key")
+	ErrAPIKeyInactive     = errors.New("api key revoked or expired")
+
+	touchMu   sync.Mutex
+	lastTouch = make(map[string]time.Time)
+)
+
+// Principal 当前请求的调用方
+type Principal struct {
+	KeyID  string `json:"key_id"`
+	Name   string `json:"name"`
+	Role   string `json:"role"`
+	Method string `json:"method"`
+}
+
+// GenerateAPIKey 生成新的 API 密钥，返回明文和只包含哈希的记录
+// 明文只在创建时返回一次
+func GenerateAPIKey(name, role string, expiresAt *time.Time) (string, *models.APIKey, error) {
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return "", nil, err
+	}
+	plain := KeyPrefix + hex.EncodeToString(buf)
+
+	return plain, &models.APIKey{
+		Name:      name,
+		Prefix:    plain[:len(KeyPrefix)+displayPrefix],
+		KeyHash:   HashAPIKey(plain),
+		Role:      role,
+		ExpiresAt: expiresAt,
+	}, nil
+}
+
+// HashAPIKey 计算密钥的 SHA-256 哈希，密钥本身是高熵随机串，不需要加盐
+func HashAPIKey(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:])
+}
+
+// AuthenticateAPIKey 校验 API 密钥
+func AuthenticateAPIKey(plain string) (*Principal, *models.APIKey, error) {
+	if !strings.HasPrefix(plain, KeyPrefix) {
+		return nil, nil, ErrInvalidAPIKey
+	}
+	key, err := models.FindAPIKeyByHash(HashAPIKey(plain))
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, nil, ErrInvalidAPIKey
+		}
+		return nil, nil, err
+	}
+
+	now := time.Now()
+	if !key.Active(now) {
+		return nil, nil, ErrAPIKeyInactive
+	}
+	touch(key, now)
+
+	return &Principal{
+		KeyID:  key.ID.Hex(),
+		Name:   key.Name,
+		Role:   key.Role,
+		Method: MethodAPIKey,
+	}, key, nil
+}
+
+// HasCredentials 请求是否携带了凭证，不校验凭证是否有效
+func HasCredentials(c *gin.Context) bool {
+	return requestCredential(c) != ""
+}
+
+// requestCredential 取出请求中的凭证，没有或 Authorization 不是 Bearer 时返回空字符串
+func requestCredential(c *gin.Context) string {
+	if header := c.GetHeader("Authorization"); header != "" {
+		scheme, value, found := strings.Cut(header, " ")
+		if !found || !strings.EqualFold(scheme, "Bearer") {
+			return ""
+		}
+		return strings.TrimSpace(value)
+	}
+	if key := c.GetHeader("X-API-Key"); key != "" {
+		return key
+	}
+	return c.Query("access_token")
+}
+
+// Authenticate 校验请求中的凭证，API 密钥和 JWT 都通过 Bearer 传递
+// 也接受 X-API-Key 请求头；浏览器的 EventSource 和 WebSocket 无法设置请求头，使用 access_token 查询参数
+func Authenticate(c *gin.Context) (*Principal, error) {
+	credential := requestCredential(c)
+	if credential == "" {
+		return nil, ErrMissingCredentials
+	}
+
+	if strings.HasPrefix(credential, KeyPrefix) {
+		principal, _, err := AuthenticateAPIKey(credential)
+		return principal, err
+	}
+
+	now := time.Now()
+	claims, err := ParseToken(credential, now)
+	if err != nil {
+		return nil, err
+	}
+
+	// 与 API 密钥一样每次校验签发令牌的密钥：吊销或过期后令牌立即失效，角色以密钥当前的角色为准
+	key, err := models.FindAPIKeyByID(claims.Subject)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) || errors.Is(err, primitive.ErrInvalidHex) {
+			return nil, ErrInvalidToken
+		}
+		return nil, err
+	}
+	if !key.Active(now) {
+		return nil, ErrAPIKeyInactive
+	}
+	touch(key, now)
+
+	return &Principal{
+		KeyID:  claims.Subject,
+		Name:   key.Name,
+		Role:   key.Role,
+		Method: MethodJWT,
+	}, nil
+}
+
+// SetPrincipal 保存当前请求的调用方
+func SetPrincipal(c *gin.Context, principal *Principal) {
+	c.Set(principalCtxKey, principal)
+}
+
+// CurrentPrincipal 获取当前请求的调用方，未认证时返回 Anonymous
+func CurrentPrincipal(c *gin.Context) *Principal {
+	if value, ok := c.Get(principalCtxKey); ok {
+		if principal, ok := value.(*Principal); ok {
+			return principal
+		}
+	}
+	return Anonymous
+}
+
+// touch 更新密钥的最近使用时间，失败只记录日志
+func touch(key *models.APIKey, now time.Time) {
+	id := key.ID.Hex()
+	touchMu.Lock()
+	if now.Sub(lastTouch[id]) < touchInterval {
+		touchMu.Unlock()
+		return
+	}
+	lastTouch[id] = now
+	touchMu.Unlock()
+
+	if err := models.TouchAPIKey(key.ID, now); err != nil {
+		log.Printf("Warning: 更新 API 密钥使用时间失败: %v", err)
+	}
+}
@@ -0,0 +1,76 @@
+package auth
+
+import "qinniu/internal/models"
+
+// Permission 接口权限
+type Permission string
+
+const (
+	PermExport         Permission = "export"          // 导出开发者
+	PermViewSensitive  Permission = "view_sensitive"  // 查看邮箱和所有状态的 AI 评估
+	PermWatch          Permission = "watch"           // 关注开发者
+	PermCrawl          Permission = "crawl"           // 启动爬取、查看爬取任务
+	PermEdit           Permission = "edit"            // 创建和编辑开发者、关联账号
+	PermManageWebhooks Permission = "manage_webhooks" // 管理 webhook 订阅
+	PermDelete         Permission = "delete"          // 删除开发者
+	PermManageKeys     Permission = "manage_keys"     // 管理 API 密钥
+	PermReview         Permission = "review"          // 审核 AI 评估
+	PermManageQueue    Permission = "manage_queue"    // 查看、重放和清除评估死信队列
+)
+
+// rolePermissions 各角色拥有的权限，高级角色包含低级角色的全部权限
+var rolePermissions = map[string][]Permission{
+	models.RoleViewer:    {PermExport},
+	models.RoleRecruiter: {PermExport, PermViewSensitive, PermWatch},
+	models.RoleOperator:  {PermExport, PermViewSensitive, PermWatch, PermCrawl, PermEdit, PermManageWebhooks, PermReview, PermManageQueue},
+	models.RoleAdmin:     {PermExport, PermViewSensitive, PermWatch, PermCrawl, PermEdit, PermManageWebhooks, PermReview, PermManageQueue, PermDelete, PermManageKeys},
+}
+
+// SensitiveFields 没有 view_sensitive 权限时隐藏的开发者字段，tech_evaluation 只保留通过审核的评估结论
+var SensitiveFields = []string{"email", "tech_evaluation", "nation_consensus"}
+
+// Anonymous 未携带凭证的请求，只能访问公开接口，权限与 viewer 相同
+var Anonymous = &Principal{Name: "anonymous", Role: models.RoleViewer}
+
+// HasPermission 判断角色是否拥有权限
+func HasPermission(role string, permission Permission) bool {
+	for _, p := range rolePermissions[role] {
+		if p == permission {
+			return true
+		}
+	}
+	return false
+}
+
+// Can 判断调用方是否拥有权限，nil 视为匿名
+func (p *Principal) Can(permission Permission) bool {
+	if p == nil {
+		p = Anonymous
+	}
+	return HasPermission(p.Role, permission)
+}
+
+// RedactDeveloper 按调用方的权限隐藏敏感字段
+// 没有 view_sensitive 权限时只展示通过审核的评估结论，不包含各评估配置的结论、注入警告和审核记录
+func RedactDeveloper(principal *Principal, developer *models.Developer) {
+	if principal.Can(PermViewSensitive) {
+		return
+	}
+	developer.Email = ""
+	developer.NationConsensus = nil
+
+	evaluation := developer.TechEvaluation
+	developer.TechEvaluation = models.TechEvaluation{}
+	if evaluation.IsApproved() {
+		developer.TechEvaluation = models.TechEvaluation{
+			Specialties:   evaluation.Specialties,
+			Experience:    evaluation.Experience,
+			AIEvaluation:  evaluation.AIEvaluation,
+			Reasons:       evaluation.Reasons,
+			Summary:       evaluation.Summary,
+			Evidence:      evaluation.Evidence,
+			Status:        evaluation.Status,
+			LastEvaluated: evaluation.LastEvaluated,
+		}
+	}
+}
@@ -0,0 +1,144 @@
+package events
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"qinniu/internal/pkg/cache"
+	"sync"
+	"time"
+)
+
+// 事件通过 Redis 发布订阅在进程间传递，爬虫、评估服务和 API 可以运行在不同进程
+const channelName = "crawl_events"
+
+// 事件类型
+const (
+	EventStarted             = "started"
+	EventReposFetched        = "repos_fetched"
+	EventRankComputed        = "rank_computed"
+	EventNationPredicted     = "nation_predicted"
+	EventEvaluationQueued    = "evaluation_queued"
+	EventEvaluationCompleted = "evaluation_completed"
+	EventCompleted           = "completed"
+	EventFailed              = "failed"
+	EventJobCompleted        = "job_completed"
+)
+
+// Event 爬取和评估过程中的进度事件
+type Event struct {
+	Type     string                 `json:"type"`
+	JobID    string                 `json:"job_id,omitempty"`
+	Username string                 `json:"username,omitempty"`
+	Data     map[string]interface{} `json:"data,omitempty"`
+	Error    string                 `json:"error,omitempty"`
+	Time     time.Time              `json:"time"`
+}
+
+// Subscription 事件订阅，消费太慢时新事件会被丢弃
+type Subscription struct {
+	C  <-chan Event
+	ch chan Event
+}
+
+var (
+	mu          sync.RWMutex
+	subscribers = make(map[*Subscription]struct{})
+
+	relayOnce sync.Once
+	relayDown bool // 本进程订阅 Redis 失败，需要直接分发本进程发布的事件
+)
+
+// Publish 发布事件，Redis 不可用时只在本进程内分发
+func Publish(event Event) {
+	if event.Time.IsZero() {
+		event.Time = time.Now()
+	}
+
+	if cache.RedisClient != nil {
+		data, err := json.Marshal(event)
+		if err == nil {
+			ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+			err = cache.RedisClient.Publish(ctx, channelName, data).Err()
+			cancel()
+			if err == nil {
+				mu.RLock()
+				down := relayDown
+				mu.RUnlock()
+				if !down {
+					return
+				}
+			}
+		}
+		if err != nil {
+			log.Printf("Warning: 发布事件失败，仅在本进程内分发: %v", err)
+		}
+	}
+
+	dispatch(event)
+}
+
+// Subscribe 订阅所有事件，使用完毕后需要调用 Close
+func Subscribe() *Subscription {
+	relayOnce.Do(startRelay)
+
+	ch := make(chan Event, 64)
+	sub := &Subscription{C: ch, ch: ch}
+
+	mu.Lock()
+	subscribers[sub] = struct{}{}
+	mu.Unlock()
+	return sub
+}
+
+// Close 取消订阅
+func (s *Subscription) Close() {
+	mu.Lock()
+	defer mu.Unlock()
+	if _, ok := subscribers[s]; ok {
+		delete(subscribers, s)
+		close(s.ch)
+	}
+}
+
+// startRelay 订阅 Redis 频道并转发给本进程的订阅者
+// 等待订阅确认后才返回，避免丢失订阅建立之前发布的事件
+func startRelay() {
+	if cache.RedisClient == nil {
+		return
+	}
+
+	pubsub := cache.RedisClient.Subscribe(context.Background(), channelName)
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if _, err := pubsub.Receive(ctx); err != nil {
+		log.Printf("Warning: 订阅事件频道失败，仅接收本进程事件: %v", err)
+		pubsub.Close()
+		mu.Lock()
+		relayDown = true
+		mu.Unlock()
+		return
+	}
+
+	go func() {
+		for msg := range pubsub.Channel() {
+			var event Event
+			if err := json.Unmarshal([]byte(msg.Payload), &event); err != nil {
+				log.Printf("Error unmarshaling event: %v", err)
+				continue
+			}
+			dispatch(event)
+		}
+	}()
+}
+
+func dispatch(event Event) {
+	mu.RLock()
+	defer mu.RUnlock()
+	for sub := range subscribers {
+		select {
+		case sub.ch <- event:
+		default:
+		}
+	}
+}
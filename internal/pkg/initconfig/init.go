@@ -3,6 +3,7 @@ package initconfig
 import (
 	"log"
 
+	"qinniu/internal/models"
 	"qinniu/internal/pkg/cache"
 	"qinniu/internal/pkg/database"
 
@@ -21,6 +22,11 @@ func Init() {
 		log.Fatalf("无法连接到数据库: %v", err)
 	}
 
+	// 创建索引，失败时不影响启动
+	if err := models.EnsureIndexes(); err != nil {
+		log.Printf("警告: %v", err)
+	}
+
 	// 初始化 Redis 连接
 	if err := cache.InitRedis(); err != nil {
 		log.Printf("警告: Redis 连接失败: %v", err)
@@ -0,0 +1,169 @@
+package query
+
+import (
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// 常见机器人账号的用户名，如 dependabot[bot]、renovate-bot
+const botPattern = `(\[bot\]|[-_]bot)$`
+
+// Options 编译查询时的上下文
+type Options struct {
+	AllowSensitive bool                // 调用方可以查询敏感字段
+	Domains        map[string][]string // 领域到技能列表的映射
+	Now            time.Time           // 计算相对时间的基准，为零时使用当前时间
+}
+
+// Compile 把语法树编译为 Mongo 查询条件
+func Compile(node Node, opts Options) (bson.M, error) {
+	if opts.Now.IsZero() {
+		opts.Now = time.Now()
+	}
+	return compile(node, opts)
+}
+
+// ParseAndCompile 解析并编译查询字符串
+func ParseAndCompile(input string, opts Options) (bson.M, error) {
+	node, err := Parse(input)
+	if err != nil {
+		return nil, err
+	}
+	return Compile(node, opts)
+}
+
+func compile(node Node, opts Options) (bson.M, error) {
+	switch n := node.(type) {
+	case *And:
+		children, err := compileAll(n.Children, opts)
+		if err != nil {
+			return nil, err
+		}
+		return bson.M{"$and": children}, nil
+	case *Or:
+		children, err := compileAll(n.Children, opts)
+		if err != nil {
+			return nil, err
+		}
+		return bson.M{"$or": children}, nil
+	case *Not:
+		child, err := compile(n.Child, opts)
+		if err != nil {
+			return nil, err
+		}
+		return bson.M{"$nor": []bson.M{child}}, nil
+	case *Term:
+		return compileTerm(n, opts)
+	}
+	return nil, errorAt(1, "unsupported query node")
+}
+
+func compileAll(nodes []Node, opts Options) ([]bson.M, error) {
+	result := make([]bson.M, 0, len(nodes))
+	for _, node := range nodes {
+		filter, err := compile(node, opts)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, filter)
+	}
+	return result, nil
+}
+
+func compileTerm(t *Term, opts Options) (bson.M, error) {
+	f := t.Field
+	if f.Sensitive && !opts.AllowSensitive {
+		return nil, errorAt(t.Pos, "field '%s' is not available for your role", f.Name)
+	}
+
+	switch f.Kind {
+	case kindText:
+		value := t.Value
+		pattern := regexp.QuoteMeta(value)
+		if t.Op == "=" {
+			pattern = "^" + pattern + "$"
+		}
+		return matchRegex(f.Path, pattern, t.Op == "!="), nil
+
+	case kindKeyword:
+		if f.Upper {
+			value := strings.ToUpper(t.Value)
+			if t.Op == "!=" {
+				return bson.M{f.Path: bson.M{"$ne": value}}, nil
+			}
+			return bson.M{f.Path: value}, nil
+		}
+		return matchRegex(f.Path, "^"+regexp.QuoteMeta(t.Value)+"$", t.Op == "!="), nil
+
+	case kindDomain:
+		skills, ok := opts.Domains[strings.ToLower(t.Value)]
+		if !ok {
+			names := make([]string, 0, len(opts.Domains))
+			for name := range opts.Domains {
+				names = append(names, name)
+			}
+			sort.Strings(names)
+			return nil, errorAt(t.Pos, "unknown domain '%s' (supported: %s)", t.Value, strings.Join(names, ", "))
+		}
+		return bson.M{f.Path: bson.M{"$in": skills}}, nil
+
+	case kindNumber:
+		value := t.parsed.(float64)
+		switch t.Op {
+		case ":", "=":
+			return bson.M{f.Path: value}, nil
+		case "!=":
+			return bson.M{f.Path: bson.M{"$ne": value}}, nil
+		}
+		return bson.M{f.Path: bson.M{comparison(t.Op): value}}, nil
+
+	case kindDate:
+		var at time.Time
+		switch v := t.parsed.(type) {
+		case time.Time:
+			at = v
+		case time.Duration:
+			at = opts.Now.Add(-v)
+		}
+		return bson.M{f.Path: bson.M{comparison(t.Op): at}}, nil
+
+	case kindBool:
+		want := t.parsed.(bool) != (t.Op == "!=")
+		if want {
+			return bson.M{f.Path: true}, nil
+		}
+		// 字段缺失视为 false
+		return bson.M{f.Path: bson.M{"$ne": true}}, nil
+
+	case kindBot:
+		want := t.parsed.(bool) != (t.Op == "!=")
+		return matchRegex(f.Path, botPattern, !want), nil
+	}
+	return nil, errorAt(t.Pos, "unsupported field '%s'", f.Name)
+}
+
+func matchRegex(path, pattern string, negate bool) bson.M {
+	regex := primitive.Regex{Pattern: pattern, Options: "i"}
+	if negate {
+		return bson.M{path: bson.M{"$not": regex}}
+	}
+	return bson.M{path: regex}
+}
+
+func comparison(op string) string {
+	switch op {
+	case ">":
+		return "$gt"
+	case ">=":
+		return "$gte"
+	case "<":
+		return "$lt"
+	default:
+		return "$lte"
+	}
+}
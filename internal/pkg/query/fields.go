@@ -0,0 +1,136 @@
+package query
+
+import (
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type fieldKind int
+
+const (
+	kindText    fieldKind = iota // 模糊匹配：: 包含，= 完全相等（忽略大小写）
+	kindKeyword                  // 精确匹配，忽略大小写
+	kindNumber
+	kindDate
+	kindBool
+	kindDomain // 领域，展开为技能列表
+	kindBot    // 机器人账号，按用户名判断
+)
+
+// Field 查询语言中可以使用的字段，只有列在 fields 中的字段才能被查询
+type Field struct {
+	Name      string
+	Aliases   []string
+	Path      string // 对应的 Mongo 字段
+	Kind      fieldKind
+	Upper     bool // 值转换为大写，如国家代码
+	Sensitive bool // 没有查看敏感字段权限时不能查询
+}
+
+var fields = []*Field{
+	{Name: "lang", Aliases: []string{"language", "skill"}, Path: "skills", Kind: kindKeyword},
+	{Name: "nation", Aliases: []string{"country"}, Path: "nation", Kind: kindKeyword, Upper: true},
+	{Name: "domain", Path: "skills", Kind: kindDomain},
+	{Name: "name", Path: "name", Kind: kindText},
+	{Name: "username", Aliases: []string{"user", "login"}, Path: "username", Kind: kindText},
+	{Name: "location", Path: "location", Kind: kindText},
+	{Name: "email", Path: "email", Kind: kindText, Sensitive: true},
+	{Name: "repo", Path: "repositories", Kind: kindText},
+	{Name: "stars", Path: "star_count", Kind: kindNumber},
+	{Name: "forks", Path: "fork_count", Kind: kindNumber},
+	{Name: "commits", Path: "commit_count", Kind: kindNumber},
+	{Name: "rank", Aliases: []string{"talent_rank"}, Path: "talent_rank", Kind: kindNumber},
+	{Name: "confidence", Path: "confidence", Kind: kindNumber},
+	{Name: "nation_confidence", Path: "nation_confidence", Kind: kindNumber},
+	{Name: "active", Path: "last_active", Kind: kindDate},
+	{Name: "updated", Path: "last_updated", Kind: kindDate},
+	{Name: "created", Path: "created_at", Kind: kindDate},
+	{Name: "watched", Path: "watched", Kind: kindBool},
+	{Name: "bot", Path: "username", Kind: kindBot},
+}
+
+var fieldIndex = func() map[string]*Field {
+	index := make(map[string]*Field)
+	for _, f := range fields {
+		index[f.Name] = f
+		for _, alias := range f.Aliases {
+			index[alias] = f
+		}
+	}
+	return index
+}()
+
+func lookupField(name string) (*Field, bool) {
+	f, ok := fieldIndex[strings.ToLower(name)]
+	return f, ok
+}
+
+// FieldNames 支持查询的字段名
+func FieldNames() []string {
+	names := make([]string, 0, len(fields))
+	for _, f := range fields {
+		names = append(names, f.Name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func (f *Field) allows(op string) bool {
+	switch f.Kind {
+	case kindNumber:
+		return true
+	case kindDate:
+		return op == ">" || op == ">=" || op == "<" || op == "<="
+	case kindDomain:
+		return op == ":" || op == "="
+	default:
+		return op == ":" || op == "=" || op == "!="
+	}
+}
+
+// 相对时间，如 30d 表示 30 天前
+var relativeTime = regexp.MustCompile(`^(\d+)([hdwmy])$`)
+
+// validate 校验并解析值，结果保存在 term.parsed 中
+func (f *Field) validate(term *Term, pos int) error {
+	value := term.Value
+	switch f.Kind {
+	case kindNumber:
+		n, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			return errorAt(pos, "'%s' expects a number, got '%s'", f.Name, value)
+		}
+		term.parsed = n
+	case kindDate:
+		if m := relativeTime.FindStringSubmatch(strings.ToLower(value)); m != nil {
+			n, _ := strconv.Atoi(m[1])
+			unit := map[string]time.Duration{
+				"h": time.Hour, "d": 24 * time.Hour, "w": 7 * 24 * time.Hour,
+				"m": 30 * 24 * time.Hour, "y": 365 * 24 * time.Hour,
+			}[m[2]]
+			term.parsed = time.Duration(n) * unit
+			return nil
+		}
+		for _, layout := range []string{time.RFC3339, "2006-01-02"} {
+			if t, err := time.Parse(layout, value); err == nil {
+				term.parsed = t
+				return nil
+			}
+		}
+		return errorAt(pos, "'%s' expects a date (2024-01-31) or a relative time (30d, 12h, 2w, 6m, 1y), got '%s'", f.Name, value)
+	case kindBool, kindBot:
+		b, err := strconv.ParseBool(value)
+		if err != nil {
+			return errorAt(pos, "'%s' expects true or false, got '%s'", f.Name, value)
+		}
+		term.parsed = b
+	default:
+		if value == "" {
+			return errorAt(pos, "'%s' expects a non-empty value", f.Name)
+		}
+	}
+	return nil
+}
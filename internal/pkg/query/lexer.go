@@ -0,0 +1,147 @@
+package query
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+)
+
+type tokenKind int
+
+const (
+	tokEOF tokenKind = iota
+	tokWord
+	tokString // 双引号括起来的值
+	tokOp     // : = != > >= < <=
+	tokLParen
+	tokRParen
+	tokAnd
+	tokOr
+	tokNot
+)
+
+type token struct {
+	kind tokenKind
+	text string
+	pos  int // 在查询字符串中的位置（按字符计，从 1 开始）
+}
+
+func (t token) String() string {
+	switch t.kind {
+	case tokEOF:
+		return "end of query"
+	case tokString:
+		return fmt.Sprintf("%q", t.text)
+	default:
+		return fmt.Sprintf("'%s'", t.text)
+	}
+}
+
+// SyntaxError 查询语法或校验错误，Pos 为出错位置（按字符计，从 1 开始）
+type SyntaxError struct {
+	Pos int
+	Msg string
+}
+
+func (e *SyntaxError) Error() string {
+	return fmt.Sprintf("query error at position %d: %s", e.Pos, e.Msg)
+}
+
+func errorAt(pos int, format string, args ...interface{}) *SyntaxError {
+	return &SyntaxError{Pos: pos, Msg: fmt.Sprintf(format, args...)}
+}
+
+// lex 把查询字符串切分成记号
+func lex(input string) ([]token, error) {
+	runes := []rune(input)
+	var tokens []token
+
+	for i := 0; i < len(runes); {
+		r := runes[i]
+		pos := i + 1
+
+		switch {
+		case unicode.IsSpace(r):
+			i++
+		case r == '(':
+			tokens = append(tokens, token{kind: tokLParen, text: "(", pos: pos})
+			i++
+		case r == ')':
+			tokens = append(tokens, token{kind: tokRParen, text: ")", pos: pos})
+			i++
+		case r == ':' || r == '=':
+			tokens = append(tokens, token{kind: tokOp, text: string(r), pos: pos})
+			i++
+		case r == '>' || r == '<' || r == '!':
+			op := string(r)
+			if i+1 < len(runes) && runes[i+1] == '=' {
+				op += "="
+			}
+			if op == "!" {
+				return nil, errorAt(pos, "unexpected '!', did you mean '!='?")
+			}
+			tokens = append(tokens, token{kind: tokOp, text: op, pos: pos})
+			i += len(op)
+		case r == '"':
+			var sb strings.Builder
+			j := i + 1
+			for ; j < len(runes) && runes[j] != '"'; j++ {
+				if runes[j] == '\\' && j+1 < len(runes) {
+					j++
+				}
+				sb.WriteRune(runes[j])
+			}
+			if j >= len(runes) {
+				return nil, errorAt(pos, "unterminated string")
+			}
+			tokens = append(tokens, token{kind: tokString, text: sb.String(), pos: pos})
+			i = j + 1
+		case r == '-' && (len(tokens) == 0 || !adjacentToValue(tokens, runes, i)):
+			// 词首的 - 表示 NOT，如 -bot:true
+			tokens = append(tokens, token{kind: tokNot, text: "-", pos: pos})
+			i++
+		default:
+			j := i
+			for ; j < len(runes) && isWordRune(runes[j]); j++ {
+			}
+			if j == i {
+				return nil, errorAt(pos, "unexpected character '%c'", r)
+			}
+			word := string(runes[i:j])
+			tokens = append(tokens, wordToken(word, pos))
+			i = j
+		}
+	}
+
+	tokens = append(tokens, token{kind: tokEOF, pos: len(runes) + 1})
+	return tokens, nil
+}
+
+// adjacentToValue 判断 - 是否紧跟在运算符之后，此时它是负号而不是 NOT
+func adjacentToValue(tokens []token, runes []rune, i int) bool {
+	last := tokens[len(tokens)-1]
+	return last.kind == tokOp && i > 0 && !unicode.IsSpace(runes[i-1])
+}
+
+func wordToken(word string, pos int) token {
+	switch strings.ToUpper(word) {
+	case "AND":
+		return token{kind: tokAnd, text: word, pos: pos}
+	case "OR":
+		return token{kind: tokOr, text: word, pos: pos}
+	case "NOT":
+		return token{kind: tokNot, text: word, pos: pos}
+	}
+	return token{kind: tokWord, text: word, pos: pos}
+}
+
+func isWordRune(r rune) bool {
+	if unicode.IsSpace(r) {
+		return false
+	}
+	switch r {
+	case '(', ')', ':', '=', '>', '<', '!', '"':
+		return false
+	}
+	return true
+}
@@ -0,0 +1,231 @@
+package query
+
+import (
+	"strings"
+)
+
+// 限制查询规模，避免构造出过大的 Mongo 查询
+const (
+	MaxQueryLength = 1000
+	maxTerms       = 50
+	maxDepth       = 20
+)
+
+// Node 查询语法树的节点
+type Node interface {
+	node()
+}
+
+// And 所有子条件都满足
+type And struct {
+	Children []Node
+}
+
+// Or 任一子条件满足
+type Or struct {
+	Children []Node
+}
+
+// Not 子条件不满足
+type Not struct {
+	Child Node
+}
+
+// Term 单个字段条件，如 stars>=500
+type Term struct {
+	Field *Field
+	Op    string
+	Value string
+	Pos   int
+
+	parsed interface{} // 校验后解析出的值：float64、time.Time、time.Duration 或 bool
+}
+
+func (*And) node()  {}
+func (*Or) node()   {}
+func (*Not) node()  {}
+func (*Term) node() {}
+
+type parser struct {
+	tokens []token
+	pos    int
+	terms  int
+	depth  int
+}
+
+// Parse 解析查询字符串并校验字段、运算符和值
+//
+//	query := or
+//	or    := and ("OR" and)*
+//	and   := not (["AND"] not)*     相邻的条件默认为 AND
+//	not   := ("NOT" | "-") not | primary
+//	primary := "(" query ")" | field op value
+func Parse(input string) (Node, error) {
+	if len([]rune(input)) > MaxQueryLength {
+		return nil, errorAt(MaxQueryLength+1, "query is longer than %d characters", MaxQueryLength)
+	}
+	if strings.TrimSpace(input) == "" {
+		return nil, errorAt(1, "query is empty")
+	}
+
+	tokens, err := lex(input)
+	if err != nil {
+		return nil, err
+	}
+
+	p := &parser{tokens: tokens}
+	node, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	if tok := p.peek(); tok.kind != tokEOF {
+		if tok.kind == tokRParen {
+			return nil, errorAt(tok.pos, "unmatched ')'")
+		}
+		return nil, errorAt(tok.pos, "unexpected %s", tok)
+	}
+	return node, nil
+}
+
+func (p *parser) peek() token {
+	return p.tokens[p.pos]
+}
+
+func (p *parser) next() token {
+	tok := p.tokens[p.pos]
+	if tok.kind != tokEOF {
+		p.pos++
+	}
+	return tok
+}
+
+func (p *parser) parseOr() (Node, error) {
+	first, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+	children := []Node{first}
+	for p.peek().kind == tokOr {
+		p.next()
+		child, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		children = append(children, child)
+	}
+	if len(children) == 1 {
+		return first, nil
+	}
+	return &Or{Children: children}, nil
+}
+
+func (p *parser) parseAnd() (Node, error) {
+	first, err := p.parseNot()
+	if err != nil {
+		return nil, err
+	}
+	children := []Node{first}
+	for {
+		tok := p.peek()
+		if tok.kind == tokAnd {
+			p.next()
+		} else if tok.kind != tokWord && tok.kind != tokNot && tok.kind != tokLParen {
+			break
+		}
+		child, err := p.parseNot()
+		if err != nil {
+			return nil, err
+		}
+		children = append(children, child)
+	}
+	if len(children) == 1 {
+		return first, nil
+	}
+	return &And{Children: children}, nil
+}
+
+func (p *parser) parseNot() (Node, error) {
+	if p.peek().kind == tokNot {
+		tok := p.next()
+		if err := p.enter(tok.pos); err != nil {
+			return nil, err
+		}
+		defer p.leave()
+
+		child, err := p.parseNot()
+		if err != nil {
+			return nil, err
+		}
+		return &Not{Child: child}, nil
+	}
+	return p.parsePrimary()
+}
+
+func (p *parser) parsePrimary() (Node, error) {
+	tok := p.next()
+	switch tok.kind {
+	case tokLParen:
+		if err := p.enter(tok.pos); err != nil {
+			return nil, err
+		}
+		defer p.leave()
+
+		node, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		if closing := p.next(); closing.kind != tokRParen {
+			return nil, errorAt(closing.pos, "expected ')' to close '(' at position %d, got %s", tok.pos, closing)
+		}
+		return node, nil
+	case tokWord:
+		return p.parseTerm(tok)
+	case tokEOF:
+		return nil, errorAt(tok.pos, "unexpected end of query, expected a condition")
+	default:
+		return nil, errorAt(tok.pos, "unexpected %s, expected a condition like field:value", tok)
+	}
+}
+
+func (p *parser) parseTerm(fieldTok token) (Node, error) {
+	p.terms++
+	if p.terms > maxTerms {
+		return nil, errorAt(fieldTok.pos, "too many conditions (max %d)", maxTerms)
+	}
+
+	field, ok := lookupField(fieldTok.text)
+	if !ok {
+		return nil, errorAt(fieldTok.pos, "unknown field '%s' (supported: %s)", fieldTok.text, strings.Join(FieldNames(), ", "))
+	}
+
+	opTok := p.next()
+	if opTok.kind != tokOp {
+		return nil, errorAt(opTok.pos, "expected an operator after '%s', got %s", fieldTok.text, opTok)
+	}
+	if !field.allows(opTok.text) {
+		return nil, errorAt(opTok.pos, "operator '%s' is not supported for field '%s'", opTok.text, field.Name)
+	}
+
+	valueTok := p.next()
+	if valueTok.kind != tokWord && valueTok.kind != tokString {
+		return nil, errorAt(valueTok.pos, "expected a value after '%s%s', got %s", fieldTok.text, opTok.text, valueTok)
+	}
+
+	term := &Term{Field: field, Op: opTok.text, Value: valueTok.text, Pos: fieldTok.pos}
+	if err := field.validate(term, valueTok.pos); err != nil {
+		return nil, err
+	}
+	return term, nil
+}
+
+func (p *parser) enter(pos int) error {
+	p.depth++
+	if p.depth > maxDepth {
+		return errorAt(pos, "query is nested too deeply (max %d)", maxDepth)
+	}
+	return nil
+}
+
+func (p *parser) leave() {
+	p.depth--
+}
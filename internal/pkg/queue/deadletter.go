@@ -0,0 +1,222 @@
+package queue
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+const (
+	// DeadLetterName 不再重试的评估任务，保存最后的错误和全部处理记录，等待人工检查后重放或清除
+	DeadLetterName = QueueName + ":dead"
+)
+
+// 任务进入死信队列的原因
+const (
+	DeadMaxAttempts = "max_attempts" // 超过最大处理次数
+	DeadPermanent   = "permanent"    // 处理函数返回了不可重试的错误，如开发者已被删除
+	DeadMalformed   = "malformed"    // 任务无法解析
+)
+
+// ErrDeadLetterNotFound 死信不存在或已被重放、清除
+var ErrDeadLetterNotFound = errors.New("dead letter not found")
+
+// permanentError 重试也不会成功的错误
+type permanentError struct {
+	err error
+}
+
+func (e *permanentError) Error() string { return e.err.Error() }
+func (e *permanentError) Unwrap() error { return e.err }
+
+// Permanent 标记处理函数返回的错误不可重试，任务直接进入死信队列
+func Permanent(err error) error {
+	if err == nil {
+		return nil
+	}
+	return &permanentError{err: err}
+}
+
+// IsPermanent 判断错误是否被标记为不可重试
+func IsPermanent(err error) bool {
+	var p *permanentError
+	return errors.As(err, &p)
+}
+
+// DeadLetter 死信队列中的一个任务，ID 为死信队列 Stream 中的消息 ID
+type DeadLetter struct {
+	ID         string          `json:"id"`
+	Username   string          `json:"username,omitempty"`
+	Task       json.RawMessage `json:"task"`
+	Reason     string          `json:"reason"`
+	LastError  string          `json:"last_error"`
+	Attempts   []Attempt       `json:"attempts"`          // 本轮的处理记录
+	History    []Attempt       `json:"history,omitempty"` // 之前被重放前的处理记录
+	Replays    int             `json:"replays,omitempty"` // 被重放的次数
+	EnqueuedAt time.Time       `json:"enqueued_at"`
+	DeadAt     time.Time       `json:"dead_at"`
+}
+
+// newDeadLetter 根据放弃处理的消息生成死信
+func newDeadLetter(m *message, reason string) *DeadLetter {
+	dead := &DeadLetter{
+		Task:       m.Task,
+		Reason:     reason,
+		Attempts:   m.Attempts,
+		History:    m.History,
+		Replays:    m.Replays,
+		EnqueuedAt: m.EnqueuedAt,
+		DeadAt:     time.Now(),
+	}
+	if len(m.Attempts) > 0 {
+		dead.LastError = m.Attempts[len(m.Attempts)-1].Error
+	}
+	var task EvaluationTask
+	if json.Unmarshal(m.Task, &task) == nil {
+		dead.Username = task.Username
+	}
+	return dead
+}
+
+// DeadLetterQueue 查看和处理死信队列
+type DeadLetterQueue struct {
+	client *redis.Client
+}
+
+func NewDeadLetterQueue() *DeadLetterQueue {
+	redisHost := os.Getenv("REDIS_HOST")
+	if redisHost == "" {
+		redisHost = "localhost"
+	}
+	redisPort := os.Getenv("REDIS_PORT")
+	if redisPort == "" {
+		redisPort = "6379"
+	}
+
+	client := redis.NewClient(&redis.Options{
+		Addr:     fmt.Sprintf("%s:%s", redisHost, redisPort),
+		DB:       0,
+		Password: os.Getenv("REDIS_PASSWORD"),
+	})
+
+	return &DeadLetterQueue{client: client}
+}
+
+// List 最近进入死信队列的任务，最新的在前，同时返回死信总数
+func (q *DeadLetterQueue) List(ctx context.Context, limit int64) ([]*DeadLetter, int64, error) {
+	total, err := q.client.XLen(ctx, DeadLetterName).Result()
+	if err != nil {
+		return nil, 0, err
+	}
+	messages, err := q.client.XRevRangeN(ctx, DeadLetterName, "+", "-", limit).Result()
+	if err != nil {
+		return nil, 0, err
+	}
+
+	letters := make([]*DeadLetter, 0, len(messages))
+	for _, msg := range messages {
+		dead, err := decodeDeadLetter(msg)
+		if err != nil {
+			return nil, 0, err
+		}
+		letters = append(letters, dead)
+	}
+	return letters, total, nil
+}
+
+// Get 查看一个死信，不存在时返回 ErrDeadLetterNotFound
+func (q *DeadLetterQueue) Get(ctx context.Context, id string) (*DeadLetter, error) {
+	messages, err := q.client.XRange(ctx, DeadLetterName, id, id).Result()
+	if err != nil {
+		return nil, err
+	}
+	if len(messages) == 0 {
+		return nil, ErrDeadLetterNotFound
+	}
+	return decodeDeadLetter(messages[0])
+}
+
+// replayScript 删除死信并重新投递，删除成功才投递，同一死信并发重放只会投递一次
+var replayScript = redis.NewScript(`
+if redis.call('XDEL', KEYS[1], ARGV[1]) == 0 then
+	return 0
+end
+redis.call('XADD', KEYS[2], '*', 'message', ARGV[2])
+return 1
+`)
+
+// Replay 把死信重新放回评估队列，处理次数从零开始计算，之前的处理记录保留在 history 中
+func (q *DeadLetterQueue) Replay(ctx context.Context, id string) (*DeadLetter, error) {
+	dead, err := q.Get(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := json.Marshal(&message{
+		Task:       dead.Task,
+		History:    append(dead.History, dead.Attempts...),
+		Replays:    dead.Replays + 1,
+		EnqueuedAt: time.Now(),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal message: %v", err)
+	}
+
+	replayed, err := replayScript.Run(ctx, q.client, []string{DeadLetterName, StreamName}, id, data).Int()
+	if err != nil {
+		return nil, err
+	}
+	if replayed == 0 {
+		return nil, ErrDeadLetterNotFound
+	}
+	return dead, nil
+}
+
+// Purge 删除一个死信，不存在时返回 ErrDeadLetterNotFound
+func (q *DeadLetterQueue) Purge(ctx context.Context, id string) error {
+	deleted, err := q.client.XDel(ctx, DeadLetterName, id).Result()
+	if err != nil {
+		return err
+	}
+	if deleted == 0 {
+		return ErrDeadLetterNotFound
+	}
+	return nil
+}
+
+// PurgeAll 清空死信队列，返回删除的数量
+func (q *DeadLetterQueue) PurgeAll(ctx context.Context) (int64, error) {
+	pipe := q.client.TxPipeline()
+	total := pipe.XLen(ctx, DeadLetterName)
+	pipe.Del(ctx, DeadLetterName)
+	if _, err := pipe.Exec(ctx); err != nil {
+		return 0, err
+	}
+	return total.Val(), nil
+}
+
+func encodeDeadLetter(dead *DeadLetter) (map[string]interface{}, error) {
+	data, err := json.Marshal(dead)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal dead letter: %v", err)
+	}
+	return map[string]interface{}{"dead_letter": data}, nil
+}
+
+func decodeDeadLetter(msg redis.XMessage) (*DeadLetter, error) {
+	raw, ok := msg.Values["dead_letter"].(string)
+	if !ok {
+		return nil, fmt.Errorf("dead letter %s: field missing", msg.ID)
+	}
+	var dead DeadLetter
+	if err := json.Unmarshal([]byte(raw), &dead); err != nil {
+		return nil, fmt.Errorf("dead letter %s: %v", msg.ID, err)
+	}
+	dead.ID = msg.ID
+	return &dead, nil
+}
@@ -3,9 +3,12 @@ package queue
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
 	"os"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/redis/go-redis/v9"
@@ -13,16 +16,88 @@ import (
 
 const (
 	QueueName = "developer_evaluation"
+
+	// StreamName 评估任务所在的 Redis Stream，消费者组保证每个任务只交给一个消费者，处理完成后确认
+	StreamName = QueueName + ":stream"
+	// RetryName 等待重试的任务，score 为可以重新投递的时间
+	RetryName = QueueName + ":retry"
+	// GroupName 评估服务使用的消费者组
+	GroupName = "evaluators"
 )
 
+// 阻塞读取的最长等待时间，期间不会检查到期的重试和超时的任务
+const pollInterval = 5 * time.Second
+
+// 每次最多处理的到期重试、超时任务数量
+const batchSize = 50
+
 type Queue interface {
 	Publish(task *EvaluationTask) error
 	Subscribe(handler func(*EvaluationTask) error)
 }
 
+// QueueConfig 评估队列的投递配置
+type QueueConfig struct {
+	VisibilityTimeout time.Duration // 任务取出后超过该时间未确认（消费者崩溃或卡住），会被重新投递
+	MaxAttempts       int           // 每个任务最多处理的次数，超过后移入死信队列
+	RetryDelay        time.Duration // 第一次失败后的重试间隔，之后每次翻倍
+	MaxRetryDelay     time.Duration // 重试间隔的上限
+	Consumer          string        // 消费者名称，同一消费者组内需唯一
+}
+
+// LoadQueueConfig 从环境变量读取评估队列配置
+func LoadQueueConfig() QueueConfig {
+	config := QueueConfig{
+		VisibilityTimeout: 10 * time.Minute,
+		MaxAttempts:       5,
+		RetryDelay:        30 * time.Second,
+		MaxRetryDelay:     time.Hour,
+		Consumer:          os.Getenv("QUEUE_CONSUMER"),
+	}
+	if v, err := time.ParseDuration(os.Getenv("QUEUE_VISIBILITY_TIMEOUT")); err == nil && v > 0 {
+		config.VisibilityTimeout = v
+	}
+	if v, err := strconv.Atoi(os.Getenv("QUEUE_MAX_ATTEMPTS")); err == nil && v > 0 {
+		config.MaxAttempts = v
+	}
+	if v, err := time.ParseDuration(os.Getenv("QUEUE_RETRY_DELAY")); err == nil && v >= 0 {
+		config.RetryDelay = v
+	}
+	if v, err := time.ParseDuration(os.Getenv("QUEUE_MAX_RETRY_DELAY")); err == nil && v > 0 {
+		config.MaxRetryDelay = v
+	}
+	if config.Consumer == "" {
+		hostname, _ := os.Hostname()
+		config.Consumer = fmt.Sprintf("%s-%d", hostname, os.Getpid())
+	}
+	return config
+}
+
+// Attempt 任务的一次失败处理
+type Attempt struct {
+	Consumer  string    `json:"consumer,omitempty"`
+	StartedAt time.Time `json:"started_at"`
+	FailedAt  time.Time `json:"failed_at"`
+	Error     string    `json:"error"`
+}
+
+// message 队列中的一条消息：任务原文和之前失败的处理记录
+// 任务保留原始 JSON，无法解析的任务也能原样保存
+type message struct {
+	Task       json.RawMessage `json:"task"`
+	Attempts   []Attempt       `json:"attempts,omitempty"`
+	History    []Attempt       `json:"history,omitempty"` // 从死信队列重放前的处理记录，不计入处理次数
+	Replays    int             `json:"replays,omitempty"`
+	EnqueuedAt time.Time       `json:"enqueued_at"`
+}
+
+// RedisQueue 基于 Redis Stream 消费者组的评估队列，保证任务至少被处理一次：
+// 任务处理成功后才确认并删除；处理失败的任务按退避间隔重试，超过 MaxAttempts 次或返回 Permanent 错误时移入死信队列；
+// 消费者取出任务后崩溃时，任务超过 VisibilityTimeout 未确认会被其他消费者收回重新投递
 type RedisQueue struct {
 	client *redis.Client
 	ctx    context.Context
+	config QueueConfig
 }
 
 func NewQueue() Queue {
@@ -55,6 +130,7 @@ func NewQueue() Queue {
 	return &RedisQueue{
 		client: client,
 		ctx:    ctx,
+		config: LoadQueueConfig(),
 	}
 }
 
@@ -67,8 +143,7 @@ func (q *RedisQueue) Publish(task *EvaluationTask) error {
 		return fmt.Errorf("failed to marshal task: %v", err)
 	}
 
-	err = q.client.LPush(q.ctx, QueueName, data).Err()
-	if err != nil {
+	if err := q.add(&message{Task: data, EnqueuedAt: time.Now()}); err != nil {
 		log.Printf("Error publishing task to Redis: %v", err)
 		return err
 	}
@@ -77,38 +152,320 @@ func (q *RedisQueue) Publish(task *EvaluationTask) error {
 	return nil
 }
 
+func (q *RedisQueue) add(m *message) error {
+	data, err := json.Marshal(m)
+	if err != nil {
+		return fmt.Errorf("failed to marshal message: %v", err)
+	}
+	return q.client.XAdd(q.ctx, &redis.XAddArgs{
+		Stream: StreamName,
+		Values: map[string]interface{}{"message": data},
+	}).Err()
+}
+
 func (q *RedisQueue) Subscribe(handler func(*EvaluationTask) error) {
-	log.Printf("Starting to listen for tasks on queue: %s", QueueName)
+	log.Printf("Starting to listen for tasks on stream %s as consumer %s", StreamName, q.config.Consumer)
 
+	if err := q.ensureGroup(); err != nil {
+		log.Printf("Error creating consumer group: %v", err)
+	}
+	if moved, err := q.migrateLegacyList(); err != nil {
+		log.Printf("Error moving tasks from legacy queue %s: %v", QueueName, err)
+	} else if moved > 0 {
+		log.Printf("Moved %d tasks from legacy queue %s to stream", moved, QueueName)
+	}
+
+	var lastReclaim time.Time
 	for {
-		// 使用 BRPOP 阻塞等待任务
-		result, err := q.client.BRPop(q.ctx, 0, QueueName).Result()
+		if promoted, err := q.promoteRetries(); err != nil {
+			log.Printf("Error promoting retry tasks: %v", err)
+		} else if promoted > 0 {
+			log.Printf("Requeued %d tasks due for retry", promoted)
+		}
+		if time.Since(lastReclaim) >= q.reclaimInterval() {
+			q.reclaimStale()
+			lastReclaim = time.Now()
+		}
+
+		streams, err := q.client.XReadGroup(q.ctx, &redis.XReadGroupArgs{
+			Group:    GroupName,
+			Consumer: q.config.Consumer,
+			Streams:  []string{StreamName, ">"},
+			Count:    1,
+			Block:    pollInterval,
+		}).Result()
+		if errors.Is(err, redis.Nil) {
+			continue
+		}
 		if err != nil {
 			log.Printf("Error getting task from queue: %v", err)
+			// Stream 或消费者组被删除后重新创建
+			if strings.HasPrefix(err.Error(), "NOGROUP") {
+				if err := q.ensureGroup(); err != nil {
+					log.Printf("Error creating consumer group: %v", err)
+				}
+			}
 			time.Sleep(time.Second)
 			continue
 		}
 
-		log.Printf("Received task from queue")
+		for _, stream := range streams {
+			for _, msg := range stream.Messages {
+				q.deliver(msg, handler)
+			}
+		}
+	}
+}
 
-		var task EvaluationTask
-		if err := json.Unmarshal([]byte(result[1]), &task); err != nil {
-			log.Printf("Error unmarshaling task: %v", err)
-			continue
+// ensureGroup 创建消费者组（Stream 不存在时一并创建），已存在时忽略
+func (q *RedisQueue) ensureGroup() error {
+	err := q.client.XGroupCreateMkStream(q.ctx, StreamName, GroupName, "0").Err()
+	if err != nil && strings.HasPrefix(err.Error(), "BUSYGROUP") {
+		return nil
+	}
+	return err
+}
+
+// deliver 处理一条消息，成功后确认并删除，失败时安排重试
+func (q *RedisQueue) deliver(msg redis.XMessage, handler func(*EvaluationTask) error) {
+	m, err := decodeMessage(msg)
+	if err != nil {
+		log.Printf("Error decoding message %s, dropping it: %v", msg.ID, err)
+		q.ack(msg.ID)
+		return
+	}
+
+	started := time.Now()
+	var task EvaluationTask
+	if err := json.Unmarshal(m.Task, &task); err != nil {
+		log.Printf("Error unmarshaling task %s: %v", msg.ID, err)
+		m.Attempts = append(m.Attempts, Attempt{Consumer: q.config.Consumer, StartedAt: started, FailedAt: time.Now(), Error: err.Error()})
+		q.deadLetter(msg.ID, m, DeadMalformed)
+		return
+	}
+
+	log.Printf("Processing task for user: %s (attempt %d/%d)", task.Username, len(m.Attempts)+1, q.config.MaxAttempts)
+
+	// 处理期间定期续期，避免耗时较长的任务被当作超时收回
+	stop := q.heartbeat(msg.ID)
+	err = safeHandle(handler, &task)
+	stop()
+
+	if err == nil {
+		q.ack(msg.ID)
+		log.Printf("Successfully processed task for user: %s", task.Username)
+		return
+	}
+
+	log.Printf("Error handling task for %s: %v", task.Username, err)
+	attempt := Attempt{Consumer: q.config.Consumer, StartedAt: started, FailedAt: time.Now(), Error: err.Error()}
+	if IsPermanent(err) {
+		m.Attempts = append(m.Attempts, attempt)
+		q.deadLetter(msg.ID, m, DeadPermanent)
+		return
+	}
+	q.retry(msg.ID, m, attempt)
+}
+
+// safeHandle 调用处理函数，panic 视为一次失败，避免有问题的任务使评估服务退出
+func safeHandle(handler func(*EvaluationTask) error, task *EvaluationTask) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("panic: %v", r)
 		}
+	}()
+	return handler(task)
+}
+
+// retry 记录失败，未超过最大次数时按退避间隔放入重试集合，同时确认原消息
+func (q *RedisQueue) retry(id string, m *message, attempt Attempt) {
+	m.Attempts = append(m.Attempts, attempt)
+	if len(m.Attempts) >= q.config.MaxAttempts {
+		q.deadLetter(id, m, DeadMaxAttempts)
+		return
+	}
+
+	data, err := json.Marshal(m)
+	if err != nil {
+		log.Printf("Error marshaling message %s: %v", id, err)
+		return
+	}
+	delay := q.backoff(len(m.Attempts))
+	pipe := q.client.TxPipeline()
+	pipe.ZAdd(q.ctx, RetryName, redis.Z{Score: float64(time.Now().Add(delay).UnixMilli()), Member: string(data)})
+	pipe.XAck(q.ctx, StreamName, GroupName, id)
+	pipe.XDel(q.ctx, StreamName, id)
+	if _, err := pipe.Exec(q.ctx); err != nil {
+		// 未确认的消息会在超时后被收回，不会丢失
+		log.Printf("Error scheduling retry for message %s: %v", id, err)
+		return
+	}
+	log.Printf("Task %s failed %d/%d times, retrying in %s", id, len(m.Attempts), q.config.MaxAttempts, delay)
+}
 
-		log.Printf("Processing task for user: %s", task.Username)
+// deadLetter 不再重试，把任务连同处理记录移入死信队列，同时确认原消息
+func (q *RedisQueue) deadLetter(id string, m *message, reason string) {
+	dead := newDeadLetter(m, reason)
+	values, err := encodeDeadLetter(dead)
+	if err != nil {
+		log.Printf("Error marshaling dead letter %s: %v", id, err)
+		return
+	}
+	pipe := q.client.TxPipeline()
+	pipe.XAdd(q.ctx, &redis.XAddArgs{Stream: DeadLetterName, Values: values})
+	pipe.XAck(q.ctx, StreamName, GroupName, id)
+	pipe.XDel(q.ctx, StreamName, id)
+	if _, err := pipe.Exec(q.ctx); err != nil {
+		log.Printf("Error moving message %s to dead letter queue: %v", id, err)
+		return
+	}
+	log.Printf("Warning: Moved task %s for %s to dead letter queue (%s) after %d attempts, last error: %s", id, dead.Username, reason, len(m.Attempts), dead.LastError)
+}
+
+// ack 确认并删除消息
+func (q *RedisQueue) ack(id string) {
+	pipe := q.client.TxPipeline()
+	pipe.XAck(q.ctx, StreamName, GroupName, id)
+	pipe.XDel(q.ctx, StreamName, id)
+	if _, err := pipe.Exec(q.ctx); err != nil {
+		log.Printf("Error acknowledging message %s: %v", id, err)
+	}
+}
+
+// backoff 第 n 次失败后的重试间隔
+func (q *RedisQueue) backoff(n int) time.Duration {
+	delay := q.config.RetryDelay
+	for i := 1; i < n && delay < q.config.MaxRetryDelay; i++ {
+		delay *= 2
+	}
+	if delay > q.config.MaxRetryDelay {
+		delay = q.config.MaxRetryDelay
+	}
+	return delay
+}
 
-		// 同步处理任务，确保错误被正确处理
-		if err := handler(&task); err != nil {
-			log.Printf("Error handling task for %s: %v", task.Username, err)
-			// 失败重试
-			time.Sleep(time.Second * 5)
-			if err := q.Publish(&task); err != nil {
-				log.Printf("Error re-queuing task: %v", err)
+// heartbeat 定期重新认领正在处理的消息以重置空闲时间，返回停止函数
+func (q *RedisQueue) heartbeat(id string) func() {
+	done := make(chan struct{})
+	go func() {
+		ticker := time.NewTicker(q.config.VisibilityTimeout / 3)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+				err := q.client.XClaimJustID(q.ctx, &redis.XClaimArgs{
+					Stream:   StreamName,
+					Group:    GroupName,
+					Consumer: q.config.Consumer,
+					Messages: []string{id},
+				}).Err()
+				if err != nil {
+					log.Printf("Error extending visibility of message %s: %v", id, err)
+				}
 			}
-		} else {
-			log.Printf("Successfully processed task for user: %s", task.Username)
 		}
+	}()
+	return func() { close(done) }
+}
+
+func (q *RedisQueue) reclaimInterval() time.Duration {
+	interval := q.config.VisibilityTimeout / 2
+	if interval < pollInterval {
+		interval = pollInterval
+	}
+	return interval
+}
+
+// reclaimStale 收回超过可见性超时仍未确认的消息（取出它的消费者已退出或卡住），记为一次失败后重试
+func (q *RedisQueue) reclaimStale() {
+	pending, err := q.client.XPendingExt(q.ctx, &redis.XPendingExtArgs{
+		Stream: StreamName,
+		Group:  GroupName,
+		Idle:   q.config.VisibilityTimeout,
+		Start:  "-",
+		End:    "+",
+		Count:  batchSize,
+	}).Result()
+	if err != nil {
+		if !strings.HasPrefix(err.Error(), "NOGROUP") {
+			log.Printf("Error listing pending messages: %v", err)
+		}
+		return
+	}
+
+	for _, p := range pending {
+		// 认领时再次检查空闲时间，其他消费者已收回或原消费者续期时返回空
+		claimed, err := q.client.XClaim(q.ctx, &redis.XClaimArgs{
+			Stream:   StreamName,
+			Group:    GroupName,
+			Consumer: q.config.Consumer,
+			MinIdle:  q.config.VisibilityTimeout,
+			Messages: []string{p.ID},
+		}).Result()
+		if err != nil {
+			log.Printf("Error claiming stale message %s: %v", p.ID, err)
+			continue
+		}
+		for _, msg := range claimed {
+			m, err := decodeMessage(msg)
+			if err != nil {
+				log.Printf("Error decoding message %s, dropping it: %v", msg.ID, err)
+				q.ack(msg.ID)
+				continue
+			}
+			log.Printf("Reclaimed message %s from consumer %s after %s idle", msg.ID, p.Consumer, p.Idle.Round(time.Second))
+			q.retry(msg.ID, m, Attempt{
+				Consumer:  p.Consumer,
+				StartedAt: time.Now().Add(-p.Idle),
+				FailedAt:  time.Now(),
+				Error:     fmt.Sprintf("not acknowledged within visibility timeout %s", q.config.VisibilityTimeout),
+			})
+		}
+	}
+}
+
+// promoteScript 把到期的重试任务移回 Stream，在 Redis 中原子执行，多个消费者同时执行也不会重复投递
+var promoteScript = redis.NewScript(`
+local due = redis.call('ZRANGEBYSCORE', KEYS[1], '-inf', ARGV[1], 'LIMIT', 0, ARGV[2])
+for _, member in ipairs(due) do
+	redis.call('XADD', KEYS[2], '*', 'message', member)
+	redis.call('ZREM', KEYS[1], member)
+end
+return #due
+`)
+
+func (q *RedisQueue) promoteRetries() (int, error) {
+	return promoteScript.Run(q.ctx, q.client, []string{RetryName, StreamName}, time.Now().UnixMilli(), batchSize).Int()
+}
+
+// migrateScript 把旧版本 List 队列中的任务移到 Stream
+var migrateScript = redis.NewScript(`
+local moved = 0
+while true do
+	local task = redis.call('RPOP', KEYS[1])
+	if not task then
+		break
+	end
+	redis.call('XADD', KEYS[2], '*', 'message', '{"task":' .. task .. ',"enqueued_at":"' .. ARGV[1] .. '"}')
+	moved = moved + 1
+end
+return moved
+`)
+
+func (q *RedisQueue) migrateLegacyList() (int, error) {
+	return migrateScript.Run(q.ctx, q.client, []string{QueueName, StreamName}, time.Now().Format(time.RFC3339)).Int()
+}
+
+func decodeMessage(msg redis.XMessage) (*message, error) {
+	raw, ok := msg.Values["message"].(string)
+	if !ok {
+		return nil, fmt.Errorf("message field missing")
+	}
+	var m message
+	if err := json.Unmarshal([]byte(raw), &m); err != nil {
+		return nil, err
 	}
+	return &m, nil
 }
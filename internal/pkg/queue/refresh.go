@@ -0,0 +1,89 @@
+package queue
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+const (
+	RefreshQueueName = "developer_refresh"
+)
+
+// RefreshTask 后台刷新任务，按平台账号定位开发者
+type RefreshTask struct {
+	Platform string `json:"platform"`
+	BaseURL  string `json:"base_url,omitempty"`
+	Login    string `json:"login"`
+	// DeveloperID 用于记录刷新失败，旧任务没有该字段
+	DeveloperID string `json:"developer_id,omitempty"`
+}
+
+// RefreshQueue 基于 Redis 有序集合的优先级队列
+// 同一账号重复入队只会更新优先级，不会产生重复任务
+type RefreshQueue struct {
+	client *redis.Client
+}
+
+func NewRefreshQueue() *RefreshQueue {
+	redisHost := os.Getenv("REDIS_HOST")
+	if redisHost == "" {
+		redisHost = "localhost"
+	}
+	redisPort := os.Getenv("REDIS_PORT")
+	if redisPort == "" {
+		redisPort = "6379"
+	}
+
+	client := redis.NewClient(&redis.Options{
+		Addr:     fmt.Sprintf("%s:%s", redisHost, redisPort),
+		DB:       0,
+		Password: os.Getenv("REDIS_PASSWORD"),
+	})
+
+	return &RefreshQueue{client: client}
+}
+
+// Enqueue 加入刷新任务，priority 越大越先执行
+func (q *RefreshQueue) Enqueue(ctx context.Context, task *RefreshTask, priority float64) error {
+	data, err := json.Marshal(task)
+	if err != nil {
+		return fmt.Errorf("failed to marshal refresh task: %v", err)
+	}
+
+	return q.client.ZAdd(ctx, RefreshQueueName, redis.Z{Score: priority, Member: string(data)}).Err()
+}
+
+// Dequeue 取出优先级最高的任务，超时没有任务时返回 nil
+func (q *RefreshQueue) Dequeue(ctx context.Context, timeout time.Duration) (*RefreshTask, error) {
+	result, err := q.client.BZPopMax(ctx, timeout, RefreshQueueName).Result()
+	if err != nil {
+		if errors.Is(err, redis.Nil) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	member, ok := result.Member.(string)
+	if !ok {
+		return nil, fmt.Errorf("unexpected refresh task type %T", result.Member)
+	}
+
+	var task RefreshTask
+	if err := json.Unmarshal([]byte(member), &task); err != nil {
+		log.Printf("Error unmarshaling refresh task: %v", err)
+		return nil, err
+	}
+	return &task, nil
+}
+
+// Len 返回等待中的刷新任务数量
+func (q *RefreshQueue) Len(ctx context.Context) (int64, error) {
+	return q.client.ZCard(ctx, RefreshQueueName).Result()
+}
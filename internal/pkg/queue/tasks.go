@@ -3,6 +3,8 @@ package queue
 import "time"
 
 type EvaluationTask struct {
+	DeveloperID  string    `json:"developer_id,omitempty"` // 旧版本的任务没有，按用户名查找
+	JobID        string    `json:"job_id,omitempty"`       // 触发评估的爬取任务
 	Username     string    `json:"username"`
 	ProfileURL   string    `json:"profile_url"`
 	BlogURL      string    `json:"blog_url"`
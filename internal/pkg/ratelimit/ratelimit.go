@@ -0,0 +1,201 @@
+package ratelimit
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"math"
+	"os"
+	"qinniu/internal/pkg/cache"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// Limit 令牌桶配置：桶容量为 Capacity，每 Period 补满一次
+type Limit struct {
+	Capacity int
+	Period   time.Duration
+}
+
+// Policy 返回 RateLimit-Policy 响应头的值，如 100;w=3600
+func (l Limit) Policy() string {
+	return fmt.Sprintf("%d;w=%d", l.Capacity, int(l.Period.Seconds()))
+}
+
+// 每毫秒补充的令牌数
+func (l Limit) ratePerMs() float64 {
+	return float64(l.Capacity) / float64(l.Period.Milliseconds())
+}
+
+// Result 一次限流判断的结果
+type Result struct {
+	Allowed    bool
+	Limit      int
+	Remaining  int
+	Reset      time.Duration // 令牌桶补满所需时间
+	RetryAfter time.Duration // 被拒绝时，至少需要等待的时间
+}
+
+// LoadLimit 读取限流配置，未配置或格式错误时使用默认值
+func LoadLimit(capacityEnv, periodEnv string, defaultCapacity int, defaultPeriod time.Duration) Limit {
+	limit := Limit{Capacity: defaultCapacity, Period: defaultPeriod}
+	if v, err := strconv.Atoi(os.Getenv(capacityEnv)); err == nil && v > 0 {
+		limit.Capacity = v
+	}
+	if v, err := time.ParseDuration(os.Getenv(periodEnv)); err == nil && v >= time.Second {
+		limit.Period = v
+	}
+	return limit
+}
+
+// tokenBucketScript 在 Redis 中原子地补充并扣减令牌，返回是否允许和剩余令牌数
+var tokenBucketScript = redis.NewScript(`
+local capacity = tonumber(ARGV[1])
+local rate = tonumber(ARGV[2])
+local now = tonumber(ARGV[3])
+local cost = tonumber(ARGV[4])
+
+local data = redis.call('HMGET', KEYS[1], 'tokens', 'ts')
+local tokens = tonumber(data[1])
+local ts = tonumber(data[2])
+if tokens == nil or ts == nil then
+	tokens = capacity
+	ts = now
+end
+if now > ts then
+	tokens = math.min(capacity, tokens + (now - ts) * rate)
+	ts = now
+end
+
+local allowed = 0
+if tokens >= cost then
+	tokens = tokens - cost
+	allowed = 1
+end
+
+redis.call('HSET', KEYS[1], 'tokens', tostring(tokens), 'ts', tostring(ts))
+redis.call('PEXPIRE', KEYS[1], math.ceil((capacity - tokens) / rate) + 1000)
+return {allowed, tostring(tokens)}
+`)
+
+// Limiter 令牌桶限流器，优先使用 Redis 在多个实例间共享计数
+// Redis 不可用时退回到进程内的令牌桶，此时每个实例单独计数
+type Limiter struct {
+	prefix string
+
+	mu      sync.Mutex
+	buckets map[string]*bucket
+
+	warnMu     sync.Mutex
+	lastWarnAt time.Time
+}
+
+type bucket struct {
+	tokens float64
+	ts     time.Time
+}
+
+// 进程内令牌桶超过该数量时清理已补满的桶
+const maxLocalBuckets = 10000
+
+// NewLimiter 创建限流器，prefix 用于区分 Redis 中不同用途的令牌桶
+func NewLimiter(prefix string) *Limiter {
+	return &Limiter{
+		prefix:  prefix,
+		buckets: make(map[string]*bucket),
+	}
+}
+
+// Allow 从 key 对应的令牌桶中取出 cost 个令牌
+func (l *Limiter) Allow(ctx context.Context, key string, limit Limit, cost int) Result {
+	now := time.Now()
+
+	tokens, allowed, err := l.allowRedis(ctx, key, limit, cost, now)
+	if err != nil {
+		l.warn(err)
+		tokens, allowed = l.allowLocal(key, limit, cost, now)
+	}
+
+	rate := limit.ratePerMs()
+	result := Result{
+		Allowed:   allowed,
+		Limit:     limit.Capacity,
+		Remaining: int(math.Floor(tokens)),
+		Reset:     time.Duration((float64(limit.Capacity)-tokens)/rate) * time.Millisecond,
+	}
+	if !allowed {
+		result.RetryAfter = time.Duration(math.Ceil((float64(cost)-tokens)/rate)) * time.Millisecond
+	}
+	return result
+}
+
+func (l *Limiter) allowRedis(ctx context.Context, key string, limit Limit, cost int, now time.Time) (float64, bool, error) {
+	if cache.RedisClient == nil {
+		return 0, false, fmt.Errorf("redis client not initialized")
+	}
+
+	values, err := tokenBucketScript.Run(ctx, cache.RedisClient,
+		[]string{"ratelimit:" + l.prefix + ":" + key},
+		limit.Capacity, limit.ratePerMs(), now.UnixMilli(), cost,
+	).Slice()
+	if err != nil {
+		return 0, false, err
+	}
+	if len(values) != 2 {
+		return 0, false, fmt.Errorf("unexpected rate limit script result: %v", values)
+	}
+
+	allowed, _ := values[0].(int64)
+	tokenStr, _ := values[1].(string)
+	tokens, err := strconv.ParseFloat(tokenStr, 64)
+	if err != nil {
+		return 0, false, fmt.Errorf("invalid token count %q: %v", tokenStr, err)
+	}
+	return tokens, allowed == 1, nil
+}
+
+func (l *Limiter) allowLocal(key string, limit Limit, cost int, now time.Time) (float64, bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	capacity := float64(limit.Capacity)
+	rate := limit.ratePerMs()
+
+	if len(l.buckets) > maxLocalBuckets {
+		for k, b := range l.buckets {
+			if b.tokens+float64(now.Sub(b.ts).Milliseconds())*rate >= capacity {
+				delete(l.buckets, k)
+			}
+		}
+	}
+
+	b, ok := l.buckets[key]
+	if !ok {
+		b = &bucket{tokens: capacity, ts: now}
+		l.buckets[key] = b
+	}
+	if elapsed := now.Sub(b.ts); elapsed > 0 {
+		b.tokens = math.Min(capacity, b.tokens+float64(elapsed.Milliseconds())*rate)
+		b.ts = now
+	}
+
+	if b.tokens >= float64(cost) {
+		b.tokens -= float64(cost)
+		return b.tokens, true
+	}
+	return b.tokens, false
+}
+
+// warn Redis 不可用时每分钟最多记录一次日志
+func (l *Limiter) warn(err error) {
+	l.warnMu.Lock()
+	defer l.warnMu.Unlock()
+	if time.Since(l.lastWarnAt) < time.Minute {
+		return
+	}
+	l.lastWarnAt = time.Now()
+	log.Printf("Warning: Redis 限流不可用，使用进程内限流: %v", err)
+}
@@ -0,0 +1,164 @@
+package webhook
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"qinniu/internal/models"
+	"strconv"
+	"sync"
+	"time"
+
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// 第 N 次失败后等待的时间，超过次数后放弃
+var retryBackoff = []time.Duration{
+	30 * time.Second,
+	2 * time.Minute,
+	10 * time.Minute,
+	time.Hour,
+	6 * time.Hour,
+}
+
+const (
+	pollInterval  = 5 * time.Second
+	deliveryLease = time.Minute // 领取后在该时间内不会被其他实例重复投递
+)
+
+// Dispatcher 从数据库领取待投递的事件并发送，失败后按退避时间重试
+type Dispatcher struct {
+	client *http.Client
+	quit   chan struct{}
+	wg     sync.WaitGroup
+}
+
+func NewDispatcher() *Dispatcher {
+	return &Dispatcher{
+		client: &http.Client{Timeout: 10 * time.Second},
+		quit:   make(chan struct{}),
+	}
+}
+
+func (d *Dispatcher) Start() error {
+	log.Println("Starting webhook dispatcher...")
+	d.wg.Add(1)
+	go func() {
+		defer d.wg.Done()
+		ticker := time.NewTicker(pollInterval)
+		defer ticker.Stop()
+		for {
+			d.deliverDue()
+			select {
+			case <-d.quit:
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+	return nil
+}
+
+func (d *Dispatcher) Stop() error {
+	log.Println("Stopping webhook dispatcher...")
+	close(d.quit)
+	d.wg.Wait()
+	log.Println("Webhook dispatcher stopped.")
+	return nil
+}
+
+// deliverDue 依次投递所有到期的事件
+func (d *Dispatcher) deliverDue() {
+	for {
+		select {
+		case <-d.quit:
+			return
+		default:
+		}
+
+		delivery, err := models.ClaimDueDelivery(time.Now(), deliveryLease)
+		if err != nil {
+			log.Printf("Error claiming webhook delivery: %v", err)
+			return
+		}
+		if delivery == nil {
+			return
+		}
+		d.deliver(delivery)
+	}
+}
+
+// deliver 发送一次事件并记录结果
+func (d *Dispatcher) deliver(delivery *models.WebhookDelivery) {
+	started := time.Now()
+	attempt := models.WebhookAttempt{At: started}
+
+	subscription, err := models.FindWebhookByID(delivery.SubscriptionID.Hex())
+	if err != nil || !subscription.Active {
+		if err == nil || errors.Is(err, mongo.ErrNoDocuments) {
+			attempt.Error = "subscription deleted or inactive"
+			if err := delivery.RecordAttempt(attempt, models.DeliveryFailed, started); err != nil {
+				log.Printf("Error recording webhook delivery: %v", err)
+			}
+		}
+		// 其他错误等待租约过期后重试
+		return
+	}
+
+	statusCode, err := d.send(subscription, delivery)
+	attempt.StatusCode = statusCode
+	attempt.DurationMs = time.Since(started).Milliseconds()
+	if err == nil {
+		if err := delivery.RecordAttempt(attempt, models.DeliverySucceeded, started); err != nil {
+			log.Printf("Error recording webhook delivery: %v", err)
+		}
+		return
+	}
+
+	attempt.Error = err.Error()
+	status := models.DeliveryPending
+	next := started
+	if delivery.AttemptCount < len(retryBackoff) {
+		next = started.Add(retryBackoff[delivery.AttemptCount])
+	} else {
+		status = models.DeliveryFailed
+	}
+	log.Printf("Webhook delivery %s to %s failed (attempt %d): %v",
+		delivery.ID.Hex(), subscription.URL, delivery.AttemptCount+1, err)
+
+	if err := delivery.RecordAttempt(attempt, status, next); err != nil {
+		log.Printf("Error recording webhook delivery: %v", err)
+	}
+}
+
+// send 以 POST 发送事件，2xx 视为成功
+func (d *Dispatcher) send(subscription *models.WebhookSubscription, delivery *models.WebhookDelivery) (int, error) {
+	body := []byte(delivery.Payload)
+	timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+
+	req, err := http.NewRequest(http.MethodPost, subscription.URL, bytes.NewReader(body))
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "qiniu-talentrank-webhook")
+	req.Header.Set("X-Webhook-Event", delivery.Event)
+	req.Header.Set("X-Webhook-Delivery", delivery.ID.Hex())
+	req.Header.Set("X-Webhook-Timestamp", timestamp)
+	req.Header.Set("X-Webhook-Signature", Sign(subscription.Secret, timestamp, body))
+
+	resp, err := d.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 64*1024))
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return resp.StatusCode, fmt.Errorf("unexpected status %d", resp.StatusCode)
+	}
+	return resp.StatusCode, nil
+}
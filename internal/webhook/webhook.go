@@ -0,0 +1,234 @@
+package webhook
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"log"
+	"math"
+	"os"
+	"qinniu/internal/models"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// 事件类型
+const (
+	EventDeveloperCreated    = "developer.created"
+	EventTalentRankChanged   = "developer.talent_rank_changed"
+	EventEvaluationCompleted = "evaluation.completed"
+	EventEvaluationReviewed  = "evaluation.reviewed"
+)
+
+// Events 支持订阅的全部事件
+var Events = []string{EventDeveloperCreated, EventTalentRankChanged, EventEvaluationCompleted, EventEvaluationReviewed}
+
+// 订阅列表缓存时间，避免每次保存开发者都查询数据库
+const subscriptionCacheTTL = 30 * time.Second
+
+// Payload 投递给订阅方的事件内容
+type Payload struct {
+	Event     string                 `json:"event"`
+	CreatedAt time.Time              `json:"created_at"`
+	Data      map[string]interface{} `json:"data"`
+}
+
+var (
+	cacheMu      sync.Mutex
+	cachedSubs   map[string][]*models.WebhookSubscription
+	cachedSubsAt time.Time
+	registerOnce sync.Once
+)
+
+// Register 监听开发者的创建和更新，生成对应的投递记录
+// 投递记录保存在数据库中，由 API 服务中的 Dispatcher 统一发送
+func Register() {
+	registerOnce.Do(func() {
+		models.RegisterDeveloperObserver(observer{})
+	})
+}
+
+// IsValidEvent 判断事件类型是否支持订阅
+func IsValidEvent(event string) bool {
+	for _, e := range Events {
+		if e == event {
+			return true
+		}
+	}
+	return false
+}
+
+// GenerateSecret 生成订阅的签名密钥
+func GenerateSecret() (string, error) {
+	buf := make([]byte, 24)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return "whsec_" + hex.EncodeToString(buf), nil
+}
+
+// Sign 计算签名：HMAC-SHA256(secret, timestamp + "." + body)
+func Sign(secret, timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// InvalidateCache 订阅变更后清除缓存
+func InvalidateCache() {
+	cacheMu.Lock()
+	defer cacheMu.Unlock()
+	cachedSubs = nil
+}
+
+type observer struct{}
+
+func (observer) DeveloperCreated(developer *models.Developer) {
+	enqueue(EventDeveloperCreated, developer, developerData(developer), nil)
+}
+
+func (observer) DeveloperUpdated(previousTalentRank float64, developer *models.Developer) {
+	change := developer.TalentRank - previousTalentRank
+	if change == 0 {
+		return
+	}
+
+	data := developerData(developer)
+	data["previous_talent_rank"] = previousTalentRank
+	data["talent_rank_change"] = change
+
+	enqueue(EventTalentRankChanged, developer, data, func(sub *models.WebhookSubscription) bool {
+		threshold := sub.Filters.RankChangeThreshold
+		if threshold <= 0 {
+			threshold = defaultRankThreshold()
+		}
+		return math.Abs(change) > threshold
+	})
+}
+
+// EvaluationCompleted AI 评估完成后通知订阅方
+func EvaluationCompleted(developer *models.Developer) {
+	data := developerData(developer)
+	data["tech_evaluation"] = developer.TechEvaluation
+	enqueue(EventEvaluationCompleted, developer, data, nil)
+}
+
+// EvaluationReviewed AI 评估被人工审核（通过、修改或驳回）后通知订阅方
+func EvaluationReviewed(developer *models.Developer, review *models.EvaluationReview) {
+	data := developerData(developer)
+	data["tech_evaluation"] = developer.TechEvaluation
+	data["review"] = review
+	enqueue(EventEvaluationReviewed, developer, data, nil)
+}
+
+// enqueue 为匹配的订阅创建投递记录，失败只记录日志，不影响开发者数据的保存
+func enqueue(event string, developer *models.Developer, data map[string]interface{}, accept func(*models.WebhookSubscription) bool) {
+	subscriptions, err := activeSubscriptions(event)
+	if err != nil {
+		log.Printf("Warning: 查询 webhook 订阅失败: %v", err)
+		return
+	}
+
+	var body []byte
+	for _, sub := range subscriptions {
+		if !matchesFilters(sub.Filters, developer) || (accept != nil && !accept(sub)) {
+			continue
+		}
+
+		if body == nil {
+			body, err = json.Marshal(Payload{Event: event, CreatedAt: time.Now(), Data: data})
+			if err != nil {
+				log.Printf("Error marshaling webhook payload: %v", err)
+				return
+			}
+		}
+
+		delivery := &models.WebhookDelivery{
+			SubscriptionID: sub.ID,
+			Event:          event,
+			Payload:        string(body),
+		}
+		if err := delivery.Create(); err != nil {
+			log.Printf("Warning: 创建 webhook 投递失败 %s: %v", sub.URL, err)
+		}
+	}
+}
+
+func activeSubscriptions(event string) ([]*models.WebhookSubscription, error) {
+	cacheMu.Lock()
+	defer cacheMu.Unlock()
+
+	if cachedSubs == nil || time.Since(cachedSubsAt) > subscriptionCacheTTL {
+		cachedSubs = make(map[string][]*models.WebhookSubscription)
+		cachedSubsAt = time.Now()
+	}
+	if subs, ok := cachedSubs[event]; ok {
+		return subs, nil
+	}
+
+	subs, err := models.FindActiveWebhooks(event)
+	if err != nil {
+		return nil, err
+	}
+	cachedSubs[event] = subs
+	return subs, nil
+}
+
+// matchesFilters 判断开发者是否满足订阅的过滤条件
+func matchesFilters(filters models.WebhookFilters, developer *models.Developer) bool {
+	if filters.MinTalentRank > 0 && developer.TalentRank < filters.MinTalentRank {
+		return false
+	}
+	if len(filters.Nations) > 0 && !containsFold(filters.Nations, developer.Nation) {
+		return false
+	}
+	if len(filters.Skills) > 0 {
+		matched := false
+		for _, skill := range developer.Skills {
+			if containsFold(filters.Skills, skill) {
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			return false
+		}
+	}
+	return true
+}
+
+func developerData(developer *models.Developer) map[string]interface{} {
+	return map[string]interface{}{
+		"id":          developer.ID.Hex(),
+		"username":    developer.Username,
+		"name":        developer.Name,
+		"nation":      developer.Nation,
+		"talent_rank": developer.TalentRank,
+		"skills":      developer.Skills,
+		"profile_url": developer.ProfileURL,
+		"avatar":      developer.Avatar,
+	}
+}
+
+func containsFold(values []string, target string) bool {
+	for _, v := range values {
+		if strings.EqualFold(v, target) {
+			return true
+		}
+	}
+	return false
+}
+
+// defaultRankThreshold 默认的 TalentRank 变化通知阈值
+func defaultRankThreshold() float64 {
+	if v, err := strconv.ParseFloat(os.Getenv("WEBHOOK_RANK_THRESHOLD"), 64); err == nil && v > 0 {
+		return v
+	}
+	return 5
+}
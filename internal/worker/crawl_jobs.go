@@ -0,0 +1,192 @@
+package worker
+
+import (
+	"log"
+	"os"
+	"qinniu/internal/crawler"
+	"qinniu/internal/models"
+	"qinniu/internal/pkg/events"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// CrawlJobRunner 在后台执行异步爬取任务
+// 所有任务共享同一个爬虫实例和工作池，单个任务的并发数不超过任务设置的 concurrency
+type CrawlJobRunner struct {
+	crawler *crawler.GitHubCrawler
+	workers int
+	items   chan crawlItem
+	quit    chan struct{}
+	wg      sync.WaitGroup
+}
+
+// crawlItem 工作池中的单个用户爬取
+type crawlItem struct {
+	job   *models.CrawlJob
+	index int
+	done  func()
+}
+
+// LoadCrawlWorkers 从环境变量读取爬取工作池大小
+func LoadCrawlWorkers() int {
+	if v, err := strconv.Atoi(os.Getenv("CRAWL_WORKERS")); err == nil && v > 0 {
+		return v
+	}
+	return 6
+}
+
+func NewCrawlJobRunner(gc *crawler.GitHubCrawler, workers int) *CrawlJobRunner {
+	return &CrawlJobRunner{
+		crawler: gc,
+		workers: workers,
+		items:   make(chan crawlItem),
+		quit:    make(chan struct{}),
+	}
+}
+
+// Start 启动工作池，并继续执行服务重启前没有完成的任务
+func (r *CrawlJobRunner) Start() error {
+	log.Printf("Starting crawl job runner with %d workers...", r.workers)
+
+	for i := 0; i < r.workers; i++ {
+		r.wg.Add(1)
+		go func() {
+			defer r.wg.Done()
+			r.work()
+		}()
+	}
+
+	jobs, err := models.FindUnfinishedJobs()
+	if err != nil {
+		return err
+	}
+	for _, job := range jobs {
+		log.Printf("Resuming crawl job %s", job.ID.Hex())
+		r.dispatch(job)
+	}
+	return nil
+}
+
+func (r *CrawlJobRunner) Stop() error {
+	log.Println("Stopping crawl job runner...")
+	close(r.quit)
+	r.wg.Wait()
+	log.Println("Crawl job runner stopped.")
+	return nil
+}
+
+// Submit 保存任务并在后台执行，立即返回
+func (r *CrawlJobRunner) Submit(job *models.CrawlJob) error {
+	if err := job.Create(); err != nil {
+		return err
+	}
+	r.dispatch(job)
+	return nil
+}
+
+// dispatch 将任务中尚未完成的用户逐个交给工作池
+func (r *CrawlJobRunner) dispatch(job *models.CrawlJob) {
+	r.wg.Add(1)
+	go func() {
+		defer r.wg.Done()
+
+		if err := models.MarkJobStarted(job.ID); err != nil {
+			log.Printf("Error marking crawl job %s started: %v", job.ID.Hex(), err)
+		}
+
+		concurrency := job.Concurrency
+		if concurrency <= 0 {
+			concurrency = 1
+		}
+		semaphore := make(chan struct{}, concurrency)
+		var pending sync.WaitGroup
+
+		for i, user := range job.Users {
+			if user.Status == models.JobUserSucceeded || user.Status == models.JobUserFailed {
+				continue
+			}
+
+			select {
+			case semaphore <- struct{}{}:
+			case <-r.quit:
+				return
+			}
+
+			pending.Add(1)
+			item := crawlItem{job: job, index: i, done: func() {
+				<-semaphore
+				pending.Done()
+			}}
+			select {
+			case r.items <- item:
+			case <-r.quit:
+				return
+			}
+		}
+
+		pending.Wait()
+		if err := models.MarkJobCompleted(job.ID); err != nil {
+			log.Printf("Error marking crawl job %s completed: %v", job.ID.Hex(), err)
+		}
+		log.Printf("Crawl job %s completed", job.ID.Hex())
+		events.Publish(events.Event{Type: events.EventJobCompleted, JobID: job.ID.Hex()})
+	}()
+}
+
+func (r *CrawlJobRunner) work() {
+	for {
+		select {
+		case item := <-r.items:
+			r.process(item)
+			item.done()
+		case <-r.quit:
+			return
+		}
+	}
+}
+
+// process 爬取单个用户并记录状态、耗时和结果
+func (r *CrawlJobRunner) process(item crawlItem) {
+	username := item.job.Users[item.index].Username
+	startedAt := time.Now()
+	if err := models.MarkJobUserStarted(item.job.ID, item.index, startedAt); err != nil {
+		log.Printf("Error updating crawl job %s: %v", item.job.ID.Hex(), err)
+	}
+
+	developer, err := r.crawler.GetUserDataWithOptions(username, crawler.CrawlOptions{Full: item.job.Full, JobID: item.job.ID.Hex()})
+
+	finishedAt := time.Now()
+	user := models.JobUser{
+		Username:   username,
+		StartedAt:  &startedAt,
+		FinishedAt: &finishedAt,
+		DurationMs: finishedAt.Sub(startedAt).Milliseconds(),
+	}
+	event := events.Event{JobID: item.job.ID.Hex(), Username: username}
+	if err != nil {
+		log.Printf("Error crawling %s in job %s: %v", username, item.job.ID.Hex(), err)
+		user.Status = models.JobUserFailed
+		user.Error = err.Error()
+		event.Type = events.EventFailed
+		event.Error = user.Error
+		event.Data = map[string]interface{}{"stage": "crawl"}
+	} else {
+		user.Status = models.JobUserSucceeded
+		user.Result = &models.JobUserResult{
+			DeveloperID:      developer.ID,
+			TalentRank:       developer.TalentRank,
+			Nation:           developer.Nation,
+			NationConfidence: developer.NationConfidence,
+			Confidence:       developer.Confidence,
+			Repositories:     len(developer.Repositories),
+		}
+		event.Type = events.EventCompleted
+		event.Data = map[string]interface{}{"result": user.Result, "duration_ms": user.DurationMs}
+	}
+
+	if err := models.MarkJobUserFinished(item.job.ID, item.index, user); err != nil {
+		log.Printf("Error updating crawl job %s: %v", item.job.ID.Hex(), err)
+	}
+	events.Publish(event)
+}
@@ -2,18 +2,26 @@ package worker
 
 import (
 	"context"
+	"errors"
+	"fmt"
 	"log"
+	"os"
 	"qinniu/internal/models"
 	"qinniu/internal/pkg/ai"
+	"qinniu/internal/pkg/events"
 	"qinniu/internal/pkg/queue"
+	"qinniu/internal/webhook"
 	"sync"
 	"time"
 
 	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
 )
 
 type Evaluator struct {
 	aiClient *ai.Client
+	evidence *ai.EvidenceBuilder
 	queue    queue.Queue
 	quit     chan struct{}
 	wg       sync.WaitGroup
@@ -27,6 +35,11 @@ func NewEvaluator(aiClient *ai.Client, queue queue.Queue) *Evaluator {
 	}
 }
 
+// SetEvidenceBuilder 设置工作证据的来源，未设置时只根据资料和仓库名评估
+func (e *Evaluator) SetEvidenceBuilder(builder *ai.EvidenceBuilder) {
+	e.evidence = builder
+}
+
 func (e *Evaluator) Start() error {
 	log.Println("Starting evaluator worker...")
 	e.queue.Subscribe(e.ProcessEvaluationTask)
@@ -42,65 +55,95 @@ func (e *Evaluator) Stop() error {
 func (e *Evaluator) ProcessEvaluationTask(task *queue.EvaluationTask) error {
 	log.Printf("Processing evaluation task for user: %s", task.Username)
 
-	// 1. 获取用户信息
-	developer, err := models.FindByUsername(task.Username)
+	// 1. 获取用户信息，按开发者 ID 查找，用户名在任务排队期间可能因改名而变化
+	developer, err := findTaskDeveloper(task)
 	if err != nil {
 		log.Printf("Error finding developer %s: %v", task.Username, err)
 		return err
 	}
+	// 开发者在评估前被删除，重试也不会成功
+	if developer == nil {
+		return queue.Permanent(fmt.Errorf("developer %s not found", task.Username))
+	}
 
-	// 2. 收集更广泛的评估信息
-	info := map[string]interface{}{
-		// 基本信息
-		"username": developer.Username,
-		"name":     developer.Name,
-		"bio":      task.Description,
-		"location": developer.Location,
-		"email":    developer.Email,
-
-		// 链接信息
-		"blog":        task.BlogURL,
-		"profile_url": task.ProfileURL,
-		"repos":       developer.Repositories,
-		"repo_urls":   developer.RepositoryURLs,
-
-		// 技术信息
-		"skills":    developer.Skills,
-		"languages": developer.Skills, // 所有使用的编程语言
+	// 2. 收集评估信息，任务中的简介和博客比数据库中的更新
+	profile := ai.ProfileFromDeveloper(developer)
+	if task.Description != "" {
+		profile.Bio = task.Description
+	}
+	if task.BlogURL != "" {
+		profile.Blog = task.BlogURL
+	}
 
-		// 统计信息
-		"stars":      developer.StarCount,
-		"commits":    developer.CommitCount,
-		"forks":      developer.ForkCount,
-		"repo_stars": developer.RepoStars,
+	profileHash := profile.Fingerprint()
 
-		// 活跃度信息
-		"last_active": developer.LastUpdated,
-		"created_at":  developer.CreatedAt,
-		"updated_at":  developer.UpdatedAt,
+	// 3. 收集 README、源码、提交和 PR 作为评估依据，失败时只用已有信息评估
+	if err := e.evidence.Build(context.Background(), profile); err != nil {
+		log.Printf("Warning: Error collecting evidence for %s: %v", task.Username, err)
 	}
 
-	// 3. 使用 AI 进行评估
-	evaluation, err := e.aiClient.EvaluateDeveloper(context.Background(), info)
+	// 4. 使用 AI 进行评估
+	evaluation, err := e.aiClient.EvaluateDeveloper(context.Background(), profile)
 	if err != nil {
 		log.Printf("Error evaluating developer %s: %v", task.Username, err)
+		events.Publish(events.Event{
+			Type:     events.EventFailed,
+			JobID:    task.JobID,
+			Username: task.Username,
+			Data:     map[string]interface{}{"stage": "evaluation"},
+			Error:    err.Error(),
+		})
 		return err
 	}
 
-	// 4. 更新开发者信息
-	developer.TechEvaluation = models.TechEvaluation{
-		BlogURL:         task.BlogURL,
-		PersonalSiteURL: task.ProfileURL,
-		Biography:       task.Description,
-		Specialties:     evaluation.Specialties,
-		Experience:      evaluation.Experience,
-		AIEvaluation:    evaluation.AIEvaluation,
-		LastEvaluated:   time.Now(),
+	for _, w := range evaluation.InjectionWarnings {
+		log.Printf("Warning: Possible prompt injection in %s of %s (%s): %s", w.Field, task.Username, w.Pattern, w.Excerpt)
 	}
 
-	// 5. 直接更新数据库中的 tech_evaluation 字段
+	// 输入没有变化时结果与已保存的相同，只刷新评估时间
 	collection := models.GetCollection()
-	filter := bson.M{"username": task.Username}
+	filter := bson.M{"_id": developer.ID}
+	if evaluation.InputHash == developer.TechEvaluation.InputHash && developer.TechEvaluation.AIEvaluation != "" {
+		log.Printf("Evaluation inputs for %s unchanged, keeping existing evaluation", task.Username)
+		_, err := collection.UpdateOne(context.Background(), filter, bson.M{
+			"$set": bson.M{
+				"tech_evaluation.profile_hash":   profileHash,
+				"tech_evaluation.last_evaluated": time.Now(),
+			},
+		})
+		return err
+	}
+
+	// 5. 更新开发者信息
+	developer.TechEvaluation = models.TechEvaluation{
+		BlogURL:           task.BlogURL,
+		PersonalSiteURL:   task.ProfileURL,
+		Biography:         task.Description,
+		Specialties:       evaluation.Specialties,
+		Experience:        evaluation.Experience,
+		AIEvaluation:      evaluation.AIEvaluation,
+		Reasons:           evaluation.Reasons,
+		PromptVersion:     evaluation.PromptVersion,
+		Evidence:          evidenceRefs(profile.Evidence),
+		Consensus:         evaluation.Consensus,
+		InjectionWarnings: evaluation.InjectionWarnings,
+		Status:            reviewStatus(developer, evaluation),
+		InputHash:         evaluation.InputHash,
+		ProfileHash:       profileHash,
+		LastEvaluated:     time.Now(),
+	}
+
+	// 可选：生成面向招聘人员的概括，失败不影响评估结果
+	if summaryEnabled() {
+		summary, err := e.aiClient.Summarize(context.Background(), profile, evaluation)
+		if err != nil {
+			log.Printf("Error summarizing developer %s: %v", task.Username, err)
+		} else {
+			developer.TechEvaluation.Summary = summary.Summary
+		}
+	}
+
+	// 6. 直接更新数据库中的 tech_evaluation 字段
 	update := bson.M{
 		"$set": bson.M{
 			"tech_evaluation": developer.TechEvaluation,
@@ -119,6 +162,66 @@ func (e *Evaluator) ProcessEvaluationTask(task *queue.EvaluationTask) error {
 	} else {
 		log.Printf("Successfully updated tech evaluation for %s", task.Username)
 	}
+	if err := models.SyncSearchEvaluation(developer.ID); err != nil {
+		log.Printf("Warning: Error updating searchable evaluation for %s: %v", task.Username, err)
+	}
+
+	webhook.EvaluationCompleted(developer)
+
+	events.Publish(events.Event{
+		Type:     events.EventEvaluationCompleted,
+		JobID:    task.JobID,
+		Username: task.Username,
+		Data:     map[string]interface{}{"specialties": evaluation.Specialties, "status": developer.TechEvaluation.Status},
+	})
 
 	return nil
 }
+
+// findTaskDeveloper 查找任务对应的开发者，不存在时返回 nil
+func findTaskDeveloper(task *queue.EvaluationTask) (*models.Developer, error) {
+	if task.DeveloperID == "" {
+		return models.FindByUsername(task.Username)
+	}
+	developer, err := models.FindByID(task.DeveloperID)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return nil, nil
+	}
+	if errors.Is(err, primitive.ErrInvalidHex) {
+		return nil, queue.Permanent(err)
+	}
+	return developer, err
+}
+
+// reviewStatus 新评估的审核状态：AI_REVIEW_MODE=manual 时全部人工审核，
+// 否则多个评估配置对专长或国家意见分歧、资料疑似有提示词注入时人工审核，其余自动通过
+func reviewStatus(developer *models.Developer, evaluation *ai.EvaluationResult) string {
+	switch {
+	case os.Getenv("AI_REVIEW_MODE") == "manual",
+		evaluation.Consensus != nil && evaluation.Consensus.NeedsReview,
+		developer.NationConsensus != nil && developer.NationConsensus.NeedsReview,
+		len(evaluation.InjectionWarnings) > 0:
+		return models.EvaluationNeedsReview
+	}
+	return models.EvaluationAutoApproved
+}
+
+// summaryEnabled 是否在评估后生成概括，每个开发者多一次模型调用
+func summaryEnabled() bool {
+	return os.Getenv("AI_SUMMARY_ENABLED") == "true"
+}
+
+// evidenceRefs 记录评估用到的证据出处
+func evidenceRefs(items []ai.Evidence) []models.EvidenceRef {
+	refs := make([]models.EvidenceRef, 0, len(items))
+	for _, item := range items {
+		refs = append(refs, models.EvidenceRef{
+			Kind:   item.Kind,
+			Repo:   item.Repo,
+			Title:  item.Title,
+			URL:    item.URL,
+			Tokens: item.Tokens,
+		})
+	}
+	return refs
+}
@@ -0,0 +1,314 @@
+package worker
+
+import (
+	"context"
+	"log"
+	"math"
+	"os"
+	"qinniu/internal/crawler"
+	"qinniu/internal/models"
+	"qinniu/internal/pkg/cache"
+	"qinniu/internal/pkg/events"
+	"qinniu/internal/pkg/queue"
+	"strconv"
+	"sync"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// SchedulerConfig 后台定时刷新配置
+type SchedulerConfig struct {
+	Interval     time.Duration // 扫描到期开发者的间隔
+	BatchSize    int64         // 每次扫描最多入队的开发者数量
+	QuotaReserve int           // 为 API 请求和手动爬取保留的 GitHub 配额
+	Workers      int           // 并发执行刷新任务的数量
+	PauseAfter   int           // 账号连续多少次不存在（404）后暂停自动刷新，0 表示不暂停
+}
+
+// LoadSchedulerConfig 从环境变量读取定时刷新配置
+func LoadSchedulerConfig() SchedulerConfig {
+	config := SchedulerConfig{
+		Interval:     10 * time.Minute,
+		BatchSize:    50,
+		QuotaReserve: 1000,
+		Workers:      2,
+		PauseAfter:   3,
+	}
+	if v, err := time.ParseDuration(os.Getenv("SCHEDULER_INTERVAL")); err == nil && v > 0 {
+		config.Interval = v
+	}
+	if v, err := strconv.ParseInt(os.Getenv("SCHEDULER_BATCH_SIZE"), 10, 64); err == nil && v > 0 {
+		config.BatchSize = v
+	}
+	if v, err := strconv.Atoi(os.Getenv("SCHEDULER_QUOTA_RESERVE")); err == nil && v >= 0 {
+		config.QuotaReserve = v
+	}
+	if v, err := strconv.Atoi(os.Getenv("SCHEDULER_WORKERS")); err == nil && v > 0 {
+		config.Workers = v
+	}
+	if v, err := strconv.Atoi(os.Getenv("SCHEDULER_PAUSE_AFTER")); err == nil && v >= 0 {
+		config.PauseAfter = v
+	}
+	return config
+}
+
+// Scheduler 根据 UpdateFrequency 定期找出需要刷新的开发者并按优先级入队
+type Scheduler struct {
+	config  SchedulerConfig
+	crawler *crawler.GitHubCrawler
+	queue   *queue.RefreshQueue
+	quit    chan struct{}
+	wg      sync.WaitGroup
+}
+
+func NewScheduler(config SchedulerConfig, gc *crawler.GitHubCrawler, refreshQueue *queue.RefreshQueue) *Scheduler {
+	return &Scheduler{
+		config:  config,
+		crawler: gc,
+		queue:   refreshQueue,
+		quit:    make(chan struct{}),
+	}
+}
+
+func (s *Scheduler) Start() error {
+	log.Printf("Starting refresh scheduler (interval=%s, batch=%d, workers=%d)...",
+		s.config.Interval, s.config.BatchSize, s.config.Workers)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	go func() {
+		<-s.quit
+		cancel()
+	}()
+
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+		s.scanLoop(ctx)
+	}()
+
+	for i := 0; i < s.config.Workers; i++ {
+		s.wg.Add(1)
+		go func() {
+			defer s.wg.Done()
+			s.refreshLoop(ctx)
+		}()
+	}
+	return nil
+}
+
+func (s *Scheduler) Stop() error {
+	log.Println("Stopping refresh scheduler...")
+	close(s.quit)
+	s.wg.Wait()
+	log.Println("Refresh scheduler stopped.")
+	return nil
+}
+
+func (s *Scheduler) scanLoop(ctx context.Context) {
+	ticker := time.NewTicker(s.config.Interval)
+	defer ticker.Stop()
+
+	for {
+		if err := s.scan(ctx); err != nil {
+			log.Printf("Error scanning developers due for refresh: %v", err)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// scan 将到期的开发者按优先级入队，入队数量受 GitHub 剩余配额限制
+func (s *Scheduler) scan(ctx context.Context) error {
+	remaining, reset, err := s.crawler.RateLimitRemaining()
+	if err != nil {
+		return err
+	}
+	budget := remaining - s.config.QuotaReserve
+	if budget <= 0 {
+		log.Printf("GitHub 配额不足（剩余 %d，保留 %d），%s 后重置，跳过本轮刷新",
+			remaining, s.config.QuotaReserve, time.Until(reset).Round(time.Second))
+		return nil
+	}
+
+	// 上一轮的任务还没处理完时不再入队，避免积压
+	pending, err := s.queue.Len(ctx)
+	if err != nil {
+		return err
+	}
+	if pending >= s.config.BatchSize {
+		log.Printf("刷新队列仍有 %d 个任务，跳过本轮扫描", pending)
+		return nil
+	}
+
+	now := time.Now()
+	developers, err := models.FindDueForRefresh(now, s.config.BatchSize)
+	if err != nil {
+		return err
+	}
+
+	enqueued := 0
+	for _, developer := range developers {
+		cost := estimateRefreshCost(developer)
+		if cost > budget {
+			break
+		}
+		budget -= cost
+
+		priority := refreshPriority(developer, now)
+		for _, task := range refreshTasks(developer) {
+			if err := s.queue.Enqueue(ctx, task, priority); err != nil {
+				return err
+			}
+		}
+		enqueued++
+	}
+
+	if len(developers) > 0 {
+		log.Printf("Scheduled refresh for %d/%d due developers", enqueued, len(developers))
+	}
+	return nil
+}
+
+func (s *Scheduler) refreshLoop(ctx context.Context) {
+	for ctx.Err() == nil {
+		task, err := s.queue.Dequeue(ctx, 5*time.Second)
+		if err != nil {
+			if ctx.Err() == nil {
+				log.Printf("Error getting refresh task: %v", err)
+				time.Sleep(time.Second)
+			}
+			continue
+		}
+		if task == nil {
+			continue
+		}
+
+		if task.Platform == crawler.PlatformGitHub && !s.waitForQuota(ctx) {
+			return
+		}
+		if err := s.refresh(task); err != nil {
+			log.Printf("Error refreshing %s user %s: %v", task.Platform, task.Login, err)
+			events.Publish(events.Event{
+				Type:     events.EventFailed,
+				Username: task.Login,
+				Data:     map[string]interface{}{"stage": "refresh", "platform": task.Platform},
+				Error:    err.Error(),
+			})
+			s.recordFailure(task, err)
+		}
+	}
+}
+
+// recordFailure 记录刷新失败并推迟该开发者的下次刷新，避免每轮扫描都重新入队
+func (s *Scheduler) recordFailure(task *queue.RefreshTask, refreshErr error) {
+	id, err := primitive.ObjectIDFromHex(task.DeveloperID)
+	if err != nil {
+		return
+	}
+	failure, err := models.RecordRefreshFailure(id, refreshErr.Error(), crawler.IsNotFound(refreshErr), s.config.PauseAfter)
+	if err != nil {
+		log.Printf("Error recording refresh failure for %s user %s: %v", task.Platform, task.Login, err)
+		return
+	}
+	if failure != nil && failure.Paused {
+		log.Printf("%s 用户 %s 连续 %d 次不存在，暂停自动刷新", task.Platform, task.Login, failure.NotFound)
+	}
+}
+
+// waitForQuota 配额低于保留值时等待配额重置，上下文结束时返回 false
+// 查询配额本身不消耗 GitHub 配额
+func (s *Scheduler) waitForQuota(ctx context.Context) bool {
+	for {
+		remaining, reset, err := s.crawler.RateLimitRemaining()
+		if err != nil || remaining > s.config.QuotaReserve {
+			return true
+		}
+
+		wait := time.Until(reset)
+		if wait < time.Minute {
+			wait = time.Minute
+		}
+		log.Printf("GitHub 配额不足（剩余 %d），暂停刷新 %s", remaining, wait.Round(time.Second))
+		select {
+		case <-ctx.Done():
+			return false
+		case <-time.After(wait):
+		}
+	}
+}
+
+// refresh 重新爬取单个平台账号，数据保存和更新频率调整由爬虫完成
+func (s *Scheduler) refresh(task *queue.RefreshTask) error {
+	log.Printf("Refreshing %s user %s", task.Platform, task.Login)
+
+	if task.Platform == crawler.PlatformGitHub {
+		// 清除缓存，避免直接返回上次爬取的结果
+		if cache.RedisClient != nil {
+			if err := cache.ClearCache(task.Login); err != nil {
+				log.Printf("Warning: 清除缓存失败 %s: %v", task.Login, err)
+			}
+		}
+		_, err := s.crawler.GetUserData(task.Login)
+		return err
+	}
+
+	platform, err := crawler.NewPlatform(task.Platform, task.BaseURL)
+	if err != nil {
+		return err
+	}
+	_, err = crawler.CrawlPlatformUser(platform, task.Login, crawler.CrawlOptions{})
+	return err
+}
+
+// refreshTasks 为开发者的每个平台账号生成刷新任务
+func refreshTasks(developer *models.Developer) []*queue.RefreshTask {
+	var tasks []*queue.RefreshTask
+	id := developer.ID.Hex()
+	hasGitHub := false
+	for _, account := range developer.Accounts {
+		if account.Platform == crawler.PlatformGitHub {
+			hasGitHub = true
+			// GitHub 账号使用当前用户名，改名后由爬虫通过 GitHub ID 关联
+			tasks = append(tasks, &queue.RefreshTask{Platform: crawler.PlatformGitHub, Login: developer.Username, DeveloperID: id})
+			continue
+		}
+		tasks = append(tasks, &queue.RefreshTask{
+			Platform:    account.Platform,
+			BaseURL:     account.BaseURL,
+			Login:       account.Login,
+			DeveloperID: id,
+		})
+	}
+
+	// 旧数据没有账号记录，按 GitHub 用户名刷新
+	if !hasGitHub && (developer.GitHubID > 0 || len(developer.Accounts) == 0) {
+		tasks = append(tasks, &queue.RefreshTask{Platform: crawler.PlatformGitHub, Login: developer.Username, DeveloperID: id})
+	}
+	return tasks
+}
+
+// refreshPriority 关注的开发者最优先，其次是 TalentRank 高和过期时间长的
+func refreshPriority(developer *models.Developer, now time.Time) float64 {
+	priority := developer.TalentRank
+	if developer.Watched {
+		priority += 1000
+	}
+
+	overdue := now.Sub(developer.LastUpdated.Add(developer.UpdateFrequency))
+	priority += math.Min(overdue.Hours()/24, 10)
+	return priority
+}
+
+// estimateRefreshCost 估算一次刷新消耗的 GitHub API 请求数
+// 每个仓库大约需要语言、提交统计等 3 次请求，另有用户信息和分页等固定开销
+func estimateRefreshCost(developer *models.Developer) int {
+	cost := 10 + 3*len(developer.Repositories)
+	if developer.StarAnalysis != nil {
+		cost += 3 * len(developer.StarAnalysis.Repositories)
+	}
+	return cost
+}